@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"orca-peer/internal/ledger"
+	"os"
+	"os/signal"
+)
+
+var listenAddr string
+var dataDir string
+var ledgerID string
+var batchSize int
+var batchInterval int
+
+func main() {
+	flag.StringVar(&listenAddr, "listen", ":50052", "Address to answer ledger calls on.")
+	flag.StringVar(&dataDir, "data", "ledger/", "Directory the blocks are kept in.")
+	flag.StringVar(&ledgerID, "id", "ledger", "Name sent back with every recorded transaction.")
+	flag.IntVar(&batchSize, "batch-size", ledger.DefaultBatchSize, "Transactions per block.")
+	flag.IntVar(&batchInterval, "batch-interval", int(ledger.DefaultBatchInterval.Seconds()), "Seconds a transaction waits for its block to fill up.")
+	flag.Parse()
+
+	chain, err := ledger.Open(dataDir, ledger.Config{BatchSize: batchSize, BatchInterval: batchInterval})
+	if err != nil {
+		fmt.Println("Error opening ledger:", err)
+		os.Exit(1)
+	}
+	listener, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		fmt.Println("Error listening:", err)
+		os.Exit(1)
+	}
+	// Seal the last batch before exiting
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		listener.Close()
+	}()
+	fmt.Printf("Ledger listening on %s with %d blocks\n", listener.Addr(), chain.Height())
+	err = ledger.Serve(listener, chain, ledgerID)
+	if closeErr := chain.Close(); closeErr != nil {
+		fmt.Println("Error closing ledger:", closeErr)
+	}
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		fmt.Println("Error serving:", err)
+		os.Exit(1)
+	}
+}
@@ -2,25 +2,58 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	orcaCLI "orca-peer/internal/cli"
+	"orca-peer/internal/config"
 	orcaHash "orca-peer/internal/hash"
 	orcaTest "orca-peer/test"
 	"os"
+	"strings"
 )
 
 var test bool
-var localOnly bool
 var boostrapNodeAddress string
+var noBootstrap bool
+var listenAddrs string
+var dhtMode string
+var configPath string
+var mdns bool
 
 func main() {
 	flag.BoolVar(&test, "test", false, "Create test server with no CLI.")
-	flag.StringVar(&boostrapNodeAddress, "bootstrap", "", "Give address to boostrap.")
+	flag.StringVar(&boostrapNodeAddress, "bootstrap", "", "Comma separated multiaddrs of peers to bootstrap from, instead of the ones in the config.")
+	flag.BoolVar(&noBootstrap, "no-bootstrap", false, "Start a new network instead of joining one.")
+	flag.StringVar(&listenAddrs, "listen", "", "Comma separated multiaddrs to listen on, instead of the ones in the config.")
+	flag.StringVar(&dhtMode, "dht-mode", "", "DHT mode: server, client or auto.")
+	flag.BoolVar(&mdns, "mdns", false, "Find peers on the local network, no bootstrap peer needed.")
+	flag.StringVar(&configPath, "config", config.DefaultPath, "Path to the node config file.")
 	flag.Parse()
+	nodeConfig, err := config.Load(configPath)
+	if err != nil {
+		fmt.Println("Error loading config:", err)
+		os.Exit(1)
+	}
+	// Flags win over the config file
+	if boostrapNodeAddress != "" {
+		nodeConfig.Network.BootstrapPeers = strings.Split(boostrapNodeAddress, ",")
+	}
+	if noBootstrap {
+		nodeConfig.Network.BootstrapPeers = []string{}
+	}
+	if listenAddrs != "" {
+		nodeConfig.Network.ListenAddrs = strings.Split(listenAddrs, ",")
+	}
+	if dhtMode != "" {
+		nodeConfig.Network.Mode = dhtMode
+	}
+	if mdns {
+		nodeConfig.Network.MDNS = true
+	}
 	publicKey, privateKey := orcaHash.LoadInKeys()
 	os.MkdirAll("./files/stored/", 0755)
 	if test {
 		orcaTest.RunTestServer()
 	} else {
-		orcaCLI.StartCLI(&boostrapNodeAddress, publicKey, privateKey)
+		orcaCLI.StartCLI(publicKey, privateKey, nodeConfig)
 	}
 }
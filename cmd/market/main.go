@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"orca-peer/internal/market"
+	"os"
+)
+
+var listenAddr string
+var dataDir string
+
+func main() {
+	flag.StringVar(&listenAddr, "listen", ":50051", "Address to answer market calls on.")
+	flag.StringVar(&dataDir, "data", "market/", "Directory the holder registry is kept in.")
+	flag.Parse()
+
+	registry, err := market.OpenRegistry(dataDir)
+	if err != nil {
+		fmt.Println("Error opening registry:", err)
+		os.Exit(1)
+	}
+	listener, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		fmt.Println("Error listening:", err)
+		os.Exit(1)
+	}
+	fmt.Println("Market listening on", listener.Addr())
+	if err := market.Serve(listener, registry); err != nil {
+		fmt.Println("Error serving:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,433 @@
+package announce
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	orcaClient "orca-peer/internal/client"
+	pb "orca-peer/internal/fileshare"
+	"orca-peer/internal/hash"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	outboxName = "outbox.json"
+
+	DefaultRetryInterval    = 30 * time.Second
+	DefaultMaxRetryInterval = time.Hour
+	// How long asking the market what it lists for this node may take
+	reconcileTimeout = time.Minute
+)
+
+var errNotAcknowledged = errors.New("market did not acknowledge the announcement")
+
+type Config struct {
+	// Seconds before a failed announcement is sent again, doubled after
+	// every failure
+	RetryInterval int `json:"retry_interval"`
+	// The longest wait between two attempts, in seconds
+	MaxRetryInterval int `json:"max_retry_interval"`
+}
+
+func (config Config) Validate() error {
+	if config.RetryInterval < 0 || config.MaxRetryInterval < 0 {
+		return fmt.Errorf("announce retry intervals can not be negative")
+	}
+	if config.MaxRetryInterval != 0 && config.MaxRetryInterval < config.RetryInterval {
+		return fmt.Errorf("announce max retry interval can not be shorter than the retry interval")
+	}
+	return nil
+}
+
+// Market is the part of the market client announcements are sent with.
+type Market interface {
+	NotifyStore(ctx context.Context, file *pb.FileDesc) (*pb.StorageACKResponse, error)
+	NotifyUnstore(ctx context.Context, file *pb.FileDesc) (*pb.StorageACKResponse, error)
+	AllFiles(ctx context.Context, me *pb.StorageIP) ([]*pb.FileDesc, error)
+}
+
+// Pricer is what files are offered for, per MB.
+type Pricer interface {
+	Price(fileHash string) float64
+}
+
+// Origin is how this node is listed as a holder. The host of Address may be
+// empty, in which case the market uses the address the announcement came
+// from.
+type Origin struct {
+	UserID  string
+	Address string
+}
+
+type Op string
+
+const (
+	Store   Op = "store"
+	Unstore Op = "unstore"
+)
+
+// Announcement is a change the market has not acknowledged yet.
+type Announcement struct {
+	CID      string    `json:"cid"`
+	Op       Op        `json:"op"`
+	Size     int64     `json:"size"`
+	Attempts int       `json:"attempts"`
+	Next     time.Time `json:"next"`
+}
+
+// outbox is what is kept on disk.
+type outbox struct {
+	Names   map[string]string `json:"names"`
+	Pending []*Announcement   `json:"pending"`
+}
+
+/*
+Announcer tells the market about every file that is added to or leaves a
+DataStore. Changes are written to an outbox on disk before they are sent, and
+stay there until the market acknowledges them, so a change is never lost to a
+market that is down or a node that stops. Failed announcements are sent again
+after a growing pause. Only the latest change of a file is kept: a file that
+is stored and evicted before the market heard of it is only unstored.
+
+When it starts, the announcer compares what the market lists for this node
+with what is in the store, to catch changes made while it was not running.
+*/
+type Announcer struct {
+	storage          *hash.DataStore
+	market           Market
+	prices           Pricer
+	origin           Origin
+	path             string
+	retryInterval    time.Duration
+	maxRetryInterval time.Duration
+	wake             chan struct{}
+	now              func() time.Time
+
+	mu      sync.Mutex
+	names   map[string]string
+	pending []*Announcement
+}
+
+// NewAnnouncer loads the outbox in dir and starts following storage.
+func NewAnnouncer(dir string, storage *hash.DataStore, market Market, prices Pricer, origin Origin, config Config) (*Announcer, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	announcer := &Announcer{
+		storage:          storage,
+		market:           market,
+		prices:           prices,
+		origin:           origin,
+		path:             filepath.Join(dir, outboxName),
+		retryInterval:    time.Duration(config.RetryInterval) * time.Second,
+		maxRetryInterval: time.Duration(config.MaxRetryInterval) * time.Second,
+		wake:             make(chan struct{}, 1),
+		now:              time.Now,
+		names:            make(map[string]string),
+	}
+	if announcer.retryInterval == 0 {
+		announcer.retryInterval = DefaultRetryInterval
+	}
+	if announcer.maxRetryInterval == 0 {
+		announcer.maxRetryInterval = max(DefaultMaxRetryInterval, announcer.retryInterval)
+	}
+	if err := announcer.load(); err != nil {
+		return nil, err
+	}
+	storage.OnPut(announcer.filePut)
+	storage.OnRemove(announcer.fileRemoved)
+	return announcer, nil
+}
+
+func (announcer *Announcer) load() error {
+	data, err := os.ReadFile(announcer.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var saved outbox
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("reading %s: %w", announcer.path, err)
+	}
+	if saved.Names != nil {
+		announcer.names = saved.Names
+	}
+	announcer.pending = saved.Pending
+	return nil
+}
+
+// save writes the outbox to disk. Callers must hold announcer.mu
+func (announcer *Announcer) save() error {
+	data, err := json.Marshal(outbox{Names: announcer.names, Pending: announcer.pending})
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first so a crash never leaves half an outbox
+	if err := os.WriteFile(announcer.path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(announcer.path+".tmp", announcer.path)
+}
+
+// filePut and fileRemoved are called by the store with its lock held, so they
+// only queue the announcement.
+func (announcer *Announcer) filePut(manifest *hash.Manifest) {
+	announcer.enqueue(Store, manifest.Root, manifest.Size)
+}
+
+func (announcer *Announcer) fileRemoved(manifest *hash.Manifest) {
+	announcer.enqueue(Unstore, manifest.Root, manifest.Size)
+}
+
+// enqueue replaces the pending announcement of cid, if any, with op.
+func (announcer *Announcer) enqueue(op Op, cid string, size int64) {
+	announcer.mu.Lock()
+	announcer.remove(cid)
+	announcer.pending = append(announcer.pending, &Announcement{CID: cid, Op: op, Size: size, Next: announcer.now()})
+	if op == Unstore {
+		delete(announcer.names, cid)
+	}
+	if err := announcer.save(); err != nil {
+		fmt.Println("Error saving announcement outbox:", err)
+	}
+	announcer.mu.Unlock()
+
+	select {
+	case announcer.wake <- struct{}{}:
+	default:
+	}
+}
+
+// remove drops the pending announcement of cid. Callers must hold
+// announcer.mu
+func (announcer *Announcer) remove(cid string) {
+	for i, announcement := range announcer.pending {
+		if announcement.CID == cid {
+			announcer.pending = append(announcer.pending[:i], announcer.pending[i+1:]...)
+			return
+		}
+	}
+}
+
+// Name sets the name cid is listed under and announces the file again if it
+// is stored.
+func (announcer *Announcer) Name(cid string, name string) {
+	announcer.mu.Lock()
+	announcer.names[cid] = name
+	announcer.mu.Unlock()
+
+	manifest, err := announcer.storage.GetManifest(cid)
+	if err != nil {
+		return
+	}
+	announcer.enqueue(Store, cid, manifest.Size)
+}
+
+// Pending returns the announcements the market has not acknowledged yet,
+// oldest first.
+func (announcer *Announcer) Pending() []Announcement {
+	announcer.mu.Lock()
+	defer announcer.mu.Unlock()
+	pending := make([]Announcement, 0, len(announcer.pending))
+	for _, announcement := range announcer.pending {
+		pending = append(pending, *announcement)
+	}
+	return pending
+}
+
+// Run reconciles with the market, then sends announcements as they come in
+// and retries the ones that failed, until ctx is done. Reconciling is tried
+// again after the retry interval until it succeeds.
+func (announcer *Announcer) Run(ctx context.Context) {
+	reconciled := false
+	for {
+		if !reconciled {
+			if err := announcer.Reconcile(ctx); err != nil {
+				fmt.Printf("\nError reconciling stored files with the market: %s\n> ", err)
+			} else {
+				reconciled = true
+			}
+		}
+		announcer.Flush(ctx)
+
+		wait, ok := announcer.untilNext()
+		if !reconciled && (!ok || wait > announcer.retryInterval) {
+			wait, ok = announcer.retryInterval, true
+		}
+		var retry <-chan time.Time
+		var timer *time.Timer
+		if ok {
+			timer = time.NewTimer(wait)
+			retry = timer.C
+		}
+		select {
+		case <-ctx.Done():
+		case <-announcer.wake:
+		case <-retry:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// untilNext is how long until the next announcement is due, if there is one.
+func (announcer *Announcer) untilNext() (time.Duration, bool) {
+	announcer.mu.Lock()
+	defer announcer.mu.Unlock()
+	if len(announcer.pending) == 0 {
+		return 0, false
+	}
+	next := announcer.pending[0].Next
+	for _, announcement := range announcer.pending[1:] {
+		if announcement.Next.Before(next) {
+			next = announcement.Next
+		}
+	}
+	return max(next.Sub(announcer.now()), 0), true
+}
+
+// Flush sends every announcement that is due. Announcements the market
+// refuses are dropped, since sending them again would not change its mind.
+func (announcer *Announcer) Flush(ctx context.Context) {
+	announcer.mu.Lock()
+	now := announcer.now()
+	due := []*Announcement{}
+	for _, announcement := range announcer.pending {
+		if !announcement.Next.After(now) {
+			due = append(due, announcement)
+		}
+	}
+	announcer.mu.Unlock()
+
+	for _, announcement := range due {
+		if ctx.Err() != nil {
+			return
+		}
+		err := announcer.send(ctx, announcement)
+		if err != nil {
+			fmt.Printf("\nError announcing %s of %s to the market: %s\n> ", announcement.Op, announcement.CID, err)
+		}
+
+		announcer.mu.Lock()
+		// A newer change of the file replaced it while it was being sent
+		current := false
+		for _, pending := range announcer.pending {
+			current = current || pending == announcement
+		}
+		if current {
+			if err == nil || errors.Is(err, orcaClient.ErrMarketRejected) {
+				announcer.remove(announcement.CID)
+			} else {
+				announcement.Attempts++
+				announcement.Next = announcer.now().Add(announcer.backoff(announcement.Attempts))
+			}
+			if err := announcer.save(); err != nil {
+				fmt.Println("Error saving announcement outbox:", err)
+			}
+		}
+		announcer.mu.Unlock()
+	}
+}
+
+// backoff is the wait after the given number of failed attempts.
+func (announcer *Announcer) backoff(attempts int) time.Duration {
+	wait := announcer.retryInterval
+	for i := 1; i < attempts && wait < announcer.maxRetryInterval; i++ {
+		wait *= 2
+	}
+	return min(wait, announcer.maxRetryInterval)
+}
+
+func (announcer *Announcer) send(ctx context.Context, announcement *Announcement) error {
+	file := announcer.describe(announcement)
+	var ack *pb.StorageACKResponse
+	var err error
+	if announcement.Op == Unstore {
+		ack, err = announcer.market.NotifyUnstore(ctx, file)
+	} else {
+		ack, err = announcer.market.NotifyStore(ctx, file)
+	}
+	if err != nil {
+		return err
+	}
+	if !ack.GetIsAcknowledged() {
+		return errNotAcknowledged
+	}
+	return nil
+}
+
+// describe is the FileDesc an announcement is sent as. The market lists files
+// and their holders, it never gets their contents, so FileBytes stays empty.
+func (announcer *Announcer) describe(announcement *Announcement) *pb.FileDesc {
+	announcer.mu.Lock()
+	name := announcer.names[announcement.CID]
+	announcer.mu.Unlock()
+	return &pb.FileDesc{
+		FileNameHash:      announcement.CID,
+		FileName:          name,
+		FileSizeBytes:     announcement.Size,
+		FileOriginAddress: announcer.origin.Address,
+		OriginUserId:      announcer.origin.UserID,
+		FileCost:          float32(announcer.prices.Price(announcement.CID)),
+		// Files are named by the Merkle root of their data
+		FileDataHash: announcement.CID,
+	}
+}
+
+/*
+Reconcile asks the market which files it lists this node as a holder of, and
+queues a store for every stored file it does not list and an unstore for every
+listed file that is not stored anymore. Files with a pending announcement are
+left alone, the announcement already brings the market up to date.
+*/
+func (announcer *Announcer) Reconcile(ctx context.Context) error {
+	reconcileCtx, cancel := context.WithTimeout(ctx, reconcileTimeout)
+	defer cancel()
+	listed, err := announcer.market.AllFiles(reconcileCtx, &pb.StorageIP{UserId: announcer.origin.UserID, Address: announcer.origin.Address})
+	if err != nil {
+		return err
+	}
+	manifests, err := announcer.storage.ListFiles()
+	if err != nil {
+		return err
+	}
+
+	announcer.mu.Lock()
+	pending := make(map[string]bool, len(announcer.pending))
+	for _, announcement := range announcer.pending {
+		pending[announcement.CID] = true
+	}
+	announcer.mu.Unlock()
+
+	stored := make(map[string]bool, len(manifests))
+	for _, manifest := range manifests {
+		stored[manifest.Root] = true
+	}
+	onMarket := make(map[string]bool, len(listed))
+	for _, file := range listed {
+		onMarket[file.GetFileNameHash()] = true
+		if !stored[file.GetFileNameHash()] && !pending[file.GetFileNameHash()] {
+			announcer.enqueue(Unstore, file.GetFileNameHash(), file.GetFileSizeBytes())
+		}
+	}
+	for _, manifest := range manifests {
+		if !onMarket[manifest.Root] && !pending[manifest.Root] {
+			announcer.enqueue(Store, manifest.Root, manifest.Size)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package announce
+
+import (
+	"context"
+	orcaClient "orca-peer/internal/client"
+	pb "orca-peer/internal/fileshare"
+	"orca-peer/internal/hash"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeMarket struct {
+	mu        sync.Mutex
+	stored    []*pb.FileDesc
+	unstored  []*pb.FileDesc
+	listed    []*pb.FileDesc
+	asked     *pb.StorageIP
+	failures  int
+	rejecting bool
+}
+
+func (fake *fakeMarket) answer(file *pb.FileDesc) (*pb.StorageACKResponse, error) {
+	if fake.rejecting {
+		return nil, &orcaClient.MarketError{Op: "test", Err: orcaClient.ErrMarketRejected, Cause: status.Error(codes.InvalidArgument, "refused")}
+	}
+	if fake.failures > 0 {
+		fake.failures--
+		return nil, &orcaClient.MarketError{Op: "test", Err: orcaClient.ErrMarketUnavailable, Cause: status.Error(codes.Unavailable, "down")}
+	}
+	return &pb.StorageACKResponse{IsAcknowledged: true, FileHash: file.FileNameHash}, nil
+}
+
+func (fake *fakeMarket) NotifyStore(ctx context.Context, file *pb.FileDesc) (*pb.StorageACKResponse, error) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	ack, err := fake.answer(file)
+	if err == nil {
+		fake.stored = append(fake.stored, file)
+	}
+	return ack, err
+}
+
+func (fake *fakeMarket) NotifyUnstore(ctx context.Context, file *pb.FileDesc) (*pb.StorageACKResponse, error) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	ack, err := fake.answer(file)
+	if err == nil {
+		fake.unstored = append(fake.unstored, file)
+	}
+	return ack, err
+}
+
+func (fake *fakeMarket) AllFiles(ctx context.Context, me *pb.StorageIP) ([]*pb.FileDesc, error) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	fake.asked = me
+	return fake.listed, nil
+}
+
+type flatPrice float64
+
+func (price flatPrice) Price(fileHash string) float64 { return float64(price) }
+
+var testOrigin = Origin{UserID: "peer", Address: ":8000"}
+
+func TestAnnouncesStoreAndRemove(t *testing.T) {
+	storage := hash.NewDataStore(t.TempDir())
+	market := &fakeMarket{}
+	announcer, err := NewAnnouncer(t.TempDir(), storage, market, flatPrice(2.5), testOrigin, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	cid, err := storage.PutFile([]byte("hello market"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	announcer.Name(cid, "hello.txt")
+	// Naming the file replaced the announcement of the put
+	if pending := announcer.Pending(); len(pending) != 1 || pending[0].Op != Store {
+		t.Fatalf("expected one store to be pending, got %+v", pending)
+	}
+	announcer.Flush(ctx)
+	want := &pb.FileDesc{FileNameHash: cid, FileName: "hello.txt", FileSizeBytes: 12, FileOriginAddress: ":8000", OriginUserId: "peer", FileCost: 2.5, FileDataHash: cid}
+	if len(market.stored) != 1 || market.stored[0].String() != want.String() {
+		t.Fatalf("expected %v to be stored, got %v", want, market.stored)
+	}
+
+	if err := storage.RemoveFile(cid); err != nil {
+		t.Fatal(err)
+	}
+	announcer.Flush(ctx)
+	if len(market.unstored) != 1 || market.unstored[0].FileNameHash != cid || market.unstored[0].OriginUserId != "peer" {
+		t.Errorf("expected %s to be unstored, got %v", cid, market.unstored)
+	}
+	if pending := announcer.Pending(); len(pending) != 0 {
+		t.Errorf("expected nothing to be pending, got %+v", pending)
+	}
+}
+
+func TestOutboxSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	storage := hash.NewDataStore(t.TempDir())
+	market := &fakeMarket{failures: 2}
+	now := time.Now()
+	announcer, err := NewAnnouncer(dir, storage, market, flatPrice(1), testOrigin, Config{RetryInterval: 10, MaxRetryInterval: 15})
+	if err != nil {
+		t.Fatal(err)
+	}
+	announcer.now = func() time.Time { return now }
+	cid, err := storage.PutFile([]byte("retry me"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	announcer.Flush(context.Background())
+	announcer.Flush(context.Background())
+	pending := announcer.Pending()
+	if len(pending) != 1 || pending[0].Attempts != 1 || !pending[0].Next.Equal(now.Add(10*time.Second)) {
+		t.Fatalf("expected a single failed attempt to wait 10s, got %+v", pending)
+	}
+
+	restarted, err := NewAnnouncer(dir, hash.NewDataStore(t.TempDir()), market, flatPrice(1), testOrigin, Config{RetryInterval: 10, MaxRetryInterval: 15})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(10 * time.Second)
+	restarted.now = func() time.Time { return now }
+	restarted.Flush(context.Background())
+	// The wait doubles, up to the maximum
+	if pending := restarted.Pending(); len(pending) != 1 || pending[0].CID != cid || pending[0].Attempts != 2 || !pending[0].Next.Equal(now.Add(15*time.Second)) {
+		t.Fatalf("expected the announcement to be loaded and retried, got %+v", pending)
+	}
+	now = now.Add(15 * time.Second)
+	restarted.Flush(context.Background())
+	if len(market.stored) != 1 || len(restarted.Pending()) != 0 {
+		t.Errorf("expected the announcement to be sent after a restart, got %v and %+v", market.stored, restarted.Pending())
+	}
+
+	market.rejecting = true
+	restarted.enqueue(Unstore, cid, 8)
+	restarted.Flush(context.Background())
+	if pending := restarted.Pending(); len(pending) != 0 {
+		t.Errorf("expected a refused announcement to be dropped, got %+v", pending)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	storage := hash.NewDataStore(t.TempDir())
+	cid, err := storage.PutFile([]byte("stored while offline"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	listedCID, err := storage.PutFile([]byte("already listed"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	market := &fakeMarket{listed: []*pb.FileDesc{{FileNameHash: "gone", FileSizeBytes: 5}, {FileNameHash: listedCID}}}
+	announcer, err := NewAnnouncer(t.TempDir(), storage, market, flatPrice(1), testOrigin, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := announcer.Reconcile(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if market.asked.GetUserId() != "peer" {
+		t.Errorf("expected the market to be asked for the files of this node, got %v", market.asked)
+	}
+	want := map[string]Op{"gone": Unstore, cid: Store}
+	pending := announcer.Pending()
+	if len(pending) != len(want) {
+		t.Fatalf("expected %v, got %+v", want, pending)
+	}
+	for _, announcement := range pending {
+		if want[announcement.CID] != announcement.Op {
+			t.Errorf("expected %s to be %q, got %q", announcement.CID, want[announcement.CID], announcement.Op)
+		}
+	}
+}
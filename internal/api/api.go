@@ -1,17 +1,24 @@
 package api
 
 import (
+	"context"
 	"crypto/rsa"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
 	orcaHash "orca-peer/internal/hash"
+	"orca-peer/internal/txstore"
+	"orca-peer/internal/wallet"
 	"os"
 	"path/filepath"
+
+	"github.com/libp2p/go-libp2p/core/host"
 )
 
 type GetFileJSONBody struct {
@@ -27,6 +34,9 @@ var backend *Backend
 var peers *PeerStorage
 var publicKey *rsa.PublicKey
 var privateKey *rsa.PrivateKey
+var storage *orcaHash.DataStore
+var nodeWallet *wallet.Wallet
+var transactions *txstore.Store
 
 func getFile(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodPost {
@@ -262,6 +272,24 @@ func deleteFile(w http.ResponseWriter, r *http.Request) {
 				writeStatusUpdate(w, "Missing Filename and CID values inside of the payload.")
 				return
 			}
+			// Files in the content addressed store are removed by CID, which
+			// also stops them from being advertised
+			if payload.CID != "" {
+				err := storage.RemoveFile(payload.CID)
+				if errors.Is(err, fs.ErrNotExist) {
+					w.WriteHeader(http.StatusNotFound)
+					writeStatusUpdate(w, "No stored file with that CID.")
+					return
+				}
+				if err != nil {
+					w.WriteHeader(http.StatusInternalServerError)
+					writeStatusUpdate(w, "Error removing file from the store.")
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+				writeStatusUpdate(w, "File deleted successfully.")
+				return
+			}
 			fileDir := "./files"
 			var filePath string
 
@@ -626,8 +654,16 @@ func removePeer(w http.ResponseWriter, r *http.Request) {
 
 }
 
-func InitServer() {
+// TrackPeers fills the peer table from the connections of h.
+func TrackPeers(h host.Host) {
+	peers.Track(context.Background(), h)
+}
+
+func InitServer(dataStore *orcaHash.DataStore, userWallet *wallet.Wallet, transactionStore *txstore.Store) {
 	backend = NewBackend()
+	storage = dataStore
+	nodeWallet = userWallet
+	transactions = transactionStore
 	peers = NewPeerStorage()
 	publicKey, privateKey = orcaHash.LoadInKeys()
 	http.HandleFunc("/getFile", getFile)
@@ -647,4 +683,8 @@ func InitServer() {
 	http.HandleFunc("/sendMoney", sendMoney)
 	http.HandleFunc("/getLocation", getLocation)
 	http.HandleFunc("/hash", hashFile)
+	http.HandleFunc("/getBalance", getBalance)
+	http.HandleFunc("/getHistory", getHistory)
+	http.HandleFunc("/transactions", getTransactions)
+	http.HandleFunc("/transactions/", getTransaction)
 }
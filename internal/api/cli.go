@@ -3,9 +3,14 @@ package api
 import (
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	orcaClient "orca-peer/internal/client"
+	orcaHash "orca-peer/internal/hash"
 	orcaStatus "orca-peer/internal/status"
+	"orca-peer/internal/txstore"
+	"orca-peer/internal/wallet"
 	"os"
 )
 
@@ -20,6 +25,7 @@ type SendMoneyJSONRequest struct {
 	Amount     float64 `json:"amount"`
 	ServerIp   string  `json:"host"`
 	ServerPort string  `json:"port"`
+	FileHash   string  `json:"file_hash"`
 }
 
 func sendMoney(w http.ResponseWriter, r *http.Request) {
@@ -35,7 +41,25 @@ func sendMoney(w http.ResponseWriter, r *http.Request) {
 				writeStatusUpdate(w, "Cannot marshal payload in Go object. Does the payload have the correct body structure?")
 				return
 			}
-			orcaClient.SendTransaction(payload.Amount, payload.ServerIp, payload.ServerPort, publicKey, privateKey)
+			transaction, err := nodeWallet.Send(payload.Amount, func() (*orcaHash.Transaction, error) {
+				return orcaClient.SendTransaction(payload.Amount, payload.ServerIp, payload.ServerPort, payload.FileHash, publicKey, privateKey)
+			})
+			if errors.Is(err, wallet.ErrInsufficientFunds) || errors.Is(err, wallet.ErrInvalidAmount) {
+				w.WriteHeader(http.StatusBadRequest)
+				writeStatusUpdate(w, err.Error())
+				return
+			}
+			if err != nil {
+				w.WriteHeader(http.StatusBadGateway)
+				writeStatusUpdate(w, "Transaction failed: "+err.Error())
+				return
+			}
+			err = transactions.Put(txstore.Record{Direction: txstore.Sent, Transaction: *transaction})
+			if err != nil {
+				fmt.Println("Error storing sent transaction:", err)
+			}
+			w.WriteHeader(http.StatusOK)
+			writeStatusUpdate(w, "Sent transaction "+transaction.Uuid)
 			return
 		default:
 			w.WriteHeader(http.StatusBadRequest)
@@ -7,12 +7,13 @@ import (
 
 // PeerInfo struct holds the information about a peer.
 type PeerInfo struct {
-	Location    string `json:"location"`
-	Latency     string `json:"latency"`
-	PeerID      string `json:"peerID"`
-	Connection  string `json:"connection"`
-	OpenStreams string `json:"openStreams"`
-	FlagUrl     string `json:"flagUrl"`
+	Location    string   `json:"location"`
+	Latency     string   `json:"latency"`
+	PeerID      string   `json:"peerID"`
+	Multiaddrs  []string `json:"multiaddrs"`
+	Connection  string   `json:"connection"`
+	OpenStreams string   `json:"openStreams"`
+	FlagUrl     string   `json:"flagUrl"`
 }
 
 // PeerStorage is a concurrent safe storage for peer information.
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/p2p/protocol/ping"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+const (
+	// How often the latency and streams of connected peers are measured again
+	peerRefreshInterval = 30 * time.Second
+	// How many peers are pinged at the same time
+	maxConcurrentPings = 16
+	pingTimeout        = 10 * time.Second
+)
+
+/*
+Track keeps the storage in line with the connections of h. Peers are added as
+soon as they connect, with their addresses, the direction of the connection
+and the number of open streams. Their latency is measured with a ping every
+peerRefreshInterval, and they are removed once the last connection to them is
+closed. Location and flag set through /updatePeer are kept. Tracking stops
+when ctx is done.
+*/
+func (ps *PeerStorage) Track(ctx context.Context, h host.Host) {
+	notifiee := &network.NotifyBundle{
+		ConnectedF: func(n network.Network, conn network.Conn) {
+			ps.refresh(h, conn.RemotePeer())
+		},
+		DisconnectedF: func(n network.Network, conn network.Conn) {
+			if n.Connectedness(conn.RemotePeer()) != network.Connected {
+				ps.RemovePeer(conn.RemotePeer().String())
+			}
+		},
+	}
+	h.Network().Notify(notifiee)
+	for _, id := range h.Network().Peers() {
+		ps.refresh(h, id)
+	}
+
+	go func() {
+		defer h.Network().StopNotify(notifiee)
+		ticker := time.NewTicker(peerRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				ps.measureAll(ctx, h)
+			}
+		}
+	}()
+}
+
+// refresh records what the connections to id look like right now.
+func (ps *PeerStorage) refresh(h host.Host, id peer.ID) {
+	conns := h.Network().ConnsToPeer(id)
+	if len(conns) == 0 {
+		return
+	}
+	streams := 0
+	for _, conn := range conns {
+		streams += len(conn.GetStreams())
+	}
+	addrs := []string{}
+	for _, addr := range h.Peerstore().Addrs(id) {
+		addrs = append(addrs, addr.String())
+	}
+	if len(addrs) == 0 {
+		addrs = append(addrs, conns[0].RemoteMultiaddr().String())
+	}
+	latency := ""
+	if ewma := h.Peerstore().LatencyEWMA(id); ewma > 0 {
+		latency = ewma.String()
+	}
+
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	// A disconnect since the connections were read has already removed the
+	// peer, and must not be undone
+	if h.Network().Connectedness(id) != network.Connected {
+		return
+	}
+	info := ps.peers[id.String()]
+	info.PeerID = id.String()
+	info.Multiaddrs = addrs
+	info.Connection = directionOf(conns[0])
+	info.OpenStreams = strconv.Itoa(streams)
+	if latency != "" {
+		info.Latency = latency
+	}
+	ps.peers[info.PeerID] = info
+}
+
+// measureAll measures every connected peer, up to maxConcurrentPings at a
+// time, and returns once all of them are done.
+func (ps *PeerStorage) measureAll(ctx context.Context, h host.Host) {
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, maxConcurrentPings)
+	for _, id := range h.Network().Peers() {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+		wg.Add(1)
+		go func(id peer.ID) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			ps.measure(ctx, h, id)
+		}(id)
+	}
+	wg.Wait()
+}
+
+// measure pings id once, which also feeds the latency kept in the peerstore.
+func (ps *PeerStorage) measure(ctx context.Context, h host.Host, id peer.ID) {
+	pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	select {
+	case <-ping.Ping(pingCtx, h, id):
+	case <-pingCtx.Done():
+	}
+	ps.refresh(h, id)
+}
+
+func directionOf(conn network.Conn) string {
+	direction := conn.Stat().Direction.String()
+	if _, err := conn.RemoteMultiaddr().ValueForProtocol(ma.P_CIRCUIT); err == nil {
+		direction += " (relayed)"
+	}
+	return direction
+}
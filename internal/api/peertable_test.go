@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+func newTestHost(t *testing.T) host.Host {
+	h, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { h.Close() })
+	return h
+}
+
+func waitFor(t *testing.T, what string, done func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for " + what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestTrackFollowsConnections(t *testing.T) {
+	local := newTestHost(t)
+	remote := newTestHost(t)
+	ps := NewPeerStorage()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ps.Track(ctx, local)
+
+	// Set through the API before the peer connects, and kept after
+	ps.AddPeer(PeerInfo{PeerID: remote.ID().String(), Location: "Stony Brook"})
+	if err := local.Connect(ctx, peer.AddrInfo{ID: remote.ID(), Addrs: remote.Addrs()}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the peer to be recorded", func() bool {
+		info, _ := ps.GetPeer(remote.ID().String())
+		return info.Connection != ""
+	})
+	info, _ := ps.GetPeer(remote.ID().String())
+	if info.Connection != "Outbound" || len(info.Multiaddrs) == 0 || info.Location != "Stony Brook" {
+		t.Errorf("unexpected peer %+v", info)
+	}
+
+	ps.measureAll(ctx, local)
+	if info, _ := ps.GetPeer(remote.ID().String()); info.Latency == "" {
+		t.Error("expected latency to be measured")
+	}
+
+	remote.Close()
+	waitFor(t, "the peer to be pruned", func() bool {
+		_, exists := ps.GetPeer(remote.ID().String())
+		return !exists
+	})
+	// A refresh that comes after the disconnect does not bring it back
+	ps.refresh(local, remote.ID())
+	if _, exists := ps.GetPeer(remote.ID().String()); exists {
+		t.Error("expected a disconnected peer to stay removed")
+	}
+}
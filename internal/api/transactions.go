@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"orca-peer/internal/txstore"
+	"strings"
+	"time"
+)
+
+// getTransactions lists stored transactions. The peer, file_hash, from and to
+// query parameters narrow the list down, from and to are RFC3339 times, and
+// format=csv returns CSV instead of JSON.
+func getTransactions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		writeStatusUpdate(w, "Only GET requests will be handled.")
+		return
+	}
+	query := r.URL.Query()
+	filter := txstore.Filter{Peer: query.Get("peer"), FileHash: query.Get("file_hash")}
+	for _, bound := range []struct {
+		name  string
+		value *time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		if query.Get(bound.name) == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, query.Get(bound.name))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			writeStatusUpdate(w, "The "+bound.name+" parameter must be an RFC3339 time")
+			return
+		}
+		*bound.value = parsed
+	}
+
+	records := transactions.List(filter)
+	switch query.Get("format") {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		txstore.ExportJSON(w, records)
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", "attachment; filename=transactions.csv")
+		w.WriteHeader(http.StatusOK)
+		txstore.ExportCSV(w, records)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		writeStatusUpdate(w, "Format must be json or csv")
+	}
+}
+
+// getTransaction returns the transaction at /transactions/:uuid.
+func getTransaction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		writeStatusUpdate(w, "Only GET requests will be handled.")
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/transactions/")
+	if id == "" {
+		getTransactions(w, r)
+		return
+	}
+	record, err := transactions.Get(id)
+	if errors.Is(err, txstore.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		writeStatusUpdate(w, "Transaction not found")
+		return
+	}
+	jsonData, err := json.Marshal(record)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		writeStatusUpdate(w, "Failed to convert JSON Data into a string")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonData)
+}
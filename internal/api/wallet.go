@@ -0,0 +1,47 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+type BalanceResponse struct {
+	Balance   float64 `json:"balance"`
+	Available float64 `json:"available"`
+}
+
+func getBalance(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		jsonData, err := json.Marshal(BalanceResponse{Balance: nodeWallet.Balance(), Available: nodeWallet.Available()})
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			writeStatusUpdate(w, "Failed to convert JSON Data into a string")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(jsonData)
+	} else {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		writeStatusUpdate(w, "Only GET requests will be handled.")
+		return
+	}
+}
+
+func getHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		jsonData, err := json.Marshal(nodeWallet.History())
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			writeStatusUpdate(w, "Failed to convert JSON Data into a string")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(jsonData)
+	} else {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		writeStatusUpdate(w, "Only GET requests will be handled.")
+		return
+	}
+}
@@ -0,0 +1,172 @@
+package approval
+
+import (
+	"context"
+	"fmt"
+	"orca-peer/internal/reputation"
+	"strings"
+)
+
+type Decision int
+
+const (
+	// Abstain leaves the decision to the next policy in a chain
+	Abstain Decision = iota
+	Accept
+	Deny
+)
+
+func (decision Decision) String() string {
+	switch decision {
+	case Accept:
+		return "accept"
+	case Deny:
+		return "deny"
+	default:
+		return "abstain"
+	}
+}
+
+type Kind string
+
+const (
+	SendFile  Kind = "send"
+	StoreFile Kind = "store"
+)
+
+// Request describes what a peer is asking this node to do.
+type Request struct {
+	Kind     Kind
+	Peer     string
+	FileHash string
+	FileName string
+	Size     int64
+}
+
+// Question is what an operator is asked when a request needs a person to
+// decide on it.
+func (request Request) Question() string {
+	name := request.FileName
+	if name == "" {
+		name = request.FileHash
+	}
+	switch request.Kind {
+	case StoreFile:
+		return fmt.Sprintf("%s is asking you to store file '%s' (%d bytes). Do you want to store the file?", request.Peer, name, request.Size)
+	default:
+		return fmt.Sprintf("%s is asking you to send file '%s' (%d bytes). Do you want to send the file?", request.Peer, name, request.Size)
+	}
+}
+
+// Policy decides whether a request from a peer is served. Decide may block
+// until ctx is done, in which case the request is denied.
+type Policy interface {
+	Decide(ctx context.Context, request Request) Decision
+}
+
+// Approve runs a request through policy. Anything that is not explicitly
+// accepted is denied.
+func Approve(ctx context.Context, policy Policy, request Request) bool {
+	if policy == nil {
+		return false
+	}
+	return policy.Decide(ctx, request) == Accept
+}
+
+type AutoAccept struct{}
+
+func (AutoAccept) Decide(ctx context.Context, request Request) Decision {
+	return Accept
+}
+
+type AutoDeny struct{}
+
+func (AutoDeny) Decide(ctx context.Context, request Request) Decision {
+	return Deny
+}
+
+// List accepts or denies requests by the peer making them or the file they are
+// about. Deny entries win over allow entries, and requests matching neither
+// are left to the next policy.
+type List struct {
+	AllowPeers  map[string]bool
+	DenyPeers   map[string]bool
+	AllowHashes map[string]bool
+	DenyHashes  map[string]bool
+}
+
+func NewList(allowPeers, denyPeers, allowHashes, denyHashes []string) *List {
+	return &List{
+		AllowPeers:  toSet(allowPeers),
+		DenyPeers:   toSet(denyPeers),
+		AllowHashes: toSet(allowHashes),
+		DenyHashes:  toSet(denyHashes),
+	}
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[strings.TrimSpace(value)] = true
+	}
+	return set
+}
+
+func (list *List) Decide(ctx context.Context, request Request) Decision {
+	// Lists can name a host without knowing which port it connects from
+	peer := reputation.Key(request.Peer)
+	if list.DenyPeers[request.Peer] || list.DenyPeers[peer] || list.DenyHashes[request.FileHash] {
+		return Deny
+	}
+	if list.AllowPeers[request.Peer] || list.AllowPeers[peer] || list.AllowHashes[request.FileHash] {
+		return Accept
+	}
+	return Abstain
+}
+
+// MaxSize denies requests for files larger than Limit bytes.
+type MaxSize struct {
+	Limit int64
+}
+
+func (maxSize MaxSize) Decide(ctx context.Context, request Request) Decision {
+	if maxSize.Limit > 0 && request.Size > maxSize.Limit {
+		return Deny
+	}
+	return Abstain
+}
+
+// Scorer tells how much a peer is trusted.
+type Scorer interface {
+	Score(peer string) float64
+}
+
+// MinScore denies requests from peers whose score is below Threshold.
+type MinScore struct {
+	Scores    Scorer
+	Threshold float64
+}
+
+func (minScore MinScore) Decide(ctx context.Context, request Request) Decision {
+	if minScore.Scores != nil && minScore.Scores.Score(request.Peer) < minScore.Threshold {
+		return Deny
+	}
+	return Abstain
+}
+
+// Chain asks each policy in order and returns the first decision that is not
+// Abstain. If every policy abstains, Default is returned.
+type Chain struct {
+	Policies []Policy
+	Default  Decision
+}
+
+func (chain *Chain) Decide(ctx context.Context, request Request) Decision {
+	for _, policy := range chain.Policies {
+		decision := policy.Decide(ctx, request)
+		if decision != Abstain {
+			return decision
+		}
+	}
+	return chain.Default
+}
@@ -0,0 +1,115 @@
+package approval
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewPolicy(t *testing.T) {
+	config := Config{
+		Mode:        ModeAccept,
+		DenyPeers:   []string{"10.0.0.9", "2001:db8::9"},
+		AllowHashes: []string{"good"},
+		DenyHashes:  []string{"bad"},
+		MaxSize:     1000,
+	}
+	policy, interactive, err := NewPolicy(config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if interactive != nil {
+		t.Errorf("expected no prompt queue outside interactive mode")
+	}
+	tests := []struct {
+		request Request
+		want    Decision
+	}{
+		{Request{Peer: "10.0.0.1:5000", FileHash: "any", Size: 10}, Accept},
+		{Request{Peer: "10.0.0.9:5000", FileHash: "good", Size: 10}, Deny},
+		{Request{Peer: "[2001:db8::9]:5000", FileHash: "any", Size: 10}, Deny},
+		{Request{Peer: "2001:db8::1", FileHash: "any", Size: 10}, Accept},
+		{Request{Peer: "10.0.0.1:5000", FileHash: "bad", Size: 10}, Deny},
+		{Request{Peer: "10.0.0.1:5000", FileHash: "any", Size: 5000}, Deny},
+		{Request{Peer: "10.0.0.1:5000", FileHash: "good", Size: 5000}, Accept},
+	}
+	for _, test := range tests {
+		if got := policy.Decide(context.Background(), test.request); got != test.want {
+			t.Errorf("Decide(%+v) = %s, want %s", test.request, got, test.want)
+		}
+	}
+
+	config.Mode = ModeDeny
+	policy, _, _ = NewPolicy(config, nil)
+	if got := policy.Decide(context.Background(), Request{Peer: "10.0.0.1:5000"}); got != Deny {
+		t.Errorf("expected deny mode to deny unlisted requests, got %s", got)
+	}
+	if _, _, err := NewPolicy(Config{Mode: "maybe"}, nil); err == nil {
+		t.Errorf("expected unknown mode to be rejected")
+	}
+}
+
+type fixedScores map[string]float64
+
+func (scores fixedScores) Score(peer string) float64 {
+	return scores[peer]
+}
+
+func TestMinScoreDeniesDistrustedPeers(t *testing.T) {
+	scores := fixedScores{"cheater:1": -30, "honest:1": 12}
+	// Lists are checked first, so an operator can let a distrusted peer back in
+	config := Config{Mode: ModeAccept, AllowPeers: []string{"cheater"}, MinScore: -10}
+	policy, _, _ := NewPolicy(config, scores)
+	for peer, want := range map[string]Decision{"cheater:1": Accept, "newcomer:1": Accept, "honest:1": Accept} {
+		if got := policy.Decide(context.Background(), Request{Peer: peer}); got != want {
+			t.Errorf("Decide(%s) = %s, want %s", peer, got, want)
+		}
+	}
+
+	config.AllowPeers = nil
+	policy, _, _ = NewPolicy(config, scores)
+	if got := policy.Decide(context.Background(), Request{Peer: "cheater:1"}); got != Deny {
+		t.Errorf("expected a low score to be denied, got %s", got)
+	}
+}
+
+func TestInteractiveAnswersEachRequestOnce(t *testing.T) {
+	interactive := NewInteractive()
+	results := make(map[string]Decision)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, peer := range []string{"a:1", "b:1"} {
+		wg.Add(1)
+		go func(peer string) {
+			defer wg.Done()
+			decision := interactive.Decide(context.Background(), Request{Peer: peer})
+			mu.Lock()
+			results[peer] = decision
+			mu.Unlock()
+		}(peer)
+	}
+
+	// Accept the first request that shows up and deny the second
+	(<-interactive.Prompts()).Respond(true)
+	(<-interactive.Prompts()).Respond(false)
+	wg.Wait()
+	accepted := 0
+	for _, decision := range results {
+		if decision == Accept {
+			accepted++
+		}
+	}
+	if len(results) != 2 || accepted != 1 {
+		t.Errorf("expected exactly one of two requests to be accepted, got %v", results)
+	}
+}
+
+func TestInteractiveGivesUpWhenRequestIsCancelled(t *testing.T) {
+	interactive := NewInteractive()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if interactive.Ask(ctx, "anyone there?") {
+		t.Errorf("expected unanswered prompt to be declined")
+	}
+}
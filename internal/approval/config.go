@@ -0,0 +1,55 @@
+package approval
+
+import "fmt"
+
+// Modes decide what happens to requests no list or size limit settles
+const (
+	ModeInteractive = "interactive"
+	ModeAccept      = "accept"
+	ModeDeny        = "deny"
+)
+
+type Config struct {
+	Mode        string   `json:"mode"`
+	AllowPeers  []string `json:"allow_peers"`
+	DenyPeers   []string `json:"deny_peers"`
+	AllowHashes []string `json:"allow_hashes"`
+	DenyHashes  []string `json:"deny_hashes"`
+	MaxSize     int64    `json:"max_size"`
+	// Peers with a lower reputation are denied. 0 turns the check off, new
+	// peers start with a score of 0.
+	MinScore float64 `json:"min_score"`
+}
+
+/*
+NewPolicy builds the policy described by config. Lists are checked first, then
+the size limit and the reputation of the peer in scores, which may be nil, and
+whatever is left is handled according to the mode. In interactive mode the
+returned Interactive must be served by someone that can answer prompts,
+otherwise it is nil.
+*/
+func NewPolicy(config Config, scores Scorer) (Policy, *Interactive, error) {
+	chain := &Chain{
+		Policies: []Policy{
+			NewList(config.AllowPeers, config.DenyPeers, config.AllowHashes, config.DenyHashes),
+			MaxSize{Limit: config.MaxSize},
+		},
+		Default: Deny,
+	}
+	if config.MinScore != 0 && scores != nil {
+		chain.Policies = append(chain.Policies, MinScore{Scores: scores, Threshold: config.MinScore})
+	}
+	var interactive *Interactive
+	switch config.Mode {
+	case ModeInteractive, "":
+		interactive = NewInteractive()
+		chain.Policies = append(chain.Policies, interactive)
+	case ModeAccept:
+		chain.Default = Accept
+	case ModeDeny:
+		chain.Default = Deny
+	default:
+		return nil, nil, fmt.Errorf("unknown approval mode %q", config.Mode)
+	}
+	return chain, interactive, nil
+}
@@ -0,0 +1,64 @@
+package approval
+
+import (
+	"context"
+	"sync"
+)
+
+// Prompt is a question waiting for an operator to answer it.
+type Prompt struct {
+	Question string
+	answer   chan bool
+	once     sync.Once
+}
+
+// Respond answers the prompt. Only the first answer counts.
+func (prompt *Prompt) Respond(accept bool) {
+	prompt.once.Do(func() {
+		prompt.answer <- accept
+	})
+}
+
+/*
+Interactive hands requests to an operator through a queue of prompts. Prompts
+are delivered one at a time in the order they were asked, so two requests that
+arrive together can never be answered with the same reply. Whoever reads from
+Prompts, usually the CLI, shows the question and calls Respond.
+*/
+type Interactive struct {
+	prompts chan *Prompt
+}
+
+func NewInteractive() *Interactive {
+	return &Interactive{prompts: make(chan *Prompt)}
+}
+
+func (interactive *Interactive) Prompts() <-chan *Prompt {
+	return interactive.prompts
+}
+
+// Ask queues question and waits for the answer. If ctx is done first, for
+// example because the peer hung up, the answer is no.
+func (interactive *Interactive) Ask(ctx context.Context, question string) bool {
+	prompt := &Prompt{Question: question, answer: make(chan bool, 1)}
+	select {
+	case interactive.prompts <- prompt:
+	case <-ctx.Done():
+		return false
+	}
+	select {
+	case accept := <-prompt.answer:
+		return accept
+	case <-ctx.Done():
+		// Make sure a late answer does not block whoever gives it
+		prompt.Respond(false)
+		return false
+	}
+}
+
+func (interactive *Interactive) Decide(ctx context.Context, request Request) Decision {
+	if interactive.Ask(ctx, request.Question()) {
+		return Accept
+	}
+	return Deny
+}
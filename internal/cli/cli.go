@@ -2,62 +2,176 @@ package cli
 
 import (
 	"bufio"
+	"context"
 	"crypto/rsa"
+	"errors"
 	"fmt"
+	"io"
 	"net"
+	"orca-peer/internal/announce"
+	"orca-peer/internal/approval"
 	orcaClient "orca-peer/internal/client"
+	"orca-peer/internal/config"
+	"orca-peer/internal/fileshare"
 	orcaHash "orca-peer/internal/hash"
+	"orca-peer/internal/p2p"
+	"orca-peer/internal/payment"
+	"orca-peer/internal/pricing"
+	"orca-peer/internal/reprovide"
+	"orca-peer/internal/reputation"
 	orcaServer "orca-peer/internal/server"
 	orcaStatus "orca-peer/internal/status"
 	orcaStore "orca-peer/internal/store"
+	"orca-peer/internal/txstore"
+	"orca-peer/internal/wallet"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 )
 
-func StartCLI(bootstrapAddress *string, pubKey *rsa.PublicKey, privKey *rsa.PrivateKey) {
+func StartCLI(pubKey *rsa.PublicKey, privKey *rsa.PrivateKey, nodeConfig *config.Config) {
 	fmt.Println("Loading...")
-	*bootstrapAddress = "local"
-	ctx, dht := orcaServer.CreateDHTConnection(bootstrapAddress)
+	ctx, dht, err := orcaServer.CreateDHTConnection(nodeConfig.Network, privKey)
+	if err != nil {
+		fmt.Println("Error joining the network:", err)
+		os.Exit(1)
+	}
 	fmt.Println("Welcome to Orcanet!")
 	fmt.Println("Dive In and Explore! Type 'help' for available commands.")
 	port := getPort()
 	serverReady := make(chan bool)
-	confirming := false
-	confirmation := ""
-	go orcaServer.StartServer(port, serverReady, &confirming, &confirmation)
+	scores, err := reputation.Open("files/reputation/", nodeConfig.Reputation)
+	if err != nil {
+		fmt.Println("Error opening reputation scores:", err)
+		os.Exit(1)
+	}
+	policy, interactive, err := approval.NewPolicy(nodeConfig.Approval, scores)
+	if err != nil {
+		fmt.Println("Error in approval config:", err)
+		os.Exit(1)
+	}
+	storage := orcaHash.NewDataStore("files/stored/")
+	userWallet, err := wallet.Open("files/wallet/", orcaStatus.GetNodeInfo().Balance)
+	if err != nil {
+		fmt.Println("Error opening wallet:", err)
+		os.Exit(1)
+	}
+	transactions, err := txstore.Open("files/transactions/")
+	if err != nil {
+		fmt.Println("Error opening transaction store:", err)
+		os.Exit(1)
+	}
+	if err := nodeConfig.Reprovide.Validate(); err != nil {
+		fmt.Println("Error in reprovide config:", err)
+		os.Exit(1)
+	}
+	if err := nodeConfig.Download.Validate(); err != nil {
+		fmt.Println("Error in download config:", err)
+		os.Exit(1)
+	}
+	prices, err := pricing.NewEngine(nodeConfig.Pricing)
+	if err != nil {
+		fmt.Println("Error in pricing config:", err)
+		os.Exit(1)
+	}
+	payments := payment.NewManager(nodeConfig.Payment, prices)
+	go orcaServer.StartServer(port, serverReady, storage, policy, payments, pubKey, userWallet, transactions, scores, dht.Host(), nodeConfig.RPC)
 	<-serverReady
 
-	reader := bufio.NewReader(os.Stdin)
-	client := orcaClient.NewClient("files/names/")
+	// Without a public address, peers reach this node by its peer ID
+	publicAddress := nodeConfig.Network.PublicAddress
+	announcer := &orcaServer.DHTAnnouncer{DHT: dht, Address: publicAddress, Prices: prices}
+	reprovider := reprovide.NewReprovider(storage, announcer, nodeConfig.Reprovide)
+	go reprovider.Run(ctx)
 
-	for {
-		fmt.Print("> ")
-		text, err := reader.ReadString('\n')
+	lines := readLines()
+	client := orcaClient.NewClient("files/names/", privKey)
+	client.UseHost(dht.Host(), dht)
+	client.UseReputation(scores)
+	market, err := dialMarket(nodeConfig.Market, dht.Host().ID().String(), port)
+	if err != nil {
+		fmt.Println("Error in market config:", err)
+		os.Exit(1)
+	}
+	// Files stored before the market was set up are caught by reconciling
+	var announcements *announce.Announcer
+	if market != nil {
+		// The market lists an origin without a host at the address its
+		// calls come from
+		origin := announce.Origin{UserID: dht.Host().ID().String(), Address: publicAddress}
+		if origin.Address == "" {
+			origin.Address = ":" + port
+		}
+		announcements, err = announce.NewAnnouncer("files/announce/", storage, market, prices, origin, nodeConfig.Announce)
+		if err != nil {
+			fmt.Println("Error opening announcement outbox:", err)
+			os.Exit(1)
+		}
+		go announcements.Run(ctx)
+	}
+	if nodeConfig.Ledger.Address != "" {
+		ledger, conn, err := orcaClient.DialRPC(&nodeConfig.Ledger.Address)
 		if err != nil {
-			fmt.Fprintln(os.Stderr, "Error reading from stdin:", err)
+			fmt.Println("Error connecting to the ledger:", err)
+			os.Exit(1)
+		}
+		defer conn.Close()
+		client.UseLedger(ledger)
+	}
+	networkStatus, err := p2p.NewStatus(dht.Host())
+	if err != nil {
+		fmt.Println("Error following network status:", err)
+		os.Exit(1)
+	}
+
+	// Prompts are only taken from the queue while none is waiting for an
+	// answer, so every answer goes to the question that was just printed
+	// Questions of the CLI itself, like whether to accept a quote, go
+	// through the same queue
+	if interactive == nil {
+		interactive = approval.NewInteractive()
+	}
+	prompts := interactive.Prompts()
+	var pending *approval.Prompt
+
+	fmt.Print("> ")
+	for {
+		var text string
+		if pending == nil {
+			select {
+			case prompt := <-prompts:
+				pending = prompt
+				fmt.Printf("\n%s (yes/no): ", prompt.Question)
+				continue
+			case line, ok := <-lines:
+				if !ok {
+					return
+				}
+				text = line
+			}
+		} else {
+			line, ok := <-lines
+			if !ok {
+				pending.Respond(false)
+				return
+			}
+			pending.Respond(strings.TrimSpace(line) == "yes")
+			pending = nil
+			fmt.Print("> ")
 			continue
 		}
 
-		text = strings.TrimSpace(text)
 		parts := strings.Fields(text)
 		if len(parts) == 0 {
+			fmt.Print("> ")
 			continue
 		}
 
 		command := parts[0]
 		args := parts[1:]
 
-		if confirming {
-			switch command {
-			case "yes":
-				confirmation = "yes"
-			default:
-				confirmation = "no"
-			}
-			continue
-		}
-
 		switch command {
 		case "get":
 			if len(args) == 3 {
@@ -70,49 +184,152 @@ func StartCLI(bootstrapAddress *string, pubKey *rsa.PublicKey, privKey *rsa.Priv
 			if len(args) == 1 {
 				go orcaServer.SearchKey(ctx, dht, args[0])
 			} else {
-				fmt.Println("Usage: getKey [key]")
+				fmt.Println("Usage: getKey [file hash]")
 				fmt.Println()
 			}
 		case "putKey":
 			if len(args) == 2 {
-				go orcaServer.PlaceKey(ctx, dht, args[0], args[1])
+				go orcaServer.PlaceKey(ctx, dht, args[0], args[1], prices.Price(args[0]))
 			} else {
-				fmt.Println("Usage: putKey [key] [value]")
+				fmt.Println("Usage: putKey [file hash] [ip:port]")
 				fmt.Println()
 			}
 		case "fileGet":
-			if len(args) == 1 {
+			if len(args) >= 1 && len(args) <= 3 {
+				downloadConfig := nodeConfig.Download
+				if len(args) >= 2 {
+					downloadConfig.Strategy = args[1]
+				}
+				if len(args) == 3 {
+					downloadConfig.MaxPrice, err = strconv.ParseFloat(args[2], 64)
+					if err != nil {
+						fmt.Println("Error: max price must be a number")
+						fmt.Println()
+						break
+					}
+				}
+				if err := downloadConfig.Validate(); err != nil {
+					fmt.Println("Error:", err)
+					fmt.Println()
+					break
+				}
 				go func() {
-					addresses := orcaServer.SearchKey(ctx, dht, args[0])
-					for _, address := range addresses {
-						addressParts := strings.Split(address, ":")
-						if len(addressParts) == 2 {
-							client.GetFileOnce(addressParts[0], addressParts[1], args[0])
-						} else {
-							fmt.Println("Error, got invalid address from DHT")
+					providers := orcaServer.SearchKey(ctx, dht, args[0])
+					holders := make([]orcaClient.Holder, 0, len(providers))
+					for _, provider := range providers {
+						// Holders are reached by peer ID, the address is only
+						// the fallback
+						holder := orcaClient.Holder{PeerID: provider.Peer, Price: provider.Price}
+						if provider.Address != "" {
+							if host, _, err := net.SplitHostPort(provider.Address); err == nil && host != "" {
+								holder.Address = provider.Address
+							} else {
+								fmt.Println("Error, got invalid address from DHT")
+							}
 						}
+						holders = append(holders, holder)
 					}
+					if market != nil {
+						marketHolders, err := market.CheckHolders(ctx, args[0])
+						if err != nil {
+							fmt.Println("Error asking the market for holders:", err)
+						}
+						holders = orcaClient.MergeHolders(holders, marketHolders)
+					}
+					quote, err := client.Quote(ctx, holders, args[0], downloadConfig)
+					if err != nil {
+						fmt.Printf("\nError getting a quote for %s: %s\n> ", args[0], err)
+						return
+					}
+					if !interactive.Ask(ctx, describeQuote(quote, scores)) {
+						fmt.Println("Download cancelled")
+						return
+					}
+					err = client.GetFileSwarm(context.Background(), quote.Holders, args[0], downloadConfig.MaxPrice)
+					if errors.Is(err, orcaClient.ErrNotRecorded) {
+						fmt.Printf("\nFile %s downloaded successfully, but %s\n> ", args[0], err)
+						return
+					}
+					if err != nil {
+						fmt.Printf("\nError downloading %s: %s\n> ", args[0], err)
+						return
+					}
+					fmt.Printf("\nFile %s downloaded successfully!\n> ", args[0])
 				}()
 			} else {
-				fmt.Println("Usage: fileGet [file hash]")
+				fmt.Println("Usage: fileGet [file hash] [cheapest|fastest|balanced] [max price per MB]")
 				fmt.Println()
 			}
 		case "fileStore":
 			if len(args) == 1 {
 				go func() {
-					fileHash, err := orcaHash.HashFile(args[0])
+					// Keep a copy in the content addressed store so that peers can
+					// request it by hash
+					data, err := os.ReadFile(filepath.Join("./files/", args[0]))
+					if err != nil {
+						fmt.Println(err)
+						return
+					}
+					fileHashStr, err := storage.PutFile(data)
 					if err != nil {
 						fmt.Println(err)
 						return
 					}
-					fileHashStr := string(fileHash)
-					address := "localhost" + ":" + port
-					orcaServer.PlaceKey(ctx, dht, fileHashStr, address)
+					orcaServer.PlaceKey(ctx, dht, fileHashStr, publicAddress, prices.Price(fileHashStr))
+					// Storing the file already queued its announcement to the
+					// market, naming it lists it under its file name
+					if announcements != nil {
+						announcements.Name(fileHashStr, filepath.Base(args[0]))
+					}
 				}()
 			} else {
 				fmt.Println("Usage: fileStore [file path]")
 				fmt.Println()
 			}
+		case "rpcGet":
+			if len(args) == 2 {
+				go func() {
+					rpc, conn, err := orcaClient.DialRPC(&args[0])
+					if err != nil {
+						fmt.Printf("\nError connecting to %s: %s\n> ", args[0], err)
+						return
+					}
+					defer conn.Close()
+					if err := client.GetFileRPC(ctx, rpc, args[1]); err != nil {
+						fmt.Printf("\nError downloading %s: %s\n> ", args[1], err)
+						return
+					}
+					fmt.Printf("\nFile %s downloaded successfully!\n> ", args[1])
+				}()
+			} else {
+				fmt.Println("Usage: rpcGet [ip:port] [file hash]")
+				fmt.Println()
+			}
+		case "rpcStore":
+			if len(args) == 2 {
+				go func() {
+					data, err := os.ReadFile(filepath.Join("./files/", args[1]))
+					if err != nil {
+						fmt.Printf("\n%s\n> ", err)
+						return
+					}
+					rpc, conn, err := orcaClient.DialRPC(&args[0])
+					if err != nil {
+						fmt.Printf("\nError connecting to %s: %s\n> ", args[0], err)
+						return
+					}
+					defer conn.Close()
+					ack, err := orcaClient.SendFileToStore(ctx, rpc, filepath.Base(args[1]), data)
+					if err != nil {
+						fmt.Printf("\nError storing %s: %s\n> ", args[1], err)
+						return
+					}
+					fmt.Printf("\n%s stored %s with hash %s\n> ", args[0], ack.FileName, ack.FileHash)
+				}()
+			} else {
+				fmt.Println("Usage: rpcStore [ip:port] [filename]")
+				fmt.Println()
+			}
 		case "store":
 			if len(args) == 3 {
 				go client.RequestStorage(args[0], args[1], args[2])
@@ -139,7 +356,7 @@ func StartCLI(bootstrapAddress *string, pubKey *rsa.PublicKey, privKey *rsa.Priv
 			}
 
 		case "list":
-			files := orcaStore.GetAllLocalFiles()
+			files := orcaStore.GetAllLocalFiles(storage)
 			fmt.Print("Files found:")
 			for _, file := range files {
 				fmt.Println(file.Name)
@@ -156,14 +373,57 @@ func StartCLI(bootstrapAddress *string, pubKey *rsa.PublicKey, privKey *rsa.Priv
 				cost, err := strconv.ParseFloat(args[0], 64)
 				if err != nil {
 					fmt.Println("Error parsing amount to send")
-					continue
+					break
 				}
-				orcaClient.SendTransaction(cost, args[1], args[2], pubKey, privKey)
+				go func() {
+					transaction, err := userWallet.Send(cost, func() (*orcaHash.Transaction, error) {
+						return orcaClient.SendTransaction(cost, args[1], args[2], "", pubKey, privKey)
+					})
+					if err != nil {
+						fmt.Printf("\nError sending transaction: %s\n> ", err)
+						return
+					}
+					err = transactions.Put(txstore.Record{Direction: txstore.Sent, Transaction: *transaction})
+					if err != nil {
+						fmt.Println("Error storing sent transaction:", err)
+					}
+					fmt.Printf("\nSent %f in transaction %s\n> ", transaction.Amount, transaction.Uuid)
+				}()
 			} else {
 				fmt.Println("Usage: send [amount] [ip] [port]")
 				fmt.Println()
 			}
 
+		case "balance":
+			fmt.Printf("Balance: %f (available: %f)\n", userWallet.Balance(), userWallet.Available())
+		case "history":
+			for _, entry := range userWallet.History() {
+				fmt.Printf("%d %s %s %f balance %f %s %s\n", entry.Seq, entry.Timestamp, entry.Kind, entry.Amount, entry.Balance, entry.Counterparty, entry.FileHash)
+			}
+		case "status":
+			direct, relayed := networkStatus.Addrs()
+			fmt.Println("Peer ID:", dht.Host().ID())
+			fmt.Println("Reachability:", networkStatus.Reachability())
+			fmt.Println("Connected peers:", networkStatus.Peers())
+			fmt.Println("Addresses:")
+			for _, addr := range direct {
+				fmt.Printf("  %s/p2p/%s\n", addr, dht.Host().ID())
+			}
+			fmt.Println("Relay addresses:")
+			for _, addr := range relayed {
+				fmt.Printf("  %s/p2p/%s\n", addr, dht.Host().ID())
+			}
+		case "providing":
+			stats := reprovider.Stats()
+			fmt.Printf("Advertising %d files, %d announcements, %d failed, %d withdrawn in %d runs\n", stats.Providing, stats.Announced, stats.Failed, stats.Withdrawn, stats.Runs)
+		case "reputation":
+			ranking := scores.Ranking()
+			if len(ranking) == 0 {
+				fmt.Println("No peers rated yet")
+			}
+			for _, entry := range ranking {
+				fmt.Printf("%8.2f  %s  %v\n", entry.Score, entry.Peer, entry.Events)
+			}
 		case "exit":
 			fmt.Println("Exiting...")
 			return
@@ -187,11 +447,19 @@ func StartCLI(bootstrapAddress *string, pubKey *rsa.PublicKey, privKey *rsa.Priv
 			fmt.Println(" store [ip] [port] [filename]   Request storage of a file")
 			fmt.Println(" getdir [ip] [port] [path]      Request a directory")
 			fmt.Println(" storedir [ip] [port] [path]    Request storage of a directory")
-			fmt.Println(" putKey [key] [value]           Put a key in the DHT")
-			fmt.Println(" getKey [key]                   Retreieve key from DHT")
+			fmt.Println(" putKey [fileHash] [ip:port]    Advertise a file in the DHT")
+			fmt.Println(" getKey [fileHash]              Find who advertises a file in the DHT")
 			fmt.Println(" import [filepath]              Import a file")
-			fmt.Println(" fileGet [fileHash]             Get the file from the network")
+			fmt.Println(" fileGet [fileHash] [strategy] [max price]")
+			fmt.Println("                                Get the file from the network, after a quote")
+			fmt.Println(" rpcGet [ip:port] [fileHash]    Get a file from a peer over gRPC")
+			fmt.Println(" rpcStore [ip:port] [filename]  Request storage of a file over gRPC")
 			fmt.Println(" send [amount] [ip] [port]      Send an amount of money to network")
+			fmt.Println(" balance                        Print your balance")
+			fmt.Println(" history                        List the transactions in your wallet")
+			fmt.Println(" providing                      Show how many files are advertised in the DHT")
+			fmt.Println(" status                         Show whether this node is reachable and its relay addresses")
+			fmt.Println(" reputation                     Show the score of every peer, best first")
 			fmt.Println(" hash [fileName]                Get the hash of a file")
 			fmt.Println(" list                           List all files you are storing")
 			fmt.Println(" location                       Print your location")
@@ -202,7 +470,72 @@ func StartCLI(bootstrapAddress *string, pubKey *rsa.PublicKey, privKey *rsa.Priv
 			fmt.Println("Unknown command. Type 'help' for available commands.")
 			fmt.Println()
 		}
+		fmt.Print("> ")
+	}
+}
+
+// describeQuote lists the holders a download would use and asks whether to
+// go ahead.
+func describeQuote(quote *orcaClient.Quote, scores *reputation.Book) string {
+	var text strings.Builder
+	text.WriteString("Holders, best first:\n")
+	for _, holder := range quote.Holders {
+		name := holder.PeerID
+		if name == "" {
+			name = holder.Address
+		}
+		latency := "unknown"
+		if holder.Latency > 0 {
+			latency = holder.Latency.Round(time.Millisecond).String()
+		}
+		fmt.Fprintf(&text, "  %s  %.4f per MB  latency %s  score %.2f\n", name, holder.Price, latency, scores.Score(name))
+	}
+	if len(quote.TooExpensive) > 0 {
+		fmt.Fprintf(&text, "%d holders ask more than the max price\n", len(quote.TooExpensive))
+	}
+	if quote.Size < 0 {
+		text.WriteString("No holder said how large the file is. Download anyway?")
+	} else if quote.MinCost == quote.MaxCost {
+		fmt.Fprintf(&text, "Download %d bytes for %.4f?", quote.Size, quote.MinCost)
+	} else {
+		fmt.Fprintf(&text, "Download %d bytes for %.4f to %.4f?", quote.Size, quote.MinCost, quote.MaxCost)
+	}
+	return text.String()
+}
+
+// readLines sends every line typed on stdin to the returned channel, which is
+// closed when stdin is.
+func readLines() <-chan string {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		reader := bufio.NewReader(os.Stdin)
+		for {
+			text, err := reader.ReadString('\n')
+			if err != nil {
+				if err != io.EOF {
+					fmt.Fprintln(os.Stderr, "Error reading from stdin:", err)
+				}
+				return
+			}
+			lines <- strings.TrimSpace(text)
+		}
+	}()
+	return lines
+}
+
+// dialMarket connects to the market in config, if there is one. This node is
+// listed by its peer ID and the port of its HTTP server.
+func dialMarket(config orcaClient.MarketConfig, peerID string, port string) (*orcaClient.MarketClient, error) {
+	if len(config.Endpoints()) == 0 {
+		return nil, nil
+	}
+	portNumber, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, err
 	}
+	name, _ := os.Hostname()
+	return orcaClient.DialMarket(config, &fileshare.User{Id: peerID, Name: name, Port: int32(portNumber)})
 }
 
 // Ask user to enter a port and returns it
@@ -8,22 +8,65 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	pb "orca-peer/internal/fileshare"
 	"orca-peer/internal/hash"
 	orcaHash "orca-peer/internal/hash"
+	"orca-peer/internal/p2p"
+	"orca-peer/internal/reputation"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/routing"
 )
 
 type Client struct {
-	name_map hash.NameMap
+	name_map    hash.NameMap
+	downloadDir string
+	http        *http.Client
+	// Signs the receipts that pay for downloads
+	privateKey *rsa.PrivateKey
+	// Whether holders can be reached by peer ID
+	p2p bool
+	// Used to measure the latency of holders, may be nil
+	host host.Host
+	// Where the outcome of downloads is recorded, may be nil
+	reputation *reputation.Book
+	// Where paid downloads are recorded, may be nil
+	ledger pb.FileShareClient
 }
 
-func NewClient(path string) *Client {
+func NewClient(path string, privateKey *rsa.PrivateKey) *Client {
 	return &Client{
-		name_map: *hash.NewNameStore(path),
+		name_map:    *hash.NewNameStore(path),
+		downloadDir: "./files/requested/",
+		http:        &http.Client{},
+		privateKey:  privateKey,
 	}
 }
 
+func (client *Client) httpClient() *http.Client {
+	if client.http == nil {
+		return http.DefaultClient
+	}
+	return client.http
+}
+
+// UseHost sends requests for peer IDs over libp2p streams from h, looking up
+// peers that are not connected yet with router.
+func (client *Client) UseHost(h host.Host, router routing.PeerRouting) {
+	client.http = &http.Client{Transport: p2p.NewTransport(h, router)}
+	client.p2p = true
+	client.host = h
+}
+
+// UseReputation records how holders behave in book and prefers the ones with
+// the best scores.
+func (client *Client) UseReputation(book *reputation.Book) {
+	client.reputation = book
+}
+
 type FileData struct {
 	FileName string `json:"filename"`
 	Content  []byte `json:"content"`
@@ -68,97 +111,104 @@ func (client *Client) ImportFile(filePath string) error {
 	return nil
 }
 
-type Data struct {
-	Bytes               []byte `json:"bytes"`
-	UnlockedTransaction []byte `json:"transaction"`
-	PublicKey           string `json:"public_key"`
+// Identity is what a peer answers on /identity
+type Identity struct {
+	PublicKey   string `json:"public_key"`
+	Fingerprint string `json:"fingerprint"`
+	PeerID      string `json:"peer_id"`
 }
 
-func SendTransaction(price float64, ip string, port string, publicKey *rsa.PublicKey, privateKey *rsa.PrivateKey) {
-	cost := orcaHash.GeneratePriceBytes(price)
-	byteBuffer := bytes.NewBuffer(cost)
-	pubKeyString, err := orcaHash.ExportRsaPublicKeyAsPemStr(publicKey)
+// GetIdentity asks the peer at ip:port for its public key and checks that the
+// fingerprint and peer ID it claims belong to that key. Older peers do not
+// send a peer ID.
+func GetIdentity(ip string, port string) (*Identity, error) {
+	return fetchIdentity(http.DefaultClient, ip+":"+port)
+}
+
+func fetchIdentity(httpClient *http.Client, address string) (*Identity, error) {
+	resp, err := httpClient.Get(fmt.Sprintf("http://%s/identity", address))
 	if err != nil {
-		fmt.Println("Error sending public key in header:", err)
-		return
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http status %d asking for identity", resp.StatusCode)
 	}
-	data := Data{
-		Bytes:               byteBuffer.Bytes(),
-		UnlockedTransaction: cost,
-		PublicKey:           string(pubKeyString),
+	var identity Identity
+	if err := json.NewDecoder(resp.Body).Decode(&identity); err != nil {
+		return nil, err
 	}
-	jsonData, err := json.Marshal(data)
+	publicKey, err := orcaHash.ParseRsaPublicKeyFromPemStr(identity.PublicKey)
 	if err != nil {
-		fmt.Println("Error marshaling JSON:", err)
-		os.Exit(1)
+		return nil, err
 	}
+	fingerprint, err := orcaHash.Fingerprint(publicKey)
+	if err != nil || fingerprint != identity.Fingerprint {
+		return nil, errors.New("peer identity does not match its public key")
+	}
+	if identity.PeerID != "" {
+		peerID, err := orcaHash.PeerIDFromKey(publicKey)
+		if err != nil || peerID.String() != identity.PeerID {
+			return nil, errors.New("peer ID does not match its public key")
+		}
+	}
+	return &identity, nil
+}
 
-	req, err := http.NewRequest("POST", fmt.Sprintf("http://%s:%s/sendTransaction", ip, port), bytes.NewReader(jsonData))
+// SendTransaction pays price to the peer at ip:port, for fileHash if it is
+// not empty. The transaction is returned once the peer has accepted it.
+func SendTransaction(price float64, ip string, port string, fileHash string, publicKey *rsa.PublicKey, privateKey *rsa.PrivateKey) (*orcaHash.Transaction, error) {
+	payee, err := GetIdentity(ip, port)
 	if err != nil {
-		fmt.Println("Error creating request:", err)
-		return
+		return nil, err
 	}
-
-	req.Header.Set("Content-Type", "application/octet-stream")
-	fmt.Println("Verifying Signature...")
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	transaction, err := orcaHash.NewTransaction(publicKey, payee.Fingerprint, price, fileHash, orcaHash.DefaultTransactionLifetime)
 	if err != nil {
-		fmt.Println("Error sending request:", err)
-		return
-	} else {
-		fmt.Println("Send Request")
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-}
-func (client *Client) GetFileOnce(ip, port, filename string) error {
-	/*
-		file_hash := client.name_map.GetFileHash(filename)
-		if file_hash == "" {
-			fmt.Println("Error: do not have hash for the file")
-			return
-		}
-	*/
-	data, err := client.getData(ip, port, filename)
+	signed, err := orcaHash.SignTransaction(transaction, privateKey)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	resp, err := http.Get(fmt.Sprintf("http://%s:%s/requestFile/%s", ip, port, filename))
+	jsonData, err := json.Marshal(signed)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	fmt.Println("Response:")
-	fmt.Println(resp)
-	fmt.Println("ResponseBody:")
-	fmt.Println(resp.Body)
+	req, err := http.NewRequest("POST", fmt.Sprintf("http://%s:%s/sendTransaction", ip, port), bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
 	defer resp.Body.Close()
-
 	if resp.StatusCode != http.StatusOK {
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			fmt.Println("Error reading Response Body:", err)
-			return err
-		}
-		fmt.Printf("\nError: %s\n> ", body)
-		return err
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("transaction rejected with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
 	}
+	return transaction, nil
+}
 
-	// Create the directory if it doesn't exist
-	err = os.MkdirAll("./files/requested/", 0755)
-	if err != nil {
-		panic(err)
+func (client *Client) GetFileOnce(ip, port, filename string) error {
+	// Files we asked someone to store are requested by their hash, and the
+	// hash lets us check the download once it is complete
+	remoteName := filename
+	file_hash := client.name_map.GetFileHash(filename)
+	if file_hash != "" {
+		remoteName = file_hash
 	}
-
-	// Create file
-	_, err = os.Create("./files/requested/" + filename)
-	if err != nil {
-		return err
+	downloadDir := client.downloadDir
+	if downloadDir == "" {
+		downloadDir = "./files/requested/"
 	}
-
-	err = os.WriteFile(filepath.Join("./files/requested/", filename), data, 0666)
+	url := fmt.Sprintf("http://%s:%s/requestFile/%s", ip, port, remoteName)
+	err := client.DownloadResumable(url, filepath.Join(downloadDir, filename), file_hash)
 	if err != nil {
+		fmt.Printf("\nError: %s\n> ", err)
 		return err
 	}
 
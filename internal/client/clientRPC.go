@@ -2,6 +2,7 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -14,15 +15,17 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 )
 
-func RequestFileFromMarket(client pb.FileShareClient, fileDesc *pb.CheckHoldersRequest) *pb.HoldersResponse {
+var ErrTransactionRefused = errors.New("ledger refused the transaction")
+
+func RequestFileFromMarket(client pb.FileShareClient, fileDesc *pb.CheckHoldersRequest) (*pb.HoldersResponse, error) {
 	log.Printf("Requesting IP For File (%s)", fileDesc.FileHash)
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	holders, err := client.CheckHolders(ctx, fileDesc)
 	if err != nil {
-		log.Fatalf("client.requestFileStorage failed: %v", err)
+		return nil, fmt.Errorf("client.CheckHolders failed: %w", err)
 	}
-	return holders
+	return holders, nil
 }
 
 func RequestFileFromProducer(baseURL string, filename string) bool {
@@ -47,34 +50,44 @@ func RequestFileFromProducer(baseURL string, filename string) bool {
 	fmt.Println(sb)
 	return false
 }
-func DialRPC(serverAddr *string) pb.FileShareClient {
+
+// DialRPC connects to the gRPC service at serverAddr. The connection stays
+// open until the caller closes it.
+func DialRPC(serverAddr *string) (pb.FileShareClient, *grpc.ClientConn, error) {
 	var opts []grpc.DialOption
 	opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	opts = append(opts, grpc.WithInitialWindowSize(RPCWindow), grpc.WithInitialConnWindowSize(RPCWindow))
 	conn, err := grpc.Dial(*serverAddr, opts...)
 	if err != nil {
-		log.Fatalf("fail to dial: %v", err)
+		return nil, nil, fmt.Errorf("fail to dial: %w", err)
 	}
-	defer conn.Close()
 	client := pb.NewFileShareClient(conn)
-	return client
+	return client, conn, nil
 }
 
-func runRecordTransaction(client pb.FileShareClient, transaction *pb.FileRequestTransaction) *pb.TransactionACKResponse {
+func runRecordTransaction(client pb.FileShareClient, transaction *pb.FileRequestTransaction) (*pb.TransactionACKResponse, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	ackResponse, err := client.RecordFileRequestTransaction(ctx, transaction)
 	if err != nil {
-		log.Fatalf("client.RecordFileRequestTransaction failed: %v", err)
+		return nil, fmt.Errorf("client.RecordFileRequestTransaction failed: %w", err)
 	}
 	log.Printf("ACK Response: %v", ackResponse)
-	return ackResponse
+	return ackResponse, nil
 }
 
-func RecordTransactionWrapper(client pb.FileShareClient, transaction *pb.FileRequestTransaction) {
-	var ack = runRecordTransaction(client, transaction)
-	if ack.IsSuccess {
-		fmt.Printf("[Server]: Successfully recorded transaction in hash: %v", ack.BlockHash)
-	} else {
+// RecordTransactionWrapper submits a signed transaction to the ledger and
+// waits for the block it ends up in.
+func RecordTransactionWrapper(client pb.FileShareClient, transaction *pb.FileRequestTransaction) error {
+	ack, err := runRecordTransaction(client, transaction)
+	if err != nil {
+		fmt.Println("[Server]: Unable to record transaction in blockchain:", err)
+		return err
+	}
+	if !ack.IsSuccess {
 		fmt.Println("[Server]: Unable to record transaction in blockchain")
+		return ErrTransactionRefused
 	}
+	fmt.Printf("[Server]: Successfully recorded transaction in block: %v\n", ack.BlockHash)
+	return nil
 }
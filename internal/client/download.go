@@ -0,0 +1,284 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	orcaHash "orca-peer/internal/hash"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	partialSuffix = ".part"
+	stateSuffix   = ".part.json"
+	// How many bytes are written to a partial file before it is synced to disk
+	// and the resume offset is advanced
+	checkpointBytes = 1024 * 1024
+)
+
+var ErrHashMismatch = errors.New("downloaded file does not match expected hash")
+
+// downloadState is stored next to a partial download so that it can be resumed
+// after the program or the connection dies.
+type downloadState struct {
+	URL      string `json:"url"`
+	FileHash string `json:"file_hash"`
+	Size     int64  `json:"size"`
+	Offset   int64  `json:"offset"`
+}
+
+func loadDownloadState(path string) (*downloadState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var state downloadState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (state *downloadState) save(path string) error {
+	return saveState(path, state)
+}
+
+// saveState writes state to path as JSON, replacing the old state at once.
+func saveState(path string, state any) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// parseContentRange reads a "bytes start-end/size" header and returns the start
+// offset and the total size of the file.
+func parseContentRange(header string) (int64, int64, error) {
+	spec, found := strings.CutPrefix(header, "bytes ")
+	if !found {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q", header)
+	}
+	rangePart, sizePart, found := strings.Cut(spec, "/")
+	if !found {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q", header)
+	}
+	startStr, _, found := strings.Cut(rangePart, "-")
+	if !found {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q", header)
+	}
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	size, err := strconv.ParseInt(sizePart, 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return start, size, nil
+}
+
+// DownloadResumable fetches url into destination. Data is first written to
+// destination + ".part" and the offset of the data that has been synced to
+// disk is recorded in destination + ".part.json". If a previous attempt was
+// interrupted, only the remaining bytes are requested with a Range header.
+// When fileHash is given the finished file must have that CID.
+func (client *Client) DownloadResumable(url string, destination string, fileHash string) error {
+	partPath := destination + partialSuffix
+	statePath := destination + stateSuffix
+	err := os.MkdirAll(filepath.Dir(destination), 0755)
+	if err != nil {
+		return err
+	}
+
+	state, err := loadDownloadState(statePath)
+	if err != nil || state.URL != url || state.FileHash != fileHash {
+		state = &downloadState{URL: url, FileHash: fileHash}
+	}
+
+	partFile, err := os.OpenFile(partPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer partFile.Close()
+
+	info, err := partFile.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < state.Offset {
+		// The partial file lost data the state says it has, start over
+		fmt.Println("Partial download is shorter than recorded, restarting download")
+		os.Remove(statePath)
+		state = &downloadState{URL: url, FileHash: fileHash}
+	}
+	// Anything past the last checkpoint might not have made it to disk intact
+	if info.Size() > state.Offset {
+		if err := partFile.Truncate(state.Offset); err != nil {
+			return err
+		}
+	}
+	if _, err := partFile.Seek(state.Offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	if state.Offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", state.Offset))
+	}
+	if err := client.setPaymentKey(req); err != nil {
+		return err
+	}
+	resp, err := client.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		start, size, err := parseContentRange(resp.Header.Get("Content-Range"))
+		if err != nil {
+			return err
+		}
+		if start != state.Offset || (state.Size != 0 && size != state.Size) {
+			// The server sent something other than the rest of our file
+			os.Remove(statePath)
+			return fmt.Errorf("server returned range starting at %d of %d bytes, expected %d of %d", start, size, state.Offset, state.Size)
+		}
+		state.Size = size
+	case http.StatusOK:
+		// The server ignored the Range header, start over from the beginning
+		if state.Offset > 0 {
+			fmt.Println("Server does not support resuming, restarting download")
+		}
+		state.Offset = 0
+		state.Size = resp.ContentLength
+		if err := partFile.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := partFile.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		// Our partial file is already as long as the remote one
+		if state.Offset == 0 || state.Size != state.Offset {
+			os.Remove(statePath)
+			return errors.New("server could not satisfy resume range")
+		}
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("http status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := state.save(statePath); err != nil {
+		return err
+	}
+
+	// A holder that charges for the file only sends what our receipts cover,
+	// so pay for one interval ahead and for the next whenever it has arrived
+	payer, interval, err := client.newPayer(resp.Header.Get, fileHash)
+	if err != nil {
+		return err
+	}
+	if payer != nil && state.Size < 0 {
+		// Receipts name how many bytes they pay for, which is not known here
+		return errors.New("holder wants payment for a file without saying how large it is")
+	}
+	expected := state.Size - state.Offset
+	received := int64(0)
+	payNext := func() error {
+		if payer == nil || received < payer.Paid() || received >= expected {
+			return nil
+		}
+		return client.payUpTo(req.Context(), req.URL.Host, payer, min(received+interval, expected))
+	}
+	if err := payNext(); err != nil {
+		return err
+	}
+
+	buffer := make([]byte, 32*1024)
+	sinceCheckpoint := int64(0)
+	var copyErr error
+	for resp.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		n, readErr := resp.Body.Read(buffer)
+		if n > 0 {
+			if _, err := partFile.Write(buffer[:n]); err != nil {
+				copyErr = err
+				break
+			}
+			received += int64(n)
+			if err := payNext(); err != nil {
+				copyErr = err
+				break
+			}
+			sinceCheckpoint += int64(n)
+			if sinceCheckpoint >= checkpointBytes {
+				if err := checkpoint(partFile, state, statePath, sinceCheckpoint); err != nil {
+					return err
+				}
+				sinceCheckpoint = 0
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			copyErr = readErr
+			break
+		}
+	}
+	// Keep whatever arrived so that the next attempt continues after it
+	if err := checkpoint(partFile, state, statePath, sinceCheckpoint); err != nil {
+		return err
+	}
+	if copyErr != nil {
+		return fmt.Errorf("download interrupted at byte %d: %w", state.Offset, copyErr)
+	}
+	if state.Size >= 0 && state.Offset != state.Size {
+		return fmt.Errorf("download interrupted at byte %d of %d", state.Offset, state.Size)
+	}
+
+	if fileHash != "" {
+		if _, err := partFile.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		cid, err := orcaHash.ComputeCID(partFile)
+		if err != nil {
+			return err
+		}
+		if cid != fileHash {
+			// Nothing in the partial file can be trusted anymore
+			partFile.Close()
+			os.Remove(partPath)
+			os.Remove(statePath)
+			return ErrHashMismatch
+		}
+	}
+
+	partFile.Close()
+	if err := os.Rename(partPath, destination); err != nil {
+		return err
+	}
+	os.Remove(statePath)
+	return nil
+}
+
+func checkpoint(partFile *os.File, state *downloadState, statePath string, written int64) error {
+	if err := partFile.Sync(); err != nil {
+		return err
+	}
+	state.Offset += written
+	return state.save(statePath)
+}
@@ -0,0 +1,248 @@
+package client
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	orcaHash "orca-peer/internal/hash"
+	"orca-peer/internal/payment"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// abortingWriter kills the connection once limit bytes have been written,
+// which looks the same to the client as the producer going away mid transfer.
+type abortingWriter struct {
+	http.ResponseWriter
+	limit   int
+	written int
+}
+
+func (w *abortingWriter) Write(p []byte) (int, error) {
+	if w.written+len(p) > w.limit {
+		p = p[:w.limit-w.written]
+		w.ResponseWriter.Write(p)
+		w.ResponseWriter.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}
+	w.written += len(p)
+	return w.ResponseWriter.Write(p)
+}
+
+func randomData(t *testing.T, size int) []byte {
+	data := make([]byte, size)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func cidOf(t *testing.T, data []byte) string {
+	manifest, _, err := orcaHash.BuildManifest(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return manifest.Root
+}
+
+func TestDownloadResumesAfterInterruption(t *testing.T) {
+	data := randomData(t, 3*checkpointBytes+512)
+	var mu sync.Mutex
+	var ranges []string
+	killed := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		kill := !killed
+		killed = true
+		mu.Unlock()
+		if kill {
+			w = &abortingWriter{ResponseWriter: w, limit: 2*checkpointBytes + 100}
+		}
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(data))
+	}))
+	defer server.Close()
+
+	client := &Client{}
+	destination := filepath.Join(t.TempDir(), "file")
+	err := client.DownloadResumable(server.URL+"/requestFile/file", destination, cidOf(t, data))
+	if err == nil {
+		t.Fatal("expected the first download attempt to be interrupted")
+	}
+	state, err := loadDownloadState(destination + stateSuffix)
+	if err != nil {
+		t.Fatalf("expected a saved download state, got %s", err)
+	}
+	if state.Offset == 0 || state.Offset > 2*checkpointBytes+100 {
+		t.Fatalf("unexpected resume offset %d", state.Offset)
+	}
+
+	err = client.DownloadResumable(server.URL+"/requestFile/file", destination, cidOf(t, data))
+	if err != nil {
+		t.Fatalf("expected resumed download to succeed, got %s", err)
+	}
+	if len(ranges) != 2 || ranges[0] != "" || ranges[1] == "" {
+		t.Fatalf("expected a fresh request followed by a range request, got %q", ranges)
+	}
+	got, err := os.ReadFile(destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("resumed file does not match original")
+	}
+	if _, err := os.Stat(destination + partialSuffix); !os.IsNotExist(err) {
+		t.Errorf("expected partial file to be removed")
+	}
+	if _, err := os.Stat(destination + stateSuffix); !os.IsNotExist(err) {
+		t.Errorf("expected state file to be removed")
+	}
+}
+
+func TestDownloadDiscardsBytesPastCheckpoint(t *testing.T) {
+	data := randomData(t, 2*checkpointBytes)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(data))
+	}))
+	defer server.Close()
+
+	destination := filepath.Join(t.TempDir(), "file")
+	url := server.URL + "/requestFile/file"
+	// Pretend a crash left garbage after the last recorded offset
+	state := &downloadState{URL: url, Size: int64(len(data)), Offset: 100}
+	if err := state.save(destination + stateSuffix); err != nil {
+		t.Fatal(err)
+	}
+	partial := append(append([]byte{}, data[:100]...), []byte("garbage")...)
+	if err := os.WriteFile(destination+partialSuffix, partial, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &Client{}
+	if err := client.DownloadResumable(url, destination, ""); err != nil {
+		t.Fatalf("expected download to succeed, got %s", err)
+	}
+	got, err := os.ReadFile(destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("downloaded file does not match original")
+	}
+}
+
+func TestDownloadRestartsWhenPartialFileIsShort(t *testing.T) {
+	data := randomData(t, 4096)
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(data))
+	}))
+	defer server.Close()
+
+	destination := filepath.Join(t.TempDir(), "file")
+	url := server.URL + "/requestFile/file"
+	// The state outlived most of the partial file
+	state := &downloadState{URL: url, Size: int64(len(data)), Offset: 100}
+	if err := state.save(destination + stateSuffix); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(destination+partialSuffix, data[:10], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &Client{}
+	if err := client.DownloadResumable(url, destination, ""); err != nil {
+		t.Fatalf("expected download to succeed, got %s", err)
+	}
+	if len(ranges) != 1 || ranges[0] != "" {
+		t.Errorf("expected the download to start over, got ranges %q", ranges)
+	}
+	got, err := os.ReadFile(destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("downloaded file does not match original")
+	}
+}
+
+func TestDownloadRefusesToPayForUnknownSize(t *testing.T) {
+	data := randomData(t, 4096)
+	fileHash := cidOf(t, data)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(payment.HeaderPrice, "5")
+		w.Header().Set(payment.HeaderTransferID, "transfer")
+		w.Header().Set(payment.HeaderFileHash, fileHash)
+		// Flushing before the body leaves out the Content-Length
+		w.(http.Flusher).Flush()
+		w.Write(data)
+	}))
+	defer server.Close()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewClient(t.TempDir(), key)
+	destination := filepath.Join(t.TempDir(), "file")
+	if err := client.DownloadResumable(server.URL+"/requestFile/file", destination, fileHash); err == nil {
+		t.Errorf("expected a priced download of unknown size to be refused")
+	}
+}
+
+func TestDownloadRestartsWithoutRangeSupport(t *testing.T) {
+	data := randomData(t, 4096)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	destination := filepath.Join(t.TempDir(), "file")
+	url := server.URL + "/requestFile/file"
+	state := &downloadState{URL: url, Size: int64(len(data)), Offset: 10}
+	if err := state.save(destination + stateSuffix); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(destination+partialSuffix, data[:10], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &Client{}
+	if err := client.DownloadResumable(url, destination, cidOf(t, data)); err != nil {
+		t.Fatalf("expected download to succeed, got %s", err)
+	}
+	got, err := os.ReadFile(destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("downloaded file does not match original")
+	}
+}
+
+func TestDownloadRejectsHashMismatch(t *testing.T) {
+	data := randomData(t, 4096)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(data))
+	}))
+	defer server.Close()
+
+	destination := filepath.Join(t.TempDir(), "file")
+	client := &Client{}
+	err := client.DownloadResumable(server.URL+"/requestFile/file", destination, cidOf(t, []byte("other")))
+	if !errors.Is(err, ErrHashMismatch) {
+		t.Fatalf("expected hash mismatch, got %v", err)
+	}
+	for _, path := range []string{destination, destination + partialSuffix, destination + stateSuffix} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed", path)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	pb "orca-peer/internal/fileshare"
+	orcaHash "orca-peer/internal/hash"
+	"orca-peer/internal/payment"
+	"os"
+	"path/filepath"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// How many bytes a peer may send on a gRPC stream before the other end has
+// read them. A transfer pauses whenever it is this far ahead of what was
+// written or stored.
+const RPCWindow = 4 * orcaHash.ChunkSize
+
+// ManifestToProto is manifest as it is sent over gRPC, naming the file name.
+func ManifestToProto(manifest *orcaHash.Manifest, name string) *pb.FileManifest {
+	return &pb.FileManifest{
+		Root:      manifest.Root,
+		Size:      manifest.Size,
+		ChunkSize: manifest.ChunkSize,
+		Chunks:    manifest.Chunks,
+		FileName:  name,
+	}
+}
+
+// ManifestFromProto is the manifest a peer sent over gRPC. It still has to be
+// verified.
+func ManifestFromProto(message *pb.FileManifest) *orcaHash.Manifest {
+	return &orcaHash.Manifest{
+		Root:      message.GetRoot(),
+		Size:      message.GetSize(),
+		ChunkSize: message.GetChunkSize(),
+		Chunks:    message.GetChunks(),
+	}
+}
+
+// withPaymentKey tells the holder which key our receipts will be signed with,
+// like setPaymentKey does for HTTP.
+func (client *Client) withPaymentKey(ctx context.Context) (context.Context, error) {
+	if client.privateKey == nil {
+		return ctx, nil
+	}
+	encoded, err := payment.EncodePublicKey(&client.privateKey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return metadata.AppendToOutgoingContext(ctx, payment.HeaderPublicKey, encoded), nil
+}
+
+// payUpToRPC is payUpTo for holders reached over gRPC.
+func payUpToRPC(ctx context.Context, rpc pb.FileShareClient, payer *payment.Payer, size int64) error {
+	signed, err := payer.PayUpTo(size)
+	if err != nil || signed == nil {
+		return err
+	}
+	body, err := json.Marshal(signed)
+	if err != nil {
+		return err
+	}
+	if _, err := rpc.SendReceipt(ctx, &pb.Receipt{SignedReceipt: body}); err != nil {
+		return err
+	}
+	payer.Accepted(size)
+	return nil
+}
+
+/*
+ReceiveFile downloads the file cid from a holder over gRPC and writes it to w.
+Every chunk is checked against the manifest before it is written. A holder
+that charges for the file only sends what our receipts cover, so every chunk
+is paid for before it is needed, an interval at a time.
+*/
+func (client *Client) ReceiveFile(ctx context.Context, rpc pb.FileShareClient, cid string, w io.Writer) (*orcaHash.Manifest, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ctx, err := client.withPaymentKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := rpc.SendFile(ctx, &pb.SendFileRequest{Cid: cid})
+	if err != nil {
+		return nil, err
+	}
+	header, err := stream.Header()
+	if err != nil {
+		return nil, err
+	}
+	payer, interval, err := client.newPayer(func(key string) string {
+		if values := header.Get(key); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}, cid)
+	if err != nil {
+		return nil, err
+	}
+
+	first, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	if first.GetManifest() == nil {
+		return nil, errors.New("holder did not start with the manifest")
+	}
+	manifest := ManifestFromProto(first.GetManifest())
+	if err := manifest.Verify(); err != nil {
+		return nil, err
+	}
+	if manifest.Root != cid {
+		return nil, fmt.Errorf("holder sent the manifest of %s instead of %s", manifest.Root, cid)
+	}
+
+	received := int64(0)
+	payFor := func(next int) error {
+		if payer == nil || next >= len(manifest.Chunks) {
+			return nil
+		}
+		// Chunks are sent whole, so the receipt has to cover the next one
+		_, length := manifest.ChunkOffset(next)
+		if received+length <= payer.Paid() {
+			return nil
+		}
+		return payUpToRPC(ctx, rpc, payer, min(received+max(interval, length), manifest.Size))
+	}
+	for i := range manifest.Chunks {
+		if err := payFor(i); err != nil {
+			return nil, err
+		}
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			return nil, fmt.Errorf("holder stopped after %d of %d chunks", i, len(manifest.Chunks))
+		}
+		if err != nil {
+			return nil, err
+		}
+		if chunk.GetIndex() != int64(i) {
+			return nil, fmt.Errorf("expected chunk %d, got %d", i, chunk.GetIndex())
+		}
+		if err := manifest.VerifySentChunk(i, chunk.GetHash(), chunk.GetData()); err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(chunk.GetData()); err != nil {
+			return nil, err
+		}
+		received += int64(len(chunk.GetData()))
+	}
+	return manifest, nil
+}
+
+// GetFileRPC downloads the file cid from a holder over gRPC into the
+// download directory.
+func (client *Client) GetFileRPC(ctx context.Context, rpc pb.FileShareClient, cid string) error {
+	if !orcaHash.IsValidHash(cid) {
+		return fmt.Errorf("invalid file hash %q", cid)
+	}
+	downloadDir := client.downloadDir
+	if downloadDir == "" {
+		downloadDir = "./files/requested/"
+	}
+	if err := os.MkdirAll(downloadDir, 0755); err != nil {
+		return err
+	}
+	destination := filepath.Join(downloadDir, cid)
+	partFile, err := os.Create(destination + ".part")
+	if err != nil {
+		return err
+	}
+	_, err = client.ReceiveFile(ctx, rpc, cid, partFile)
+	if closeErr := partFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(destination + ".part")
+		return err
+	}
+	return os.Rename(destination+".part", destination)
+}
+
+// SendFileToStore asks a peer reached over gRPC to store data under name.
+// Sending pauses whenever the peer has not stored what was sent so far.
+func SendFileToStore(ctx context.Context, rpc pb.FileShareClient, name string, data []byte) (*pb.StorageACKResponse, error) {
+	manifest, chunks, err := orcaHash.BuildManifest(data)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := rpc.SendFileToStore(ctx)
+	if err != nil {
+		return nil, err
+	}
+	messages := []*pb.FileChunk{{Manifest: ManifestToProto(manifest, name)}}
+	for i, chunk := range chunks {
+		messages = append(messages, &pb.FileChunk{Index: int64(i), Hash: manifest.Chunks[i], Data: chunk})
+	}
+	for _, message := range messages {
+		// io.EOF means the peer gave up on the stream, CloseAndRecv says why
+		if err := stream.Send(message); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return stream.CloseAndRecv()
+}
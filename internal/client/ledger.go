@@ -0,0 +1,73 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	pb "orca-peer/internal/fileshare"
+	"orca-peer/internal/ledger"
+	"orca-peer/internal/payment"
+	"sync"
+)
+
+// ErrNotRecorded means a download finished but is missing from the ledger.
+var ErrNotRecorded = errors.New("download was not recorded in the ledger")
+
+type LedgerConfig struct {
+	// host:port of the ledger service. Empty means downloads are not recorded.
+	Address string `json:"address"`
+}
+
+// UseLedger makes the client record every paid download in the ledger
+// behind rpc.
+func (client *Client) UseLedger(rpc pb.FileShareClient) {
+	client.ledger = rpc
+}
+
+/*
+recordTransfers submits a signed transaction to the ledger for every source
+that was paid for its part of cid, all at the same time, and returns what went
+wrong with any of them. Receivers are named by the fingerprint of their key,
+like senders, or by their address if they do not say who they are.
+*/
+func (client *Client) recordTransfers(cid string, sources []*chunkSource) error {
+	if client.ledger == nil || client.privateKey == nil {
+		return nil
+	}
+	var wg sync.WaitGroup
+	errs := make([]error, len(sources))
+	for i, source := range sources {
+		if source.payer == nil || source.payer.Paid() == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, source *chunkSource) {
+			defer wg.Done()
+			if err := client.recordTransfer(cid, source); err != nil {
+				errs[i] = fmt.Errorf("recording the transfer from %s: %w", source.address, err)
+			}
+		}(i, source)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (client *Client) recordTransfer(cid string, source *chunkSource) error {
+	receiver := source.address
+	if identity, err := fetchIdentity(client.httpClient(), source.address); err == nil {
+		receiver = identity.Fingerprint
+	}
+	// The size is what the accepted receipts cover, not what arrived, so that
+	// it always matches the currency exchanged
+	paid := source.payer.Paid()
+	transaction := &pb.FileRequestTransaction{
+		FileByteSize:      paid,
+		FileHashName:      cid,
+		CurrencyExchanged: float32(payment.Cost(paid, source.payer.PricePerMB)),
+		ReceiverId:        receiver,
+		FileIpLocation:    source.address,
+	}
+	if err := ledger.SignTransaction(transaction, client.privateKey); err != nil {
+		return err
+	}
+	return RecordTransactionWrapper(client.ledger, transaction)
+}
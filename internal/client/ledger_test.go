@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	pb "orca-peer/internal/fileshare"
+	"orca-peer/internal/ledger"
+	"orca-peer/internal/payment"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc"
+)
+
+// recordingLedger keeps every transaction, except those from the holder at
+// refused, which it refuses.
+type recordingLedger struct {
+	pb.FileShareClient
+	mu       sync.Mutex
+	recorded []*pb.FileRequestTransaction
+	refused  string
+}
+
+func (recorder *recordingLedger) RecordFileRequestTransaction(ctx context.Context, transaction *pb.FileRequestTransaction, opts ...grpc.CallOption) (*pb.TransactionACKResponse, error) {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if transaction.FileIpLocation == recorder.refused {
+		return &pb.TransactionACKResponse{IsSuccess: false}, nil
+	}
+	recorder.recorded = append(recorder.recorded, transaction)
+	return &pb.TransactionACKResponse{IsSuccess: true, BlockHash: "block"}, nil
+}
+
+func TestRecordTransfersSignsPaidSources(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(t.TempDir(), key)
+	recorder := &recordingLedger{refused: "127.0.0.1:3"}
+	client.UseLedger(recorder)
+
+	paid := payment.NewPayer("transfer", "file", 2, key)
+	paid.Accepted(payment.MB)
+	sources := []*chunkSource{
+		// More arrived than the holder accepted receipts for
+		{address: "127.0.0.1:1", payer: paid, received: 2 * payment.MB},
+		// Free sources have nothing to record
+		{address: "127.0.0.1:2", received: payment.MB},
+		{address: "127.0.0.1:3", payer: paid, received: payment.MB},
+	}
+	err = client.recordTransfers("file", sources)
+	if !errors.Is(err, ErrTransactionRefused) || !strings.Contains(err.Error(), "127.0.0.1:3") {
+		t.Errorf("expected the refused transaction to be reported, got %v", err)
+	}
+
+	if len(recorder.recorded) != 1 {
+		t.Fatalf("expected one transaction, got %d", len(recorder.recorded))
+	}
+	transaction := recorder.recorded[0]
+	if err := ledger.VerifyTransaction(transaction); err != nil {
+		t.Errorf("expected a signed transaction, got %s", err)
+	}
+	// The holder could not be asked for its identity
+	if transaction.ReceiverId != "127.0.0.1:1" || transaction.CurrencyExchanged != 2 || transaction.FileByteSize != payment.MB || transaction.FileHashName != "file" {
+		t.Errorf("unexpected transaction %v", transaction)
+	}
+}
@@ -0,0 +1,427 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	pb "orca-peer/internal/fileshare"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	DefaultMarketTimeout    = 10 * time.Second
+	DefaultMarketRetries    = 2
+	DefaultBreakerThreshold = 3
+	DefaultBreakerCooldown  = 30 * time.Second
+	// Wait before the first retry, doubled for every retry after it
+	marketBackoff    = 500 * time.Millisecond
+	maxMarketBackoff = 10 * time.Second
+)
+
+var (
+	ErrNoMarket          = errors.New("no market address is configured")
+	ErrMarketUnavailable = errors.New("market could not be reached")
+	ErrMarketRejected    = errors.New("market refused the call")
+	ErrCircuitOpen       = errors.New("market is skipped after failing repeatedly")
+)
+
+// MarketError is a market call that failed. Err is one of the errors above,
+// Cause is what the market or the connection to it answered, if anything.
+type MarketError struct {
+	Op      string
+	Address string
+	Err     error
+	Cause   error
+}
+
+func (err *MarketError) Error() string {
+	if err.Cause == nil {
+		return fmt.Sprintf("%s at %s: %s", err.Op, err.Address, err.Err)
+	}
+	return fmt.Sprintf("%s at %s: %s: %s", err.Op, err.Address, err.Err, status.Convert(err.Cause).Message())
+}
+
+func (err *MarketError) Unwrap() []error {
+	if err.Cause == nil {
+		return []error{err.Err}
+	}
+	return []error{err.Err, err.Cause}
+}
+
+type MarketConfig struct {
+	// host:port of the market service, tried before Addresses
+	Address string `json:"address"`
+	// More markets to fail over to, in order
+	Addresses []string `json:"addresses"`
+	// Deadline of each call in seconds
+	Timeout int `json:"timeout"`
+	// How many more rounds over all markets a failed call gets
+	Retries int `json:"retries"`
+	// A market that failed this many calls in a row is skipped for
+	// BreakerCooldown seconds
+	BreakerThreshold int `json:"breaker_threshold"`
+	BreakerCooldown  int `json:"breaker_cooldown"`
+}
+
+func (config MarketConfig) Validate() error {
+	if config.Timeout < 0 || config.Retries < 0 || config.BreakerThreshold < 0 || config.BreakerCooldown < 0 {
+		return fmt.Errorf("market timeout, retries and breaker settings can not be negative")
+	}
+	return nil
+}
+
+// Endpoints lists every configured market once, in the order they are tried.
+// No endpoints means no market is used.
+func (config MarketConfig) Endpoints() []string {
+	endpoints := []string{}
+	seen := make(map[string]bool)
+	for _, address := range append([]string{config.Address}, config.Addresses...) {
+		if address != "" && !seen[address] {
+			seen[address] = true
+			endpoints = append(endpoints, address)
+		}
+	}
+	return endpoints
+}
+
+/*
+breaker stops calls to a market that keeps failing. After threshold failures
+in a row it opens and refuses calls for cooldown. Then it lets a single call
+through: if that succeeds it closes again, if it fails it stays open for
+another cooldown.
+*/
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	trying    bool
+	now       func() time.Time
+}
+
+func (breaker *breaker) allow() bool {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+	if breaker.failures < breaker.threshold {
+		return true
+	}
+	if breaker.now().Before(breaker.openUntil) || breaker.trying {
+		return false
+	}
+	breaker.trying = true
+	return true
+}
+
+func (breaker *breaker) success() {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+	breaker.failures = 0
+	breaker.trying = false
+}
+
+// release gives up the call allow let through without a verdict on the
+// market, so the next call may try it.
+func (breaker *breaker) release() {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+	breaker.trying = false
+}
+
+func (breaker *breaker) failure() {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+	breaker.failures++
+	breaker.trying = false
+	if breaker.failures >= breaker.threshold {
+		breaker.openUntil = breaker.now().Add(breaker.cooldown)
+	}
+}
+
+// endpoint is one market and the connection to it.
+type endpoint struct {
+	address string
+	conn    *grpc.ClientConn
+	rpc     pb.FileShareClient
+	breaker *breaker
+}
+
+/*
+MarketClient talks to the configured markets, keeping one connection to each
+for all calls. Every call has its own deadline. A call that fails because a
+market could not be reached goes to the next market, and once every market
+failed, the round is repeated after a growing, jittered pause. Markets that
+fail repeatedly are skipped for a while. Calls start at the market that
+answered last.
+*/
+type MarketClient struct {
+	endpoints        []*endpoint
+	timeout          time.Duration
+	retries          int
+	backoff          time.Duration
+	breakerThreshold int
+	breakerCooldown  time.Duration
+	// How this node is listed as a holder
+	user *pb.User
+
+	mu        sync.Mutex
+	preferred int
+}
+
+// DialMarket connects to the markets in config. Connections are made lazily,
+// so an unreachable market only shows up as failing calls. user is how this
+// node is listed when it registers files. Its IP may be empty, in which case
+// the market lists the address the call came from.
+func DialMarket(config MarketConfig, user *pb.User) (*MarketClient, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if len(config.Endpoints()) == 0 {
+		return nil, ErrNoMarket
+	}
+	market := newMarketClient(config, user)
+	for _, address := range config.Endpoints() {
+		conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			market.Close()
+			return nil, err
+		}
+		market.addEndpoint(address, conn, pb.NewFileShareClient(conn))
+	}
+	return market, nil
+}
+
+func newMarketClient(config MarketConfig, user *pb.User) *MarketClient {
+	market := &MarketClient{
+		timeout:          time.Duration(config.Timeout) * time.Second,
+		retries:          config.Retries,
+		backoff:          marketBackoff,
+		breakerThreshold: config.BreakerThreshold,
+		breakerCooldown:  time.Duration(config.BreakerCooldown) * time.Second,
+		user:             user,
+	}
+	if market.timeout == 0 {
+		market.timeout = DefaultMarketTimeout
+	}
+	if market.breakerThreshold == 0 {
+		market.breakerThreshold = DefaultBreakerThreshold
+	}
+	if market.breakerCooldown == 0 {
+		market.breakerCooldown = DefaultBreakerCooldown
+	}
+	return market
+}
+
+func (market *MarketClient) addEndpoint(address string, conn *grpc.ClientConn, rpc pb.FileShareClient) {
+	market.endpoints = append(market.endpoints, &endpoint{
+		address: address,
+		conn:    conn,
+		rpc:     rpc,
+		breaker: &breaker{threshold: market.breakerThreshold, cooldown: market.breakerCooldown, now: time.Now},
+	})
+}
+
+func (market *MarketClient) Close() error {
+	var errs []error
+	for _, endpoint := range market.endpoints {
+		if endpoint.conn != nil {
+			errs = append(errs, endpoint.conn.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// retryable reports whether a call that failed with err may succeed if it is
+// made again, possibly at another market.
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+		return true
+	}
+	return false
+}
+
+// jitter spreads out the retries of peers that failed at the same moment, so
+// they do not all come back at once. It returns a duration between half of d
+// and d.
+func jitter(d time.Duration) time.Duration {
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// call runs rpc against the markets in turn until one answers, see
+// MarketClient.
+func (market *MarketClient) call(ctx context.Context, op string, rpc func(ctx context.Context, client pb.FileShareClient) error) error {
+	if len(market.endpoints) == 0 {
+		return ErrNoMarket
+	}
+	market.mu.Lock()
+	first := market.preferred
+	market.mu.Unlock()
+
+	backoff := market.backoff
+	var last error
+	for round := 0; ; round++ {
+		tried := false
+		for i := range market.endpoints {
+			index := (first + i) % len(market.endpoints)
+			endpoint := market.endpoints[index]
+			if !endpoint.breaker.allow() {
+				if last == nil {
+					last = &MarketError{Op: op, Address: endpoint.address, Err: ErrCircuitOpen}
+				}
+				continue
+			}
+			tried = true
+			callCtx, cancel := context.WithTimeout(ctx, market.timeout)
+			err := rpc(callCtx, endpoint.rpc)
+			cancel()
+			if err != nil && ctx.Err() != nil {
+				// The caller gave up, which says nothing about the market
+				endpoint.breaker.release()
+				return &MarketError{Op: op, Address: endpoint.address, Err: ErrMarketUnavailable, Cause: ctx.Err()}
+			}
+			if err == nil || !retryable(err) {
+				// Even a refusal shows the market is up
+				endpoint.breaker.success()
+				market.mu.Lock()
+				market.preferred = index
+				market.mu.Unlock()
+				if err != nil {
+					return &MarketError{Op: op, Address: endpoint.address, Err: ErrMarketRejected, Cause: err}
+				}
+				return nil
+			}
+			endpoint.breaker.failure()
+			last = &MarketError{Op: op, Address: endpoint.address, Err: ErrMarketUnavailable, Cause: err}
+		}
+		// Waiting does not help if every market is skipped
+		if !tried || round >= market.retries {
+			return last
+		}
+		select {
+		case <-time.After(jitter(backoff)):
+		case <-ctx.Done():
+			return last
+		}
+		backoff = min(backoff*2, maxMarketBackoff)
+	}
+}
+
+// RegisterFile lists this node as a holder of fileHash at pricePerMB. The
+// market keeps whole prices, so the price is rounded up, never offering the
+// file for less than it costs here.
+func (market *MarketClient) RegisterFile(ctx context.Context, fileHash string, pricePerMB float64) error {
+	user := &pb.User{
+		Id:    market.user.GetId(),
+		Name:  market.user.GetName(),
+		Ip:    market.user.GetIp(),
+		Port:  market.user.GetPort(),
+		Price: int64(math.Ceil(pricePerMB)),
+	}
+	return market.call(ctx, "RegisterFile", func(ctx context.Context, client pb.FileShareClient) error {
+		_, err := client.RegisterFile(ctx, &pb.RegisterFileRequest{User: user, FileHash: fileHash})
+		return err
+	})
+}
+
+// CheckHolders asks the market who holds fileHash. Holders whose ID is a
+// peer ID can be reached by it as well as by their address.
+func (market *MarketClient) CheckHolders(ctx context.Context, fileHash string) ([]Holder, error) {
+	var response *pb.HoldersResponse
+	err := market.call(ctx, "CheckHolders", func(ctx context.Context, client pb.FileShareClient) error {
+		var err error
+		response, err = client.CheckHolders(ctx, &pb.CheckHoldersRequest{FileHash: fileHash})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	holders := make([]Holder, 0, len(response.GetHolders()))
+	for _, user := range response.GetHolders() {
+		holder := Holder{
+			Address: net.JoinHostPort(user.GetIp(), strconv.Itoa(int(user.GetPort()))),
+			Price:   float64(user.GetPrice()),
+		}
+		if _, err := peer.Decode(user.GetId()); err == nil {
+			holder.PeerID = user.GetId()
+		}
+		holders = append(holders, holder)
+	}
+	return holders, nil
+}
+
+// NotifyStore tells the market this node stores file.
+func (market *MarketClient) NotifyStore(ctx context.Context, file *pb.FileDesc) (*pb.StorageACKResponse, error) {
+	var ack *pb.StorageACKResponse
+	err := market.call(ctx, "NotifyFileStore", func(ctx context.Context, client pb.FileShareClient) error {
+		var err error
+		ack, err = client.NotifyFileStore(ctx, file)
+		return err
+	})
+	return ack, err
+}
+
+// NotifyUnstore tells the market this node no longer stores file.
+func (market *MarketClient) NotifyUnstore(ctx context.Context, file *pb.FileDesc) (*pb.StorageACKResponse, error) {
+	var ack *pb.StorageACKResponse
+	err := market.call(ctx, "NotifyFileUnstore", func(ctx context.Context, client pb.FileShareClient) error {
+		var err error
+		ack, err = client.NotifyFileUnstore(ctx, file)
+		return err
+	})
+	return ack, err
+}
+
+// AllFiles lists every file the market knows. If the list breaks off, it is
+// asked for again from the start.
+func (market *MarketClient) AllFiles(ctx context.Context, me *pb.StorageIP) ([]*pb.FileDesc, error) {
+	var files []*pb.FileDesc
+	err := market.call(ctx, "RequestAllAvailableFileNames", func(ctx context.Context, client pb.FileShareClient) error {
+		files = nil
+		stream, err := client.RequestAllAvailableFileNames(ctx, me)
+		if err != nil {
+			return err
+		}
+		for {
+			file, err := stream.Recv()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			files = append(files, file)
+		}
+	})
+	return files, err
+}
+
+// MergeHolders adds the holders in more that are not in holders yet. Holders
+// are the same if they have the same peer ID, or no peer ID and the same
+// address.
+func MergeHolders(holders []Holder, more []Holder) []Holder {
+	merged := append([]Holder{}, holders...)
+	seen := make(map[string]bool, len(holders))
+	for _, holder := range holders {
+		seen[holder.name()] = true
+	}
+	for _, holder := range more {
+		if !seen[holder.name()] {
+			seen[holder.name()] = true
+			merged = append(merged, holder)
+		}
+	}
+	return merged
+}
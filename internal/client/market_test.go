@@ -0,0 +1,153 @@
+package client
+
+import (
+	"context"
+	"errors"
+	pb "orca-peer/internal/fileshare"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// flakyMarket fails the first failures calls with code. onCall runs at the
+// start of every call, if set.
+type flakyMarket struct {
+	pb.FileShareClient
+	failures int
+	code     codes.Code
+	calls    int
+	onCall   func()
+}
+
+func (market *flakyMarket) CheckHolders(ctx context.Context, request *pb.CheckHoldersRequest, opts ...grpc.CallOption) (*pb.HoldersResponse, error) {
+	market.calls++
+	if market.onCall != nil {
+		market.onCall()
+	}
+	if market.calls <= market.failures {
+		return nil, status.Error(market.code, "failing")
+	}
+	return &pb.HoldersResponse{Holders: []*pb.User{{Id: "holder", Ip: "10.0.0.1", Port: 80, Price: 2}}}, nil
+}
+
+func TestMarketRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int
+		code      codes.Code
+		wantCalls int
+		wantErr   bool
+	}{
+		{"recovers", 2, codes.Unavailable, 3, false},
+		{"gives up", 5, codes.Unavailable, 3, true},
+		{"not retryable", 1, codes.InvalidArgument, 1, true},
+	}
+	for _, test := range tests {
+		rpc := &flakyMarket{failures: test.failures, code: test.code}
+		market := newMarketClient(MarketConfig{Retries: 2, BreakerThreshold: 10}, &pb.User{})
+		market.backoff = time.Millisecond
+		market.addEndpoint("market", nil, rpc)
+		holders, err := market.CheckHolders(context.Background(), "file")
+		if (err != nil) != test.wantErr || rpc.calls != test.wantCalls {
+			t.Errorf("%s: expected %d calls and error %v, got %d calls and %v", test.name, test.wantCalls, test.wantErr, rpc.calls, err)
+		}
+		if err == nil && (len(holders) != 1 || holders[0].Address != "10.0.0.1:80" || holders[0].PeerID != "") {
+			t.Errorf("%s: unexpected holders %v", test.name, holders)
+		}
+	}
+}
+
+func TestMarketErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		code codes.Code
+		want error
+	}{
+		{"unavailable", codes.Unavailable, ErrMarketUnavailable},
+		{"rejected", codes.InvalidArgument, ErrMarketRejected},
+	}
+	for _, test := range tests {
+		market := newMarketClient(MarketConfig{}, &pb.User{})
+		market.addEndpoint("market", nil, &flakyMarket{failures: 1, code: test.code})
+		_, err := market.CheckHolders(context.Background(), "file")
+		var marketErr *MarketError
+		if !errors.Is(err, test.want) || !errors.As(err, &marketErr) || status.Code(marketErr.Cause) != test.code {
+			t.Errorf("%s: expected %v, got %v", test.name, test.want, err)
+		}
+	}
+	if _, err := newMarketClient(MarketConfig{}, &pb.User{}).CheckHolders(context.Background(), "file"); !errors.Is(err, ErrNoMarket) {
+		t.Errorf("expected %v without markets, got %v", ErrNoMarket, err)
+	}
+}
+
+func TestMarketFailover(t *testing.T) {
+	down := &flakyMarket{failures: 100, code: codes.Unavailable}
+	up := &flakyMarket{}
+	market := newMarketClient(MarketConfig{}, &pb.User{})
+	market.addEndpoint("down", nil, down)
+	market.addEndpoint("up", nil, up)
+
+	for i := 0; i < 2; i++ {
+		if _, err := market.CheckHolders(context.Background(), "file"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The second call starts at the market that answered the first
+	if down.calls != 1 || up.calls != 2 {
+		t.Errorf("expected 1 call to the failing market and 2 to the other, got %d and %d", down.calls, up.calls)
+	}
+}
+
+func TestMarketBreaker(t *testing.T) {
+	now := time.Now()
+	rpc := &flakyMarket{failures: 100, code: codes.Unavailable}
+	market := newMarketClient(MarketConfig{BreakerThreshold: 2, BreakerCooldown: 30}, &pb.User{})
+	market.addEndpoint("market", nil, rpc)
+	market.endpoints[0].breaker.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		market.CheckHolders(context.Background(), "file")
+	}
+	_, err := market.CheckHolders(context.Background(), "file")
+	if !errors.Is(err, ErrCircuitOpen) || rpc.calls != 2 {
+		t.Fatalf("expected the open breaker to skip the market after 2 calls, got %d calls and %v", rpc.calls, err)
+	}
+
+	// After the cooldown a single call is let through, and the market
+	// answering it closes the breaker
+	now = now.Add(31 * time.Second)
+	rpc.failures = 0
+	if _, err := market.CheckHolders(context.Background(), "file"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := market.CheckHolders(context.Background(), "file"); err != nil || rpc.calls != 4 {
+		t.Errorf("expected the breaker to close, got %d calls and %v", rpc.calls, err)
+	}
+}
+
+func TestMarketBreakerCancelledProbe(t *testing.T) {
+	now := time.Now()
+	rpc := &flakyMarket{failures: 100, code: codes.Unavailable}
+	market := newMarketClient(MarketConfig{BreakerThreshold: 1, BreakerCooldown: 30}, &pb.User{})
+	market.addEndpoint("market", nil, rpc)
+	market.endpoints[0].breaker.now = func() time.Time { return now }
+	market.CheckHolders(context.Background(), "file")
+
+	// The caller gives up during the single call let through after the
+	// cooldown
+	now = now.Add(31 * time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	rpc.onCall = cancel
+	if _, err := market.CheckHolders(ctx, "file"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the cancelled call to fail, got %v", err)
+	}
+
+	rpc.onCall = nil
+	rpc.failures = 0
+	if _, err := market.CheckHolders(context.Background(), "file"); err != nil || rpc.calls != 3 {
+		t.Errorf("expected the market to be tried again, got %d calls and %v", rpc.calls, err)
+	}
+}
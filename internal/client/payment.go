@@ -0,0 +1,83 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"orca-peer/internal/payment"
+	"strconv"
+	"strings"
+)
+
+var ErrNoPaymentKey = errors.New("the holder wants payment but no key is loaded to sign receipts")
+
+// setPaymentKey tells the holder which key our receipts will be signed with.
+func (client *Client) setPaymentKey(req *http.Request) error {
+	if client.privateKey == nil {
+		return nil
+	}
+	encoded, err := payment.EncodePublicKey(&client.privateKey.PublicKey)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(payment.HeaderPublicKey, encoded)
+	return nil
+}
+
+// newPayer reads the payment terms the holder sent in the headers header
+// looks up. It returns nil when the transfer is free.
+func (client *Client) newPayer(header func(key string) string, fileHash string) (*payment.Payer, int64, error) {
+	price, _ := strconv.ParseFloat(header(payment.HeaderPrice), 64)
+	if price <= 0 {
+		return nil, 0, nil
+	}
+	if client.privateKey == nil {
+		return nil, 0, ErrNoPaymentKey
+	}
+	transferID := header(payment.HeaderTransferID)
+	holderHash := header(payment.HeaderFileHash)
+	if transferID == "" || holderHash == "" {
+		return nil, 0, errors.New("holder asked for payment without naming the transfer")
+	}
+	if fileHash != "" && holderHash != fileHash {
+		return nil, 0, fmt.Errorf("holder wants payment for %s instead of %s", holderHash, fileHash)
+	}
+	interval, err := strconv.ParseInt(header(payment.HeaderInterval), 10, 64)
+	if err != nil || interval <= 0 {
+		interval = payment.DefaultInterval
+	}
+	return payment.NewPayer(transferID, holderHash, price, client.privateKey), interval, nil
+}
+
+// payUpTo sends a receipt for the first size bytes of a transfer to the
+// holder at address, unless an earlier receipt already covers them.
+func (client *Client) payUpTo(ctx context.Context, address string, payer *payment.Payer, size int64) error {
+	signed, err := payer.PayUpTo(size)
+	if err != nil || signed == nil {
+		return err
+	}
+	body, err := json.Marshal(signed)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("http://%s/sendReceipt", address), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("receipt rejected with status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	payer.Accepted(size)
+	return nil
+}
@@ -0,0 +1,250 @@
+package client
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"orca-peer/internal/payment"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/p2p/protocol/ping"
+)
+
+// Strategies decide which holders a download prefers
+const (
+	StrategyCheapest = "cheapest"
+	StrategyFastest  = "fastest"
+	StrategyBalanced = "balanced"
+)
+
+// How long measuring the latency of one holder may take
+const latencyTimeout = 2 * time.Second
+
+var (
+	ErrNoAffordableHolders = errors.New("no holder offers the file at or below the max price")
+	ErrPriceAboveQuote     = errors.New("holder asks more per MB than it was selected for")
+)
+
+type DownloadConfig struct {
+	// The most this node pays per MB. 0 means no limit.
+	MaxPrice float64 `json:"max_price"`
+	Strategy string  `json:"strategy"`
+}
+
+func (config DownloadConfig) Validate() error {
+	if config.MaxPrice < 0 {
+		return fmt.Errorf("max price can not be negative")
+	}
+	switch config.Strategy {
+	case StrategyCheapest, StrategyFastest, StrategyBalanced, "":
+		return nil
+	}
+	return fmt.Errorf("unknown download strategy %q", config.Strategy)
+}
+
+/*
+Select ranks the holders that ask at most config.MaxPrice per MB and keeps the
+best MaxHolders of them. The rest are returned as too expensive.
+
+  - cheapest orders by price, then reputation
+  - fastest orders by latency, holders whose latency is unknown last, then
+    reputation
+  - balanced ranks holders by price, latency and reputation separately and
+    orders them by the sum of the three places
+*/
+func (client *Client) Select(holders []Holder, config DownloadConfig) ([]Holder, []Holder) {
+	selected := []Holder{}
+	tooExpensive := []Holder{}
+	for _, holder := range holders {
+		if config.MaxPrice > 0 && holder.Price > config.MaxPrice {
+			tooExpensive = append(tooExpensive, holder)
+		} else {
+			selected = append(selected, holder)
+		}
+	}
+
+	scores := make([]float64, len(selected))
+	for i, holder := range selected {
+		scores[i] = client.reputation.Score(holder.name())
+	}
+	byPrice := func(a, b int) int { return cmp.Compare(selected[a].Price, selected[b].Price) }
+	byLatency := func(a, b int) int {
+		la, lb := selected[a].Latency, selected[b].Latency
+		switch {
+		case la == lb:
+			return 0
+		case la == 0:
+			return 1
+		case lb == 0:
+			return -1
+		}
+		return cmp.Compare(la, lb)
+	}
+	byScore := func(a, b int) int { return cmp.Compare(scores[b], scores[a]) }
+
+	var order []func(a, b int) int
+	switch config.Strategy {
+	case StrategyFastest:
+		order = []func(a, b int) int{byLatency, byScore, byPrice}
+	case StrategyBalanced:
+		places := make([]int, len(selected))
+		for _, by := range []func(a, b int) int{byPrice, byLatency, byScore} {
+			for i, place := range placesBy(len(selected), by) {
+				places[i] += place
+			}
+		}
+		byPlaces := func(a, b int) int { return cmp.Compare(places[a], places[b]) }
+		order = []func(a, b int) int{byPlaces, byPrice}
+	default:
+		order = []func(a, b int) int{byPrice, byScore, byLatency}
+	}
+
+	indexes := make([]int, len(selected))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	slices.SortStableFunc(indexes, func(a, b int) int {
+		for _, by := range order {
+			if c := by(a, b); c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+	ranked := make([]Holder, 0, len(selected))
+	for _, index := range indexes {
+		ranked = append(ranked, selected[index])
+	}
+	if len(ranked) > MaxHolders {
+		ranked = ranked[:MaxHolders]
+	}
+	return ranked, tooExpensive
+}
+
+// placesBy returns the place of each of n holders when ordered by by. Holders
+// that compare equal share a place.
+func placesBy(n int, by func(a, b int) int) []int {
+	indexes := make([]int, n)
+	for i := range indexes {
+		indexes[i] = i
+	}
+	slices.SortStableFunc(indexes, by)
+	places := make([]int, n)
+	for i, index := range indexes {
+		if i > 0 && by(indexes[i-1], index) == 0 {
+			places[index] = places[indexes[i-1]]
+		} else {
+			places[index] = i
+		}
+	}
+	return places
+}
+
+// Quote is what downloading a file would look like before it starts.
+type Quote struct {
+	Holders      []Holder
+	TooExpensive []Holder
+	// Size of the file in bytes, or -1 if no holder said
+	Size int64
+	// What the download costs if every byte comes from the cheapest or the
+	// most expensive selected holder
+	MinCost float64
+	MaxCost float64
+}
+
+/*
+Quote measures the latency of the holders that can be reached by peer ID,
+selects holders according to config and asks them how large the file is. No
+transfer is started, so holders are not asked to approve anything yet.
+*/
+func (client *Client) Quote(ctx context.Context, holders []Holder, cid string, config DownloadConfig) (*Quote, error) {
+	holders = client.measureLatency(ctx, holders)
+	selected, tooExpensive := client.Select(holders, config)
+	quote := &Quote{Holders: selected, TooExpensive: tooExpensive, Size: -1}
+	if len(selected) == 0 {
+		if len(tooExpensive) > 0 {
+			return quote, ErrNoAffordableHolders
+		}
+		return quote, ErrNoSources
+	}
+	for _, holder := range selected {
+		size, err := client.fileSize(ctx, holder, cid)
+		if err == nil {
+			quote.Size = size
+			break
+		}
+	}
+	if quote.Size >= 0 {
+		quote.MinCost = payment.Cost(quote.Size, selected[0].Price)
+		quote.MaxCost = quote.MinCost
+		for _, holder := range selected {
+			cost := payment.Cost(quote.Size, holder.Price)
+			if cost < quote.MinCost {
+				quote.MinCost = cost
+			}
+			if cost > quote.MaxCost {
+				quote.MaxCost = cost
+			}
+		}
+	}
+	return quote, nil
+}
+
+// measureLatency pings the holders that have a peer ID, if the client has a
+// host to ping from.
+func (client *Client) measureLatency(ctx context.Context, holders []Holder) []Holder {
+	measured := append([]Holder{}, holders...)
+	if client.host == nil {
+		return measured
+	}
+	var wg sync.WaitGroup
+	for i := range measured {
+		id, err := peer.Decode(measured[i].PeerID)
+		if err != nil || measured[i].Latency > 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(holder *Holder) {
+			defer wg.Done()
+			pingCtx, cancel := context.WithTimeout(ctx, latencyTimeout)
+			defer cancel()
+			select {
+			case result := <-ping.Ping(pingCtx, client.host, id):
+				if result.Error == nil {
+					holder.Latency = result.RTT
+				}
+			case <-pingCtx.Done():
+			}
+		}(&measured[i])
+	}
+	wg.Wait()
+	return measured
+}
+
+// fileSize asks holder how large the file is with a HEAD request, which holders
+// answer without asking for approval.
+func (client *Client) fileSize(ctx context.Context, holder Holder, cid string) (int64, error) {
+	address := holder.Address
+	if holder.PeerID != "" && client.p2p {
+		address = holder.PeerID
+	}
+	url := fmt.Sprintf("http://%s/requestFile/%s", address, cid)
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := client.httpClient().Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.ContentLength < 0 {
+		return 0, fmt.Errorf("http status %d from %s", resp.StatusCode, address)
+	}
+	return resp.ContentLength, nil
+}
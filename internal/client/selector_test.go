@@ -0,0 +1,92 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"orca-peer/internal/payment"
+	"orca-peer/internal/reputation"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func addresses(holders []Holder) []string {
+	names := []string{}
+	for _, holder := range holders {
+		names = append(names, holder.Address)
+	}
+	return names
+}
+
+func TestSelectHolders(t *testing.T) {
+	book, err := reputation.Open(t.TempDir(), reputation.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	book.Record("trusted", reputation.ServedPiece)
+	book.Record("cheater", reputation.CorruptPiece)
+	holders := []Holder{
+		{Address: "cheater", Price: 1, Latency: 5 * time.Millisecond},
+		{Address: "slow", Price: 1, Latency: 300 * time.Millisecond},
+		{Address: "trusted", Price: 2, Latency: 50 * time.Millisecond},
+		{Address: "unmeasured", Price: 2},
+		{Address: "greedy", Price: 9, Latency: time.Millisecond},
+	}
+	client := &Client{reputation: book}
+
+	tests := []struct {
+		config DownloadConfig
+		want   []string
+	}{
+		{DownloadConfig{Strategy: StrategyCheapest}, []string{"slow", "cheater", "trusted", "unmeasured", "greedy"}},
+		{DownloadConfig{Strategy: StrategyFastest}, []string{"greedy", "cheater", "trusted", "slow", "unmeasured"}},
+		{DownloadConfig{Strategy: StrategyBalanced}, []string{"slow", "trusted", "cheater", "greedy", "unmeasured"}},
+		{DownloadConfig{Strategy: StrategyCheapest, MaxPrice: 2}, []string{"slow", "cheater", "trusted", "unmeasured"}},
+	}
+	for _, test := range tests {
+		selected, tooExpensive := client.Select(holders, test.config)
+		if fmt.Sprint(addresses(selected)) != fmt.Sprint(test.want) {
+			t.Errorf("%+v: expected %v, got %v", test.config, test.want, addresses(selected))
+		}
+		if len(selected)+len(tooExpensive) != len(holders) {
+			t.Errorf("%+v: expected every holder to be selected or too expensive", test.config)
+		}
+	}
+
+	many := []Holder{}
+	for i := 0; i < MaxHolders+3; i++ {
+		many = append(many, Holder{Address: strconv.Itoa(i)})
+	}
+	if selected, _ := client.Select(many, DownloadConfig{}); len(selected) != MaxHolders {
+		t.Errorf("expected at most %d holders, got %d", MaxHolders, len(selected))
+	}
+}
+
+func TestQuote(t *testing.T) {
+	size := int64(3 * payment.MB)
+	holder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("expected only HEAD requests for a quote, got %s", r.Method)
+		}
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	}))
+	defer holder.Close()
+
+	client := &Client{}
+	holders := []Holder{{Address: hostOf(holder), Price: 2}, {Address: hostOf(holder), Price: 1}, {Address: "expensive", Price: 10}}
+	quote, err := client.Quote(context.Background(), holders, "cid", DownloadConfig{MaxPrice: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quote.Size != size || quote.MinCost != 3 || quote.MaxCost != 6 || len(quote.TooExpensive) != 1 {
+		t.Errorf("unexpected quote %+v", quote)
+	}
+
+	_, err = client.Quote(context.Background(), holders, "cid", DownloadConfig{MaxPrice: 0.5})
+	if !errors.Is(err, ErrNoAffordableHolders) {
+		t.Errorf("expected ErrNoAffordableHolders, got %v", err)
+	}
+}
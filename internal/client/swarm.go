@@ -0,0 +1,655 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	orcaHash "orca-peer/internal/hash"
+	"orca-peer/internal/payment"
+	"orca-peer/internal/reputation"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultPieceSize    = 1024 * 1024
+	DefaultPieceTimeout = 30 * time.Second
+	DefaultMaxFailures  = 3
+	// At most this many holders are downloaded from at once
+	MaxHolders = 8
+)
+
+var (
+	ErrNoSources      = errors.New("no sources to download from")
+	ErrAllSourcesLost = errors.New("every source failed before the download finished")
+	ErrPieceCorrupted = errors.New("piece failed verification")
+	ErrPieceWrongSize = errors.New("piece has the wrong size")
+)
+
+// Piece is one part of a file that can be fetched independently of the rest.
+// Hash is the chunk hash of the piece when it is known ahead of time.
+type Piece struct {
+	Index  int
+	Offset int64
+	Length int64
+	Hash   string
+}
+
+// PieceSource is a holder of a file that pieces can be requested from.
+type PieceSource interface {
+	Name() string
+	FetchPiece(ctx context.Context, piece Piece) ([]byte, error)
+}
+
+// SplitPieces divides a file of the given size into pieces of pieceSize bytes.
+func SplitPieces(size int64, pieceSize int64) []Piece {
+	pieces := make([]Piece, 0, (size+pieceSize-1)/pieceSize)
+	for offset := int64(0); offset < size; offset += pieceSize {
+		length := pieceSize
+		if offset+length > size {
+			length = size - offset
+		}
+		pieces = append(pieces, Piece{Index: len(pieces), Offset: offset, Length: length})
+	}
+	return pieces
+}
+
+// VerifyPiece checks that data is a complete, uncorrupted copy of piece.
+func VerifyPiece(piece Piece, data []byte) error {
+	if int64(len(data)) != piece.Length {
+		return fmt.Errorf("%w: got %d bytes, expected %d", ErrPieceWrongSize, len(data), piece.Length)
+	}
+	if piece.Hash != "" {
+		if orcaHash.HashChunk(data) != piece.Hash {
+			return ErrPieceCorrupted
+		}
+	}
+	return nil
+}
+
+// httpSource fetches byte ranges of a file from a peer's /requestFile/ route.
+type httpSource struct {
+	address    string
+	remoteName string
+	client     *http.Client
+}
+
+func NewHTTPSource(address string, remoteName string, client *http.Client) PieceSource {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &httpSource{address: address, remoteName: remoteName, client: client}
+}
+
+func (source *httpSource) Name() string {
+	return source.address
+}
+
+func (source *httpSource) url() string {
+	return fmt.Sprintf("http://%s/requestFile/%s", source.address, source.remoteName)
+}
+
+func (source *httpSource) FetchPiece(ctx context.Context, piece Piece) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source.url(), nil)
+	if err != nil {
+		return nil, err
+	}
+	end := piece.Offset + piece.Length - 1
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", piece.Offset, end))
+	resp, err := source.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		return nil, fmt.Errorf("http status %d from %s", resp.StatusCode, source.address)
+	}
+	start, _, err := parseContentRange(resp.Header.Get("Content-Range"))
+	if err != nil {
+		return nil, err
+	}
+	if start != piece.Offset {
+		return nil, fmt.Errorf("%s sent range starting at %d instead of %d", source.address, start, piece.Offset)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, piece.Length+1))
+}
+
+// Swarm downloads the pieces of one file from several sources at once. Every
+// source gets its own worker that keeps taking the next missing piece. A piece
+// that fails, times out or does not verify goes back in the queue for another
+// source, and a source that fails MaxFailures times in a row is dropped.
+type Swarm struct {
+	PieceTimeout time.Duration
+	MaxFailures  int
+	// Report, if set, is told how every piece fetched from a source went,
+	// with a nil error for pieces that arrived intact
+	Report func(source string, err error)
+
+	mu        sync.Mutex
+	cond      *sync.Cond
+	pending   []int
+	fetching  map[int]map[string]bool
+	done      []bool
+	remaining int
+	alive     int
+	served    map[string]int
+	pieces    []Piece
+	// Bytes written since the last checkpoint
+	unsaved int64
+
+	// Held while the part file is synced and the state saved
+	saveMu    sync.Mutex
+	partFile  *os.File
+	state     swarmState
+	statePath string
+}
+
+func NewSwarm() *Swarm {
+	return &Swarm{
+		PieceTimeout: DefaultPieceTimeout,
+		MaxFailures:  DefaultMaxFailures,
+	}
+}
+
+/*
+Download writes every piece into destination. The file is assembled in
+destination + ".part" and only moved into place once all pieces arrived. The
+pieces that are synced to disk are recorded in destination + ".part.json", so
+a download that is interrupted keeps them and only fetches the rest when it is
+started again with the same pieces.
+*/
+func (swarm *Swarm) Download(ctx context.Context, sources []PieceSource, pieces []Piece, destination string) error {
+	if len(sources) == 0 {
+		return ErrNoSources
+	}
+	err := os.MkdirAll(filepath.Dir(destination), 0755)
+	if err != nil {
+		return err
+	}
+	partPath := destination + partialSuffix
+	statePath := destination + stateSuffix
+	partFile, err := os.OpenFile(partPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer partFile.Close()
+	done, err := resumePieces(statePath, partFile, pieces)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	swarm.reset(pieces, done, len(sources))
+	swarm.partFile = partFile
+	swarm.state = swarmState{Layout: pieceLayout(pieces)}
+	swarm.statePath = statePath
+	go func() {
+		<-ctx.Done()
+		swarm.mu.Lock()
+		swarm.cond.Broadcast()
+		swarm.mu.Unlock()
+	}()
+
+	var wg sync.WaitGroup
+	for _, source := range sources {
+		wg.Add(1)
+		go func(source PieceSource) {
+			defer wg.Done()
+			swarm.work(ctx, source, pieces, partFile)
+		}(source)
+	}
+	wg.Wait()
+
+	if swarm.remaining > 0 {
+		if swarm.remaining == len(pieces) {
+			// Nothing arrived that would be worth resuming
+			partFile.Close()
+			os.Remove(partPath)
+			os.Remove(statePath)
+		} else if err := swarm.checkpoint(); err != nil {
+			fmt.Println("Error saving download state:", err)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return ErrAllSourcesLost
+	}
+	if err := partFile.Sync(); err != nil {
+		return err
+	}
+	partFile.Close()
+	if err := os.Rename(partPath, destination); err != nil {
+		return err
+	}
+	os.Remove(statePath)
+	return nil
+}
+
+// swarmState is stored next to a partial swarm download so that it can be
+// resumed. Layout tells which pieces Done refers to.
+type swarmState struct {
+	Layout string `json:"layout"`
+	Done   []int  `json:"done"`
+}
+
+// pieceLayout fingerprints how a file is split into pieces.
+func pieceLayout(pieces []Piece) string {
+	layout := sha256.New()
+	for _, piece := range pieces {
+		fmt.Fprintf(layout, "%d %d %s\n", piece.Offset, piece.Length, piece.Hash)
+	}
+	return hex.EncodeToString(layout.Sum(nil))
+}
+
+/*
+resumePieces returns which pieces an earlier download already wrote to
+partFile, according to the state at statePath. Pieces with a hash are checked
+again. If the state is missing or belongs to other pieces, partFile is emptied
+and nothing counts as done.
+*/
+func resumePieces(statePath string, partFile *os.File, pieces []Piece) ([]bool, error) {
+	done := make([]bool, len(pieces))
+	var state swarmState
+	data, err := os.ReadFile(statePath)
+	if err != nil || json.Unmarshal(data, &state) != nil || state.Layout != pieceLayout(pieces) {
+		return done, partFile.Truncate(0)
+	}
+	for _, index := range state.Done {
+		if index < 0 || index >= len(pieces) {
+			continue
+		}
+		piece := pieces[index]
+		if piece.Hash != "" {
+			data := make([]byte, piece.Length)
+			if _, err := partFile.ReadAt(data, piece.Offset); err != nil || VerifyPiece(piece, data) != nil {
+				continue
+			}
+		}
+		done[index] = true
+	}
+	return done, nil
+}
+
+// Served reports how many pieces each source delivered in the last download.
+func (swarm *Swarm) Served() map[string]int {
+	swarm.mu.Lock()
+	defer swarm.mu.Unlock()
+	served := make(map[string]int, len(swarm.served))
+	for name, count := range swarm.served {
+		served[name] = count
+	}
+	return served
+}
+
+// reset prepares the swarm for fetching every piece that is not done yet.
+func (swarm *Swarm) reset(pieces []Piece, done []bool, sourceCount int) {
+	swarm.mu.Lock()
+	defer swarm.mu.Unlock()
+	swarm.cond = sync.NewCond(&swarm.mu)
+	swarm.pending = []int{}
+	for i := range pieces {
+		if !done[i] {
+			swarm.pending = append(swarm.pending, i)
+		}
+	}
+	swarm.fetching = make(map[int]map[string]bool)
+	swarm.done = done
+	swarm.remaining = len(swarm.pending)
+	swarm.alive = sourceCount
+	swarm.served = make(map[string]int)
+	swarm.pieces = pieces
+	swarm.unsaved = 0
+}
+
+/*
+checkpoint syncs the part file and records every piece that is done. Pieces
+are only marked done once they were written, so everything recorded is on
+disk.
+*/
+func (swarm *Swarm) checkpoint() error {
+	swarm.saveMu.Lock()
+	defer swarm.saveMu.Unlock()
+	swarm.mu.Lock()
+	done := []int{}
+	for i, isDone := range swarm.done {
+		if isDone {
+			done = append(done, i)
+		}
+	}
+	swarm.unsaved = 0
+	swarm.mu.Unlock()
+	if err := swarm.partFile.Sync(); err != nil {
+		return err
+	}
+	swarm.state.Done = done
+	return saveState(swarm.statePath, swarm.state)
+}
+
+func (swarm *Swarm) work(ctx context.Context, source PieceSource, pieces []Piece, partFile *os.File) {
+	failures := 0
+	defer func() {
+		swarm.mu.Lock()
+		swarm.alive--
+		swarm.cond.Broadcast()
+		swarm.mu.Unlock()
+	}()
+	for {
+		index, ok := swarm.next(ctx, source.Name())
+		if !ok {
+			return
+		}
+		piece := pieces[index]
+		pieceCtx, cancel := context.WithTimeout(ctx, swarm.PieceTimeout)
+		data, err := source.FetchPiece(pieceCtx, piece)
+		cancel()
+		if err == nil {
+			err = VerifyPiece(piece, data)
+		}
+		if err == nil {
+			_, err = partFile.WriteAt(data, piece.Offset)
+			if err != nil {
+				// The local disk is the problem, not the source
+				swarm.finish(index, source.Name(), false)
+				fmt.Println("Error writing piece:", err)
+				return
+			}
+		}
+		if swarm.finish(index, source.Name(), err == nil) {
+			if err := swarm.checkpoint(); err != nil {
+				fmt.Println("Error saving download state:", err)
+			}
+		}
+		if swarm.Report != nil {
+			swarm.Report(source.Name(), err)
+		}
+		if err != nil {
+			failures++
+			fmt.Printf("Piece %d from %s failed: %s\n", index, source.Name(), err)
+			if failures >= swarm.MaxFailures {
+				fmt.Printf("Dropping source %s after %d failures\n", source.Name(), failures)
+				return
+			}
+			continue
+		}
+		failures = 0
+	}
+}
+
+// next hands out a piece nobody has started yet. Once all pieces are handed
+// out, idle workers also pick up pieces that are still being fetched by
+// someone else so that one slow source cannot hold up the end of the download.
+func (swarm *Swarm) next(ctx context.Context, name string) (int, bool) {
+	swarm.mu.Lock()
+	defer swarm.mu.Unlock()
+	for {
+		if swarm.remaining == 0 || ctx.Err() != nil {
+			return 0, false
+		}
+		if len(swarm.pending) > 0 {
+			index := swarm.pending[0]
+			swarm.pending = swarm.pending[1:]
+			swarm.claim(index, name)
+			return index, true
+		}
+		for index, fetchers := range swarm.fetching {
+			if !swarm.done[index] && !fetchers[name] && len(fetchers) < 2 {
+				swarm.claim(index, name)
+				return index, true
+			}
+		}
+		swarm.cond.Wait()
+	}
+}
+
+func (swarm *Swarm) claim(index int, name string) {
+	if swarm.fetching[index] == nil {
+		swarm.fetching[index] = make(map[string]bool)
+	}
+	swarm.fetching[index][name] = true
+}
+
+// finish records how fetching piece index from name went. It reports whether
+// enough was written since the last checkpoint to make another one.
+func (swarm *Swarm) finish(index int, name string, success bool) bool {
+	swarm.mu.Lock()
+	defer swarm.mu.Unlock()
+	delete(swarm.fetching[index], name)
+	switch {
+	case swarm.done[index]:
+		// Someone else already delivered this piece
+	case success:
+		swarm.done[index] = true
+		swarm.remaining--
+		swarm.served[name]++
+		swarm.unsaved += swarm.pieces[index].Length
+		delete(swarm.fetching, index)
+	case len(swarm.fetching[index]) == 0:
+		delete(swarm.fetching, index)
+		swarm.pending = append(swarm.pending, index)
+	}
+	swarm.cond.Broadcast()
+	// The finished file is synced when it is moved into place
+	return swarm.remaining > 0 && swarm.unsaved >= checkpointBytes
+}
+
+// ManifestPieces turns every chunk of a manifest into a piece to download.
+func ManifestPieces(manifest *orcaHash.Manifest) []Piece {
+	pieces := make([]Piece, len(manifest.Chunks))
+	for i, chunkHash := range manifest.Chunks {
+		offset, length := manifest.ChunkOffset(i)
+		pieces[i] = Piece{Index: i, Offset: offset, Length: length, Hash: chunkHash}
+	}
+	return pieces
+}
+
+// chunkSource fetches chunks by hash from a peer that accepted our request
+// for the file they belong to. If the peer charges for the file, every chunk
+// is paid for right before it is requested.
+type chunkSource struct {
+	address    string
+	transferID string
+	client     *Client
+	payer      *payment.Payer
+	received   int64
+}
+
+func (source *chunkSource) Name() string {
+	return source.address
+}
+
+func (source *chunkSource) FetchPiece(ctx context.Context, piece Piece) ([]byte, error) {
+	if source.payer != nil {
+		// Bytes paid for a chunk that never arrived count toward the next one
+		err := source.client.payUpTo(ctx, source.address, source.payer, source.received+piece.Length)
+		if err != nil {
+			return nil, err
+		}
+	}
+	url := fmt.Sprintf("http://%s/chunk/%s?transfer=%s", source.address, piece.Hash, source.transferID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := source.client.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http status %d from %s", resp.StatusCode, source.address)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, piece.Length+1))
+	if err != nil {
+		return nil, err
+	}
+	source.received += int64(len(data))
+	return data, nil
+}
+
+// openChunkSource asks a holder for the manifest of a file and checks that it
+// belongs to cid. The returned source fetches chunks under the transfer the
+// holder started for us. The holder may not ask more per MB than quoted, or
+// than maxPrice when it is set.
+func (client *Client) openChunkSource(ctx context.Context, address string, cid string, quoted float64, maxPrice float64) (*orcaHash.Manifest, *chunkSource, error) {
+	url := fmt.Sprintf("http://%s/manifest/%s", address, cid)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := client.setPaymentKey(req); err != nil {
+		return nil, nil, err
+	}
+	resp, err := client.httpClient().Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, nil, fmt.Errorf("http status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var manifest orcaHash.Manifest
+	if err := json.NewDecoder(resp.Body).Decode(&manifest); err != nil {
+		return nil, nil, err
+	}
+	if manifest.Root != cid || manifest.Verify() != nil {
+		return nil, nil, orcaHash.ErrInvalidManifest
+	}
+	payer, _, err := client.newPayer(resp.Header.Get, cid)
+	if err != nil {
+		return nil, nil, err
+	}
+	if payer != nil && (payer.PricePerMB > quoted || (maxPrice > 0 && payer.PricePerMB > maxPrice)) {
+		return nil, nil, fmt.Errorf("%w: asks %f, quoted %f", ErrPriceAboveQuote, payer.PricePerMB, quoted)
+	}
+	source := &chunkSource{
+		address:    address,
+		transferID: resp.Header.Get(payment.HeaderTransferID),
+		client:     client,
+		payer:      payer,
+	}
+	return &manifest, source, nil
+}
+
+// Holder is a peer that has a file. It is reached over libp2p by its peer ID
+// when the client has a host, and over HTTP at Address otherwise or when that
+// fails. Price is what it asks per MB, and Latency is 0 until it is measured.
+type Holder struct {
+	PeerID  string
+	Address string
+	Price   float64
+	Latency time.Duration
+}
+
+// openHolder opens a chunk source on holder, trying libp2p first.
+func (client *Client) openHolder(ctx context.Context, holder Holder, cid string, maxPrice float64) (*orcaHash.Manifest, *chunkSource, error) {
+	var err error
+	if holder.PeerID != "" && client.p2p {
+		manifest, source, p2pErr := client.openChunkSource(ctx, holder.PeerID, cid, holder.Price, maxPrice)
+		if p2pErr == nil {
+			return manifest, source, nil
+		}
+		err = p2pErr
+		fmt.Printf("Could not reach %s over libp2p: %s\n", holder.PeerID, p2pErr)
+	}
+	if holder.Address != "" {
+		return client.openChunkSource(ctx, holder.Address, cid, holder.Price, maxPrice)
+	}
+	if err == nil {
+		err = errors.New("holder has no address")
+	}
+	return nil, nil, err
+}
+
+func (holder Holder) name() string {
+	if holder.PeerID != "" {
+		return holder.PeerID
+	}
+	return holder.Address
+}
+
+// outcome is the reputation event for a piece fetched with err. Pieces that
+// were cancelled because someone else delivered them first do not count.
+func outcome(err error) (reputation.Event, bool) {
+	switch {
+	case err == nil:
+		return reputation.ServedPiece, true
+	case errors.Is(err, ErrPieceCorrupted), errors.Is(err, ErrPieceWrongSize):
+		return reputation.CorruptPiece, true
+	case errors.Is(err, context.DeadlineExceeded):
+		return reputation.TimedOut, true
+	case errors.Is(err, context.Canceled):
+		return "", false
+	}
+	return reputation.Failed, true
+}
+
+func (client *Client) report(source string, err error) {
+	event, ok := outcome(err)
+	if !ok || client.reputation == nil {
+		return
+	}
+	if err := client.reputation.Record(source, event); err != nil {
+		fmt.Println("Error recording reputation:", err)
+	}
+}
+
+// GetFileSwarm downloads the file with the given CID from the first MaxHolders
+// holders at the same time, see Select for putting the best ones first. Each
+// holder is asked for the manifest first, and every chunk is checked against
+// the manifest as it arrives. Holders that ask more than their Price or
+// maxPrice are skipped. How every holder did is recorded in the reputation
+// book, if there is one, and what was paid for is recorded in the ledger
+// before it returns. If the file arrived but recording it failed, the error
+// wraps ErrNotRecorded.
+func (client *Client) GetFileSwarm(ctx context.Context, holders []Holder, cid string, maxPrice float64) error {
+	if len(holders) > MaxHolders {
+		holders = holders[:MaxHolders]
+	}
+	var manifest *orcaHash.Manifest
+	sources := make([]PieceSource, 0, len(holders))
+	chunkSources := make([]*chunkSource, 0, len(holders))
+	for _, holder := range holders {
+		holderManifest, source, err := client.openHolder(ctx, holder, cid, maxPrice)
+		if err != nil {
+			fmt.Printf("Skipping %v: %s\n", holder, err)
+			client.report(holder.name(), err)
+			continue
+		}
+		manifest = holderManifest
+		sources = append(sources, source)
+		chunkSources = append(chunkSources, source)
+	}
+	if len(sources) == 0 {
+		return ErrNoSources
+	}
+
+	downloadDir := client.downloadDir
+	if downloadDir == "" {
+		downloadDir = "./files/requested/"
+	}
+	destination := filepath.Join(downloadDir, cid)
+	swarm := NewSwarm()
+	swarm.Report = client.report
+	err := swarm.Download(ctx, sources, ManifestPieces(manifest), destination)
+	if err != nil {
+		return err
+	}
+	for name, count := range swarm.Served() {
+		fmt.Println(name + " served " + strconv.Itoa(count) + " pieces")
+	}
+	if err := client.recordTransfers(cid, chunkSources); err != nil {
+		return fmt.Errorf("%w: %w", ErrNotRecorded, err)
+	}
+	return nil
+}
@@ -0,0 +1,415 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	orcaHash "orca-peer/internal/hash"
+	"orca-peer/internal/p2p"
+	"orca-peer/internal/payment"
+	"orca-peer/internal/reputation"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+)
+
+func serveData(data []byte) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(data))
+	}
+}
+
+func hostOf(server *httptest.Server) string {
+	return strings.TrimPrefix(server.URL, "http://")
+}
+
+func TestSplitPieces(t *testing.T) {
+	pieces := SplitPieces(10, 4)
+	if len(pieces) != 3 {
+		t.Fatalf("expected 3 pieces, got %d", len(pieces))
+	}
+	if pieces[2].Offset != 8 || pieces[2].Length != 2 {
+		t.Errorf("unexpected last piece %+v", pieces[2])
+	}
+	if len(SplitPieces(0, 4)) != 0 {
+		t.Errorf("expected no pieces for an empty file")
+	}
+}
+
+// serveChunks answers manifest and chunk requests for data the way a peer
+// holding it would.
+func serveChunks(t *testing.T, data []byte, hits *atomic.Int32) http.HandlerFunc {
+	manifest, chunks, err := orcaHash.BuildManifest(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/manifest/") {
+			w.Header().Set("X-Transfer-Id", "transfer")
+			json.NewEncoder(w).Encode(manifest)
+			return
+		}
+		hits.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		chunkHash := strings.TrimPrefix(r.URL.Path, "/chunk/")
+		for i, c := range manifest.Chunks {
+			if c == chunkHash && r.URL.Query().Get("transfer") == "transfer" {
+				w.Write(chunks[i])
+				return
+			}
+		}
+		http.NotFound(w, r)
+	}
+}
+
+func TestSwarmDownloadsFromSeveralHolders(t *testing.T) {
+	data := randomData(t, 16*orcaHash.ChunkSize+100)
+	var hitsA, hitsB atomic.Int32
+	holderA := httptest.NewServer(serveChunks(t, data, &hitsA))
+	defer holderA.Close()
+	holderB := httptest.NewServer(serveChunks(t, data, &hitsB))
+	defer holderB.Close()
+
+	client := &Client{downloadDir: t.TempDir()}
+	fileHash := cidOf(t, data)
+	err := client.GetFileSwarm(context.Background(), []Holder{{Address: hostOf(holderA)}, {Address: hostOf(holderB)}}, fileHash, 0)
+	if err != nil {
+		t.Fatalf("expected swarm download to succeed, got %s", err)
+	}
+	got, err := os.ReadFile(filepath.Join(client.downloadDir, fileHash))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("downloaded file does not match original")
+	}
+	if hitsA.Load() == 0 || hitsB.Load() == 0 {
+		t.Errorf("expected both holders to be used, got %d and %d requests", hitsA.Load(), hitsB.Load())
+	}
+}
+
+func TestSwarmRejectsForgedManifest(t *testing.T) {
+	data := randomData(t, 2*orcaHash.ChunkSize)
+	var hits atomic.Int32
+	holder := httptest.NewServer(serveChunks(t, randomData(t, len(data)), &hits))
+	defer holder.Close()
+
+	client := &Client{downloadDir: t.TempDir()}
+	err := client.GetFileSwarm(context.Background(), []Holder{{Address: hostOf(holder)}}, cidOf(t, data), 0)
+	if !errors.Is(err, ErrNoSources) {
+		t.Fatalf("expected ErrNoSources, got %v", err)
+	}
+	if hits.Load() != 0 {
+		t.Errorf("expected no chunks to be requested for a forged manifest")
+	}
+}
+
+func TestSwarmRejectsRaisedPrice(t *testing.T) {
+	data := randomData(t, 2*orcaHash.ChunkSize)
+	fileHash := cidOf(t, data)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hits atomic.Int32
+	chunks := serveChunks(t, data, &hits)
+	// The holder charges 5 per MB whatever it advertised
+	holder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(payment.HeaderPrice, "5")
+		w.Header().Set(payment.HeaderFileHash, fileHash)
+		chunks(w, r)
+	}))
+	defer holder.Close()
+
+	tests := []struct {
+		name     string
+		quoted   float64
+		maxPrice float64
+	}{
+		{"above the quote", 1, 0},
+		{"above the max price", 5, 2},
+	}
+	for _, test := range tests {
+		client := &Client{downloadDir: t.TempDir(), privateKey: key}
+		err := client.GetFileSwarm(context.Background(), []Holder{{Address: hostOf(holder), Price: test.quoted}}, fileHash, test.maxPrice)
+		if !errors.Is(err, ErrNoSources) {
+			t.Errorf("%s: expected ErrNoSources, got %v", test.name, err)
+		}
+	}
+	if hits.Load() != 0 {
+		t.Errorf("expected no chunks to be requested from a holder that raised its price")
+	}
+}
+
+func TestSwarmReportsUnrecordedDownload(t *testing.T) {
+	data := randomData(t, 2*orcaHash.ChunkSize)
+	fileHash := cidOf(t, data)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hits atomic.Int32
+	chunks := serveChunks(t, data, &hits)
+	holder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/sendReceipt" {
+			return
+		}
+		w.Header().Set(payment.HeaderPrice, "1")
+		w.Header().Set(payment.HeaderFileHash, fileHash)
+		chunks(w, r)
+	}))
+	defer holder.Close()
+
+	client := &Client{downloadDir: t.TempDir(), privateKey: key}
+	recorder := &recordingLedger{refused: hostOf(holder)}
+	client.UseLedger(recorder)
+	err = client.GetFileSwarm(context.Background(), []Holder{{Address: hostOf(holder), Price: 1}}, fileHash, 0)
+	if !errors.Is(err, ErrNotRecorded) || !errors.Is(err, ErrTransactionRefused) {
+		t.Fatalf("expected the refused transaction to be returned, got %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(client.downloadDir, fileHash))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("expected the file to be kept even though it was not recorded")
+	}
+}
+
+func newHost(t *testing.T) host.Host {
+	h, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { h.Close() })
+	return h
+}
+
+func TestSwarmOverLibp2pFallsBackToHTTP(t *testing.T) {
+	data := randomData(t, 8*orcaHash.ChunkSize)
+	var hitsP2P, hitsHTTP atomic.Int32
+
+	// One holder is only reachable over libp2p
+	p2pHolder := newHost(t)
+	listener := p2p.Listen(p2pHolder)
+	defer listener.Close()
+	go http.Serve(listener, serveChunks(t, data, &hitsP2P))
+
+	// The other has a peer ID nobody can reach, so its HTTP address is used
+	unreachable := newHost(t)
+	unreachableID := unreachable.ID()
+	unreachable.Close()
+	httpHolder := httptest.NewServer(serveChunks(t, data, &hitsHTTP))
+	defer httpHolder.Close()
+
+	consumer := newHost(t)
+	consumer.Peerstore().AddAddrs(p2pHolder.ID(), p2pHolder.Addrs(), peerstore.PermanentAddrTTL)
+	client := &Client{downloadDir: t.TempDir()}
+	client.UseHost(consumer, nil)
+
+	fileHash := cidOf(t, data)
+	holders := []Holder{
+		{PeerID: p2pHolder.ID().String()},
+		{PeerID: unreachableID.String(), Address: hostOf(httpHolder)},
+	}
+	if err := client.GetFileSwarm(context.Background(), holders, fileHash, 0); err != nil {
+		t.Fatalf("expected swarm download to succeed, got %s", err)
+	}
+	got, err := os.ReadFile(filepath.Join(client.downloadDir, fileHash))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("downloaded file does not match original")
+	}
+	if hitsP2P.Load() == 0 || hitsHTTP.Load() == 0 {
+		t.Errorf("expected both holders to be used, got %d over libp2p and %d over HTTP", hitsP2P.Load(), hitsHTTP.Load())
+	}
+}
+
+func TestSwarmReassignsFailedAndSlowPieces(t *testing.T) {
+	data := randomData(t, 64*1024)
+	good := httptest.NewServer(serveData(data))
+	defer good.Close()
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusInternalServerError)
+	}))
+	defer broken.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	defer slow.Close()
+
+	sources := []PieceSource{
+		NewHTTPSource(hostOf(good), "file", nil),
+		NewHTTPSource(hostOf(broken), "file", nil),
+		NewHTTPSource(hostOf(slow), "file", nil),
+	}
+	swarm := NewSwarm()
+	swarm.PieceTimeout = 50 * time.Millisecond
+	destination := filepath.Join(t.TempDir(), "file")
+	err := swarm.Download(context.Background(), sources, SplitPieces(int64(len(data)), 8*1024), destination)
+	if err != nil {
+		t.Fatalf("expected download to succeed, got %s", err)
+	}
+	got, err := os.ReadFile(destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("downloaded file does not match original")
+	}
+	served := swarm.Served()
+	if served[hostOf(good)] != 8 {
+		t.Errorf("expected every piece to come from the good holder, got %v", served)
+	}
+}
+
+func TestSwarmRejectsCorruptPieces(t *testing.T) {
+	data := randomData(t, 16*1024)
+	corrupt := bytes.Repeat([]byte{0xAA}, len(data))
+	good := httptest.NewServer(serveData(data))
+	defer good.Close()
+	liar := httptest.NewServer(serveData(corrupt))
+	defer liar.Close()
+
+	pieces := SplitPieces(int64(len(data)), 4*1024)
+	for i := range pieces {
+		pieces[i].Hash = orcaHash.HashChunk(data[pieces[i].Offset : pieces[i].Offset+pieces[i].Length])
+	}
+	sources := []PieceSource{
+		NewHTTPSource(hostOf(liar), "file", nil),
+		NewHTTPSource(hostOf(good), "file", nil),
+	}
+	swarm := NewSwarm()
+	var mu sync.Mutex
+	events := map[string]map[reputation.Event]int{}
+	swarm.Report = func(source string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if event, ok := outcome(err); ok {
+			if events[source] == nil {
+				events[source] = map[reputation.Event]int{}
+			}
+			events[source][event]++
+		}
+	}
+	destination := filepath.Join(t.TempDir(), "file")
+	if err := swarm.Download(context.Background(), sources, pieces, destination); err != nil {
+		t.Fatalf("expected download to succeed, got %s", err)
+	}
+	got, err := os.ReadFile(destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("downloaded file contains corrupt pieces")
+	}
+	if served := swarm.Served(); served[hostOf(liar)] != 0 {
+		t.Errorf("expected no pieces to be accepted from the corrupt holder, got %v", served)
+	}
+	if events[hostOf(liar)][reputation.CorruptPiece] == 0 || events[hostOf(liar)][reputation.ServedPiece] != 0 {
+		t.Errorf("expected only corrupt pieces to be reported for the liar, got %v", events[hostOf(liar)])
+	}
+	if events[hostOf(good)][reputation.ServedPiece] == 0 {
+		t.Errorf("expected served pieces to be reported for the honest holder, got %v", events[hostOf(good)])
+	}
+}
+
+func TestSwarmFailsWhenEverySourceFails(t *testing.T) {
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusInternalServerError)
+	}))
+	defer broken.Close()
+
+	swarm := NewSwarm()
+	destination := filepath.Join(t.TempDir(), "file")
+	sources := []PieceSource{NewHTTPSource(hostOf(broken), "file", nil)}
+	err := swarm.Download(context.Background(), sources, SplitPieces(1024, 256), destination)
+	if !errors.Is(err, ErrAllSourcesLost) {
+		t.Fatalf("expected ErrAllSourcesLost, got %v", err)
+	}
+	if _, err := os.Stat(destination + partialSuffix); !os.IsNotExist(err) {
+		t.Errorf("expected partial file to be removed")
+	}
+}
+
+// memorySource serves pieces of data, failing those at the offsets in broken.
+type memorySource struct {
+	data    []byte
+	broken  map[int64]bool
+	mu      sync.Mutex
+	fetched []int64
+}
+
+func (source *memorySource) Name() string {
+	return "memory"
+}
+
+func (source *memorySource) FetchPiece(ctx context.Context, piece Piece) ([]byte, error) {
+	source.mu.Lock()
+	defer source.mu.Unlock()
+	source.fetched = append(source.fetched, piece.Offset)
+	if source.broken[piece.Offset] {
+		return nil, errors.New("broken")
+	}
+	return source.data[piece.Offset : piece.Offset+piece.Length], nil
+}
+
+func TestSwarmResumesAfterInterruption(t *testing.T) {
+	data := randomData(t, 16*1024)
+	pieces := SplitPieces(int64(len(data)), 4*1024)
+	for i := range pieces {
+		pieces[i].Hash = orcaHash.HashChunk(data[pieces[i].Offset : pieces[i].Offset+pieces[i].Length])
+	}
+	destination := filepath.Join(t.TempDir(), "file")
+
+	flaky := &memorySource{data: data, broken: map[int64]bool{8 * 1024: true, 12 * 1024: true}}
+	swarm := NewSwarm()
+	swarm.MaxFailures = 1
+	err := swarm.Download(context.Background(), []PieceSource{flaky}, pieces, destination)
+	if !errors.Is(err, ErrAllSourcesLost) {
+		t.Fatalf("expected ErrAllSourcesLost, got %v", err)
+	}
+	for _, path := range []string{destination + partialSuffix, destination + stateSuffix} {
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("expected %s to be kept for resuming, got %s", path, err)
+		}
+	}
+
+	healthy := &memorySource{data: data}
+	if err := NewSwarm().Download(context.Background(), []PieceSource{healthy}, pieces, destination); err != nil {
+		t.Fatalf("expected resumed download to succeed, got %s", err)
+	}
+	for _, offset := range healthy.fetched {
+		if offset < 8*1024 {
+			t.Errorf("expected piece at %d to be kept from the first attempt", offset)
+		}
+	}
+	got, err := os.ReadFile(destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("resumed file does not match original")
+	}
+	if _, err := os.Stat(destination + stateSuffix); !os.IsNotExist(err) {
+		t.Errorf("expected state file to be removed")
+	}
+}
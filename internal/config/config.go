@@ -0,0 +1,90 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"orca-peer/internal/announce"
+	"orca-peer/internal/approval"
+	"orca-peer/internal/client"
+	"orca-peer/internal/payment"
+	"orca-peer/internal/pricing"
+	"orca-peer/internal/reprovide"
+	"orca-peer/internal/reputation"
+	"orca-peer/internal/server"
+	"os"
+)
+
+const DefaultPath = "config/node.json"
+
+// Config holds the settings of this node that are read at startup.
+type Config struct {
+	Approval   approval.Config       `json:"approval"`
+	Payment    payment.Config        `json:"payment"`
+	Pricing    pricing.Config        `json:"pricing"`
+	Reprovide  reprovide.Config      `json:"reprovide"`
+	Network    server.NetworkConfig  `json:"network"`
+	Reputation reputation.Config     `json:"reputation"`
+	Download   client.DownloadConfig `json:"download"`
+	Market     client.MarketConfig   `json:"market"`
+	Ledger     client.LedgerConfig   `json:"ledger"`
+	Announce   announce.Config       `json:"announce"`
+	RPC        server.RPCConfig      `json:"rpc"`
+}
+
+func Default() *Config {
+	return &Config{
+		Approval: approval.Config{Mode: approval.ModeInteractive},
+		Payment: payment.Config{
+			Interval:   payment.DefaultInterval,
+			AbortAfter: int(payment.DefaultAbortAfter.Seconds()),
+		},
+		Pricing: pricing.Config{PricePerMB: 1},
+		Reprovide: reprovide.Config{
+			Interval: int(reprovide.DefaultInterval.Seconds()),
+			TTL:      int(reprovide.DefaultTTL.Seconds()),
+		},
+		Reputation: reputation.Config{
+			HalfLife: int(reputation.DefaultHalfLife.Seconds()),
+		},
+		Download: client.DownloadConfig{Strategy: client.StrategyCheapest},
+		Market: client.MarketConfig{
+			Timeout:          int(client.DefaultMarketTimeout.Seconds()),
+			Retries:          client.DefaultMarketRetries,
+			BreakerThreshold: client.DefaultBreakerThreshold,
+			BreakerCooldown:  int(client.DefaultBreakerCooldown.Seconds()),
+		},
+		Announce: announce.Config{
+			RetryInterval:    int(announce.DefaultRetryInterval.Seconds()),
+			MaxRetryInterval: int(announce.DefaultMaxRetryInterval.Seconds()),
+		},
+		Network: server.NetworkConfig{
+			// Copied so loading a config never writes into the defaults
+			ListenAddrs:    append([]string{}, server.DefaultListenAddrs...),
+			BootstrapPeers: append([]string{}, server.DefaultBootstrapPeers...),
+			Mode:           server.ModeServer,
+			PortMap:        true,
+			AutoRelay:      true,
+			HolePunching:   true,
+			LowWater:       server.DefaultLowWater,
+			HighWater:      server.DefaultHighWater,
+		},
+	}
+}
+
+// Load reads the config at path on top of the defaults. A missing file is
+// not an error, the defaults are used instead.
+func Load(path string) (*Config, error) {
+	config := Default()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
@@ -0,0 +1,599 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.25.3
+// source: internal/fileshare/file_share.proto
+
+package fileshare
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	FileShare_RecordFileRequestTransaction_FullMethodName = "/fileshare.FileShare/RecordFileRequestTransaction"
+	FileShare_GetBlock_FullMethodName                     = "/fileshare.FileShare/GetBlock"
+	FileShare_GetTransactions_FullMethodName              = "/fileshare.FileShare/GetTransactions"
+	FileShare_RegisterFile_FullMethodName                 = "/fileshare.FileShare/RegisterFile"
+	FileShare_CheckHolders_FullMethodName                 = "/fileshare.FileShare/CheckHolders"
+	FileShare_RequestAllAvailableFileNames_FullMethodName = "/fileshare.FileShare/RequestAllAvailableFileNames"
+	FileShare_NotifyFileStore_FullMethodName              = "/fileshare.FileShare/NotifyFileStore"
+	FileShare_NotifyFileUnstore_FullMethodName            = "/fileshare.FileShare/NotifyFileUnstore"
+	FileShare_SendFile_FullMethodName                     = "/fileshare.FileShare/SendFile"
+	FileShare_SendFileToStore_FullMethodName              = "/fileshare.FileShare/SendFileToStore"
+	FileShare_SendReceipt_FullMethodName                  = "/fileshare.FileShare/SendReceipt"
+)
+
+// FileShareClient is the client API for FileShare service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type FileShareClient interface {
+	// Producer/Consumer --> Blockchain
+	RecordFileRequestTransaction(ctx context.Context, in *FileRequestTransaction, opts ...grpc.CallOption) (*TransactionACKResponse, error)
+	// Anyone --> Blockchain
+	GetBlock(ctx context.Context, in *BlockRequest, opts ...grpc.CallOption) (*Block, error)
+	GetTransactions(ctx context.Context, in *TransactionsRequest, opts ...grpc.CallOption) (*TransactionsResponse, error)
+	// Consumer --> Market
+	// register a file on the market
+	RegisterFile(ctx context.Context, in *RegisterFileRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// check for holders of a file. returns a list of users
+	CheckHolders(ctx context.Context, in *CheckHoldersRequest, opts ...grpc.CallOption) (*HoldersResponse, error)
+	// Consumer --> Market
+	RequestAllAvailableFileNames(ctx context.Context, in *StorageIP, opts ...grpc.CallOption) (FileShare_RequestAllAvailableFileNamesClient, error)
+	// Producer --> Market
+	NotifyFileStore(ctx context.Context, in *FileDesc, opts ...grpc.CallOption) (*StorageACKResponse, error)
+	// Market --> Producer ?? Maybe Market --> Consumer instead
+	NotifyFileUnstore(ctx context.Context, in *FileDesc, opts ...grpc.CallOption) (*StorageACKResponse, error)
+	// Consumer --> Producer
+	// Streams a stored file. The first message carries its manifest, every
+	// message after it one chunk, in order.
+	SendFile(ctx context.Context, in *SendFileRequest, opts ...grpc.CallOption) (FileShare_SendFileClient, error)
+	// Consumer --> Producer, Market --> Producer
+	// Stores a file sent the same way SendFile sends one
+	SendFileToStore(ctx context.Context, opts ...grpc.CallOption) (FileShare_SendFileToStoreClient, error)
+	// Consumer --> Producer
+	// Pays for a running SendFile
+	SendReceipt(ctx context.Context, in *Receipt, opts ...grpc.CallOption) (*emptypb.Empty, error)
+}
+
+type fileShareClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewFileShareClient(cc grpc.ClientConnInterface) FileShareClient {
+	return &fileShareClient{cc}
+}
+
+func (c *fileShareClient) RecordFileRequestTransaction(ctx context.Context, in *FileRequestTransaction, opts ...grpc.CallOption) (*TransactionACKResponse, error) {
+	out := new(TransactionACKResponse)
+	err := c.cc.Invoke(ctx, FileShare_RecordFileRequestTransaction_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileShareClient) GetBlock(ctx context.Context, in *BlockRequest, opts ...grpc.CallOption) (*Block, error) {
+	out := new(Block)
+	err := c.cc.Invoke(ctx, FileShare_GetBlock_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileShareClient) GetTransactions(ctx context.Context, in *TransactionsRequest, opts ...grpc.CallOption) (*TransactionsResponse, error) {
+	out := new(TransactionsResponse)
+	err := c.cc.Invoke(ctx, FileShare_GetTransactions_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileShareClient) RegisterFile(ctx context.Context, in *RegisterFileRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, FileShare_RegisterFile_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileShareClient) CheckHolders(ctx context.Context, in *CheckHoldersRequest, opts ...grpc.CallOption) (*HoldersResponse, error) {
+	out := new(HoldersResponse)
+	err := c.cc.Invoke(ctx, FileShare_CheckHolders_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileShareClient) RequestAllAvailableFileNames(ctx context.Context, in *StorageIP, opts ...grpc.CallOption) (FileShare_RequestAllAvailableFileNamesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &FileShare_ServiceDesc.Streams[0], FileShare_RequestAllAvailableFileNames_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &fileShareRequestAllAvailableFileNamesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type FileShare_RequestAllAvailableFileNamesClient interface {
+	Recv() (*FileDesc, error)
+	grpc.ClientStream
+}
+
+type fileShareRequestAllAvailableFileNamesClient struct {
+	grpc.ClientStream
+}
+
+func (x *fileShareRequestAllAvailableFileNamesClient) Recv() (*FileDesc, error) {
+	m := new(FileDesc)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *fileShareClient) NotifyFileStore(ctx context.Context, in *FileDesc, opts ...grpc.CallOption) (*StorageACKResponse, error) {
+	out := new(StorageACKResponse)
+	err := c.cc.Invoke(ctx, FileShare_NotifyFileStore_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileShareClient) NotifyFileUnstore(ctx context.Context, in *FileDesc, opts ...grpc.CallOption) (*StorageACKResponse, error) {
+	out := new(StorageACKResponse)
+	err := c.cc.Invoke(ctx, FileShare_NotifyFileUnstore_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileShareClient) SendFile(ctx context.Context, in *SendFileRequest, opts ...grpc.CallOption) (FileShare_SendFileClient, error) {
+	stream, err := c.cc.NewStream(ctx, &FileShare_ServiceDesc.Streams[1], FileShare_SendFile_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &fileShareSendFileClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type FileShare_SendFileClient interface {
+	Recv() (*FileChunk, error)
+	grpc.ClientStream
+}
+
+type fileShareSendFileClient struct {
+	grpc.ClientStream
+}
+
+func (x *fileShareSendFileClient) Recv() (*FileChunk, error) {
+	m := new(FileChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *fileShareClient) SendFileToStore(ctx context.Context, opts ...grpc.CallOption) (FileShare_SendFileToStoreClient, error) {
+	stream, err := c.cc.NewStream(ctx, &FileShare_ServiceDesc.Streams[2], FileShare_SendFileToStore_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &fileShareSendFileToStoreClient{stream}
+	return x, nil
+}
+
+type FileShare_SendFileToStoreClient interface {
+	Send(*FileChunk) error
+	CloseAndRecv() (*StorageACKResponse, error)
+	grpc.ClientStream
+}
+
+type fileShareSendFileToStoreClient struct {
+	grpc.ClientStream
+}
+
+func (x *fileShareSendFileToStoreClient) Send(m *FileChunk) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *fileShareSendFileToStoreClient) CloseAndRecv() (*StorageACKResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(StorageACKResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *fileShareClient) SendReceipt(ctx context.Context, in *Receipt, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, FileShare_SendReceipt_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// FileShareServer is the server API for FileShare service.
+// All implementations must embed UnimplementedFileShareServer
+// for forward compatibility
+type FileShareServer interface {
+	// Producer/Consumer --> Blockchain
+	RecordFileRequestTransaction(context.Context, *FileRequestTransaction) (*TransactionACKResponse, error)
+	// Anyone --> Blockchain
+	GetBlock(context.Context, *BlockRequest) (*Block, error)
+	GetTransactions(context.Context, *TransactionsRequest) (*TransactionsResponse, error)
+	// Consumer --> Market
+	// register a file on the market
+	RegisterFile(context.Context, *RegisterFileRequest) (*emptypb.Empty, error)
+	// check for holders of a file. returns a list of users
+	CheckHolders(context.Context, *CheckHoldersRequest) (*HoldersResponse, error)
+	// Consumer --> Market
+	RequestAllAvailableFileNames(*StorageIP, FileShare_RequestAllAvailableFileNamesServer) error
+	// Producer --> Market
+	NotifyFileStore(context.Context, *FileDesc) (*StorageACKResponse, error)
+	// Market --> Producer ?? Maybe Market --> Consumer instead
+	NotifyFileUnstore(context.Context, *FileDesc) (*StorageACKResponse, error)
+	// Consumer --> Producer
+	// Streams a stored file. The first message carries its manifest, every
+	// message after it one chunk, in order.
+	SendFile(*SendFileRequest, FileShare_SendFileServer) error
+	// Consumer --> Producer, Market --> Producer
+	// Stores a file sent the same way SendFile sends one
+	SendFileToStore(FileShare_SendFileToStoreServer) error
+	// Consumer --> Producer
+	// Pays for a running SendFile
+	SendReceipt(context.Context, *Receipt) (*emptypb.Empty, error)
+	mustEmbedUnimplementedFileShareServer()
+}
+
+// UnimplementedFileShareServer must be embedded to have forward compatible implementations.
+type UnimplementedFileShareServer struct {
+}
+
+func (UnimplementedFileShareServer) RecordFileRequestTransaction(context.Context, *FileRequestTransaction) (*TransactionACKResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RecordFileRequestTransaction not implemented")
+}
+func (UnimplementedFileShareServer) GetBlock(context.Context, *BlockRequest) (*Block, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBlock not implemented")
+}
+func (UnimplementedFileShareServer) GetTransactions(context.Context, *TransactionsRequest) (*TransactionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTransactions not implemented")
+}
+func (UnimplementedFileShareServer) RegisterFile(context.Context, *RegisterFileRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterFile not implemented")
+}
+func (UnimplementedFileShareServer) CheckHolders(context.Context, *CheckHoldersRequest) (*HoldersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CheckHolders not implemented")
+}
+func (UnimplementedFileShareServer) RequestAllAvailableFileNames(*StorageIP, FileShare_RequestAllAvailableFileNamesServer) error {
+	return status.Errorf(codes.Unimplemented, "method RequestAllAvailableFileNames not implemented")
+}
+func (UnimplementedFileShareServer) NotifyFileStore(context.Context, *FileDesc) (*StorageACKResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method NotifyFileStore not implemented")
+}
+func (UnimplementedFileShareServer) NotifyFileUnstore(context.Context, *FileDesc) (*StorageACKResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method NotifyFileUnstore not implemented")
+}
+func (UnimplementedFileShareServer) SendFile(*SendFileRequest, FileShare_SendFileServer) error {
+	return status.Errorf(codes.Unimplemented, "method SendFile not implemented")
+}
+func (UnimplementedFileShareServer) SendFileToStore(FileShare_SendFileToStoreServer) error {
+	return status.Errorf(codes.Unimplemented, "method SendFileToStore not implemented")
+}
+func (UnimplementedFileShareServer) SendReceipt(context.Context, *Receipt) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendReceipt not implemented")
+}
+func (UnimplementedFileShareServer) mustEmbedUnimplementedFileShareServer() {}
+
+// UnsafeFileShareServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to FileShareServer will
+// result in compilation errors.
+type UnsafeFileShareServer interface {
+	mustEmbedUnimplementedFileShareServer()
+}
+
+func RegisterFileShareServer(s grpc.ServiceRegistrar, srv FileShareServer) {
+	s.RegisterService(&FileShare_ServiceDesc, srv)
+}
+
+func _FileShare_RecordFileRequestTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FileRequestTransaction)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileShareServer).RecordFileRequestTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileShare_RecordFileRequestTransaction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileShareServer).RecordFileRequestTransaction(ctx, req.(*FileRequestTransaction))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileShare_GetBlock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileShareServer).GetBlock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileShare_GetBlock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileShareServer).GetBlock(ctx, req.(*BlockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileShare_GetTransactions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransactionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileShareServer).GetTransactions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileShare_GetTransactions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileShareServer).GetTransactions(ctx, req.(*TransactionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileShare_RegisterFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterFileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileShareServer).RegisterFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileShare_RegisterFile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileShareServer).RegisterFile(ctx, req.(*RegisterFileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileShare_CheckHolders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckHoldersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileShareServer).CheckHolders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileShare_CheckHolders_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileShareServer).CheckHolders(ctx, req.(*CheckHoldersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileShare_RequestAllAvailableFileNames_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StorageIP)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FileShareServer).RequestAllAvailableFileNames(m, &fileShareRequestAllAvailableFileNamesServer{stream})
+}
+
+type FileShare_RequestAllAvailableFileNamesServer interface {
+	Send(*FileDesc) error
+	grpc.ServerStream
+}
+
+type fileShareRequestAllAvailableFileNamesServer struct {
+	grpc.ServerStream
+}
+
+func (x *fileShareRequestAllAvailableFileNamesServer) Send(m *FileDesc) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _FileShare_NotifyFileStore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FileDesc)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileShareServer).NotifyFileStore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileShare_NotifyFileStore_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileShareServer).NotifyFileStore(ctx, req.(*FileDesc))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileShare_NotifyFileUnstore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FileDesc)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileShareServer).NotifyFileUnstore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileShare_NotifyFileUnstore_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileShareServer).NotifyFileUnstore(ctx, req.(*FileDesc))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileShare_SendFile_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SendFileRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FileShareServer).SendFile(m, &fileShareSendFileServer{stream})
+}
+
+type FileShare_SendFileServer interface {
+	Send(*FileChunk) error
+	grpc.ServerStream
+}
+
+type fileShareSendFileServer struct {
+	grpc.ServerStream
+}
+
+func (x *fileShareSendFileServer) Send(m *FileChunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _FileShare_SendFileToStore_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(FileShareServer).SendFileToStore(&fileShareSendFileToStoreServer{stream})
+}
+
+type FileShare_SendFileToStoreServer interface {
+	SendAndClose(*StorageACKResponse) error
+	Recv() (*FileChunk, error)
+	grpc.ServerStream
+}
+
+type fileShareSendFileToStoreServer struct {
+	grpc.ServerStream
+}
+
+func (x *fileShareSendFileToStoreServer) SendAndClose(m *StorageACKResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *fileShareSendFileToStoreServer) Recv() (*FileChunk, error) {
+	m := new(FileChunk)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _FileShare_SendReceipt_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Receipt)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileShareServer).SendReceipt(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileShare_SendReceipt_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileShareServer).SendReceipt(ctx, req.(*Receipt))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// FileShare_ServiceDesc is the grpc.ServiceDesc for FileShare service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var FileShare_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "fileshare.FileShare",
+	HandlerType: (*FileShareServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "RecordFileRequestTransaction",
+			Handler:    _FileShare_RecordFileRequestTransaction_Handler,
+		},
+		{
+			MethodName: "GetBlock",
+			Handler:    _FileShare_GetBlock_Handler,
+		},
+		{
+			MethodName: "GetTransactions",
+			Handler:    _FileShare_GetTransactions_Handler,
+		},
+		{
+			MethodName: "RegisterFile",
+			Handler:    _FileShare_RegisterFile_Handler,
+		},
+		{
+			MethodName: "CheckHolders",
+			Handler:    _FileShare_CheckHolders_Handler,
+		},
+		{
+			MethodName: "NotifyFileStore",
+			Handler:    _FileShare_NotifyFileStore_Handler,
+		},
+		{
+			MethodName: "NotifyFileUnstore",
+			Handler:    _FileShare_NotifyFileUnstore_Handler,
+		},
+		{
+			MethodName: "SendReceipt",
+			Handler:    _FileShare_SendReceipt_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "RequestAllAvailableFileNames",
+			Handler:       _FileShare_RequestAllAvailableFileNames_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SendFile",
+			Handler:       _FileShare_SendFile_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SendFileToStore",
+			Handler:       _FileShare_SendFileToStore_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "internal/fileshare/file_share.proto",
+}
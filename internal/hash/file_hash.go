@@ -3,12 +3,15 @@ package hash
 import (
 	"bufio"
 	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 )
 
 type MemSize int
@@ -25,16 +28,21 @@ type NameMap struct {
 }
 
 /*
-File data read only
+File data read only. Files are stored as chunks named by their hash, next to
+a manifest for each file named by its CID.
 */
 
 type DataStore struct {
+	mu         sync.Mutex
 	path       string
 	buf        map[string][]byte
 	buf_size   int
 	buf_cap    int
 	drive_size int
 	drive_cap  int
+	// Called with ds.mu held whenever a file is added to or leaves the store
+	on_put    []func(manifest *Manifest)
+	on_remove []func(manifest *Manifest)
 }
 
 func NewNameStore(path string) *NameMap {
@@ -49,15 +57,19 @@ func NewNameStore(path string) *NameMap {
 }
 
 func NewDataStore(path string) *DataStore {
-	Assert(os.MkdirAll(path, 0755) == nil, "Failed to create namestore dir")
-	return &DataStore{
+	Assert(os.MkdirAll(filepath.Join(path, "chunks"), 0755) == nil, "Failed to create datastore chunk dir")
+	Assert(os.MkdirAll(filepath.Join(path, "manifests"), 0755) == nil, "Failed to create datastore manifest dir")
+	ds := &DataStore{
 		path:       path,
 		buf:        map[string][]byte{},
 		buf_size:   0,
-		buf_cap:    4 * Kilobyte,
+		buf_cap:    4 * Megabyte,
 		drive_size: 0,
 		drive_cap:  100 * Megabyte,
 	}
+	ds.measureDrive()
+	ds.migrateLegacyFiles()
+	return ds
 }
 
 func HashFile(address string) ([]byte, error) {
@@ -105,12 +117,166 @@ func (npm *NameMap) Recover() {
 	}
 }
 
-func (ds *DataStore) GetFile(hash_val string) ([]byte, error) {
+func (ds *DataStore) chunkPath(hash_val string) string {
+	return filepath.Join(ds.path, "chunks", hash_val)
+}
+
+func (ds *DataStore) manifestPath(cid string) string {
+	return filepath.Join(ds.path, "manifests", cid)
+}
+
+// IsValidHash reports whether hash_val looks like a hex encoded SHA-256, which
+// is the only kind of name that may be turned into a path inside the store.
+func IsValidHash(hash_val string) bool {
+	decoded, err := hex.DecodeString(hash_val)
+	return err == nil && len(decoded) == sha256.Size
+}
+
+// GetFile puts the chunks of the file with the given CID back together.
+func (ds *DataStore) GetFile(cid string) ([]byte, error) {
+	manifest, err := ds.GetManifest(cid)
+	if err != nil {
+		return []byte{}, err
+	}
+	data := make([]byte, 0, manifest.Size)
+	for _, chunk_hash := range manifest.Chunks {
+		chunk, err := ds.GetChunk(chunk_hash)
+		if err != nil {
+			return []byte{}, err
+		}
+		data = append(data, chunk...)
+	}
+	return data, nil
+}
+
+// PutFile splits data into chunks, stores every chunk that is not already in
+// the store and returns the CID of the file.
+func (ds *DataStore) PutFile(data []byte) (string, error) {
+	manifest, chunks, err := BuildManifest(data)
+	if err != nil {
+		return "", err
+	}
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	new_size := 0
+	for i, chunk_hash := range manifest.Chunks {
+		if !ds.hasChunk(chunk_hash) {
+			new_size += len(chunks[i])
+		}
+	}
+	if err := ds.makeRoom(new_size); err != nil {
+		return "", err
+	}
+	for i, chunk_hash := range manifest.Chunks {
+		if err := ds.DrivePut(chunk_hash, chunks[i]); err != nil {
+			return "", err
+		}
+		ds.BufferPut(chunk_hash, chunks[i])
+	}
+	added, err := ds.writeManifest(manifest)
+	if err != nil {
+		return "", err
+	}
+	if added {
+		for _, callback := range ds.on_put {
+			callback(manifest)
+		}
+	}
+	return manifest.Root, nil
+}
+
+func (ds *DataStore) GetManifest(cid string) (*Manifest, error) {
+	if !IsValidHash(cid) {
+		return nil, os.ErrNotExist
+	}
+	data, err := os.ReadFile(ds.manifestPath(cid))
+	if err != nil {
+		return nil, err
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// PutManifest records a manifest for a file whose chunks are added
+// separately. Once all of them are in, the file counts as added.
+func (ds *DataStore) PutManifest(manifest *Manifest) error {
+	if err := manifest.Verify(); err != nil {
+		return err
+	}
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	added, err := ds.writeManifest(manifest)
+	if err != nil || !added {
+		return err
+	}
+	for _, chunk_hash := range manifest.Chunks {
+		if !ds.hasChunk(chunk_hash) {
+			return nil
+		}
+	}
+	for _, callback := range ds.on_put {
+		callback(manifest)
+	}
+	return nil
+}
+
+// writeManifest stores manifest and reports whether it was new.
+func (ds *DataStore) writeManifest(manifest *Manifest) (bool, error) {
+	if _, err := os.Stat(ds.manifestPath(manifest.Root)); err == nil {
+		return false, nil
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return false, err
+	}
+	if err := os.WriteFile(ds.manifestPath(manifest.Root), data, 0444); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ListFiles returns the manifest of every file in the store.
+func (ds *DataStore) ListFiles() ([]*Manifest, error) {
+	entries, err := os.ReadDir(filepath.Join(ds.path, "manifests"))
+	if err != nil {
+		return nil, err
+	}
+	manifests := make([]*Manifest, 0, len(entries))
+	for _, entry := range entries {
+		manifest, err := ds.GetManifest(entry.Name())
+		if err != nil {
+			continue
+		}
+		manifests = append(manifests, manifest)
+	}
+	return manifests, nil
+}
+
+// OpenFile returns a reader over the chunks of a stored file.
+func (ds *DataStore) OpenFile(cid string) (io.ReadSeekCloser, *Manifest, error) {
+	manifest, err := ds.GetManifest(cid)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &chunkReader{store: ds, manifest: manifest}, manifest, nil
+}
+
+func (ds *DataStore) GetChunk(hash_val string) ([]byte, error) {
+	if !IsValidHash(hash_val) {
+		return []byte{}, os.ErrNotExist
+	}
+	ds.mu.Lock()
 	if data, ok := ds.buf[hash_val]; ok {
+		ds.mu.Unlock()
 		return data, nil
 	}
+	ds.mu.Unlock()
 
-	file, err := ds.OpenFile(hash_val)
+	file, err := os.Open(ds.chunkPath(hash_val))
 	if err != nil {
 		return []byte{}, err
 	}
@@ -121,73 +287,244 @@ func (ds *DataStore) GetFile(hash_val string) ([]byte, error) {
 		return []byte{}, err
 	}
 
+	ds.mu.Lock()
 	ds.BufferPut(hash_val, data)
+	ds.mu.Unlock()
 
 	return data, nil
 }
 
-func (ds *DataStore) PutFile(data []byte) (string, error) {
-	checksum := sha256.Sum256(data)
-	hash_val := fmt.Sprintf("%x", checksum)
+// PutChunk stores a single chunk, which is how files arrive over the network.
+func (ds *DataStore) PutChunk(data []byte) (string, error) {
+	hash_val := HashChunk(data)
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if !ds.hasChunk(hash_val) {
+		if err := ds.makeRoom(len(data)); err != nil {
+			return "", err
+		}
+	}
 	if err := ds.DrivePut(hash_val, data); err != nil {
 		return "", err
 	}
-
 	ds.BufferPut(hash_val, data)
-
 	return hash_val, nil
 }
 
+func (ds *DataStore) HasChunk(hash_val string) bool {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return ds.hasChunk(hash_val)
+}
+
+func (ds *DataStore) hasChunk(hash_val string) bool {
+	_, err := os.Stat(ds.chunkPath(hash_val))
+	return err == nil
+}
+
+// Callers must hold ds.mu
 func (ds *DataStore) BufferPut(hash_val string, data []byte) {
-	if len(data)+ds.buf_size > ds.buf_cap {
+	if _, ok := ds.buf[hash_val]; ok {
+		return
+	}
+	for len(data)+ds.buf_size > ds.buf_cap && len(ds.buf) > 0 {
 		ds.EvictBuffer()
 	}
+	if len(data) > ds.buf_cap {
+		return
+	}
 	ds.buf[hash_val] = data
+	ds.buf_size += len(data)
 }
 
 func (ds *DataStore) EvictBuffer() {
 	largest_file_hash := ""
 	largest_file_size := 0
 	for hash_val, data := range ds.buf {
-		if len(data) > largest_file_size {
+		if len(data) >= largest_file_size {
 			largest_file_hash = hash_val
+			largest_file_size = len(data)
 		}
 	}
 	if largest_file_hash != "" {
 		delete(ds.buf, largest_file_hash)
+		ds.buf_size -= largest_file_size
 	}
 }
 
+// DrivePut writes a chunk unless an identical chunk is already stored, which
+// is what deduplicates chunks that are shared between files.
 func (ds *DataStore) DrivePut(hash_val string, data []byte) error {
-	if len(data)+ds.drive_size > ds.drive_cap {
-		fmt.Printf("Drive evict %d %d\n", ds.drive_size, len(data))
-		ds.DriveEvict()
+	if ds.hasChunk(hash_val) {
+		return nil
 	}
-	return ds.WriteFile(hash_val, data)
+	if err := ds.WriteFile(hash_val, data); err != nil {
+		return err
+	}
+	ds.drive_size += len(data)
+	return nil
 }
 
-func (ds *DataStore) DriveEvict() {
-	entries, err := os.ReadDir(ds.path)
-	Assert(err == nil, "Todo handle directory read failure during drive eviction")
+// makeRoom evicts files until size more bytes fit on the drive.
+func (ds *DataStore) makeRoom(size int) error {
+	for size+ds.drive_size > ds.drive_cap {
+		fmt.Printf("Drive evict %d %d\n", ds.drive_size, size)
+		evicted, err := ds.DriveEvict()
+		if err != nil {
+			return fmt.Errorf("making room: %w", err)
+		}
+		if !evicted {
+			return nil
+		}
+	}
+	return nil
+}
 
+// DriveEvict removes the largest file along with every chunk that no other
+// file still uses. It returns false when there was nothing left to evict.
+func (ds *DataStore) DriveEvict() (bool, error) {
+	manifests, err := ds.manifestsByRoot()
+	if err != nil {
+		return false, err
+	}
 	largest_file_hash := ""
-	largest_file_size := 0
-	for _, entry := range entries {
-		info, err := entry.Info()
-		Assert(err == nil, "Todo handle stat on dir entry fail")
-		if info.Size() > int64(largest_file_size) {
-			largest_file_hash = info.Name()
+	largest_file_size := int64(-1)
+	for _, manifest := range manifests {
+		if manifest.Size > largest_file_size {
+			largest_file_hash = manifest.Root
+			largest_file_size = manifest.Size
 		}
 	}
-	if largest_file_hash != "" {
-		Assert(os.Remove(filepath.Join(ds.path, largest_file_hash)) == nil, "Todo remove file failed")
+	if largest_file_hash == "" {
+		return false, nil
 	}
+	if err := ds.removeFile(largest_file_hash, manifests); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
-func (ds *DataStore) OpenFile(hash_val string) (*os.File, error) {
-	return os.Open(filepath.Join(ds.path, hash_val))
+// RemoveFile deletes a file from the store, keeping chunks other files share.
+func (ds *DataStore) RemoveFile(cid string) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	manifests, err := ds.manifestsByRoot()
+	if err != nil {
+		return err
+	}
+	if _, ok := manifests[cid]; !ok {
+		return os.ErrNotExist
+	}
+	return ds.removeFile(cid, manifests)
+}
+
+// OnPut registers a function that is told the manifest of every file PutFile
+// adds to the store, or PutManifest adds once all its chunks are stored. It
+// is called while the store is locked, so it must not use the store.
+func (ds *DataStore) OnPut(callback func(manifest *Manifest)) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.on_put = append(ds.on_put, callback)
+}
+
+// OnRemove registers a function that is told the manifest of every file that
+// is evicted or removed. Like OnPut, it must not use the store.
+func (ds *DataStore) OnRemove(callback func(manifest *Manifest)) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.on_remove = append(ds.on_remove, callback)
+}
+
+func (ds *DataStore) manifestsByRoot() (map[string]*Manifest, error) {
+	entries, err := os.ReadDir(filepath.Join(ds.path, "manifests"))
+	if err != nil {
+		return nil, err
+	}
+
+	manifests := map[string]*Manifest{}
+	for _, entry := range entries {
+		manifest, err := ds.GetManifest(entry.Name())
+		if err != nil {
+			continue
+		}
+		manifests[manifest.Root] = manifest
+	}
+	return manifests, nil
+}
+
+// removeFile deletes a manifest and the chunks that only it referenced. A
+// chunk that can not be deleted is left behind, but the file counts as removed
+// once its manifest is gone. Callers must hold ds.mu
+func (ds *DataStore) removeFile(cid string, manifests map[string]*Manifest) error {
+	if err := os.Remove(ds.manifestPath(cid)); err != nil {
+		return err
+	}
+	evicted := manifests[cid]
+	delete(manifests, cid)
+	in_use := map[string]bool{}
+	for _, manifest := range manifests {
+		for _, chunk_hash := range manifest.Chunks {
+			in_use[chunk_hash] = true
+		}
+	}
+	for _, chunk_hash := range evicted.Chunks {
+		if in_use[chunk_hash] {
+			continue
+		}
+		info, err := os.Stat(ds.chunkPath(chunk_hash))
+		if err != nil {
+			continue
+		}
+		if os.Remove(ds.chunkPath(chunk_hash)) == nil {
+			ds.drive_size -= int(info.Size())
+			if data, ok := ds.buf[chunk_hash]; ok {
+				delete(ds.buf, chunk_hash)
+				ds.buf_size -= len(data)
+			}
+		}
+	}
+	for _, callback := range ds.on_remove {
+		callback(evicted)
+	}
+	return nil
 }
 
 func (ds *DataStore) WriteFile(hash_val string, data []byte) error {
-	return os.WriteFile(filepath.Join(ds.path, hash_val), data, 0444)
+	return os.WriteFile(ds.chunkPath(hash_val), data, 0444)
+}
+
+// migrateLegacyFiles moves whole files stored by older versions, which sit
+// directly in the store directory, into chunks and manifests.
+func (ds *DataStore) migrateLegacyFiles() {
+	entries, err := os.ReadDir(ds.path)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		legacy_path := filepath.Join(ds.path, entry.Name())
+		data, err := os.ReadFile(legacy_path)
+		if err != nil {
+			continue
+		}
+		if _, err := ds.PutFile(data); err != nil {
+			fmt.Println("Error migrating stored file:", err)
+			continue
+		}
+		os.Remove(legacy_path)
+	}
+}
+
+func (ds *DataStore) measureDrive() {
+	entries, err := os.ReadDir(filepath.Join(ds.path, "chunks"))
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if info, err := entry.Info(); err == nil {
+			ds.drive_size += int(info.Size())
+		}
+	}
 }
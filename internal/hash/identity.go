@@ -0,0 +1,31 @@
+package hash
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// HostKey turns the RSA key this node signs transactions with into the key of
+// its libp2p host, so the peer ID stays the same across restarts and belongs
+// to the same key as the transactions and DHT records of this node.
+func HostKey(privateKey *rsa.PrivateKey) (crypto.PrivKey, error) {
+	hostKey, _, err := crypto.KeyPairFromStdKey(privateKey)
+	return hostKey, err
+}
+
+// PeerIDFromKey returns the peer ID that belongs to an RSA public key, which
+// lets anyone holding a node's public key check which peer it is.
+func PeerIDFromKey(publicKey *rsa.PublicKey) (peer.ID, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	hostKey, err := crypto.UnmarshalRsaPublicKey(der)
+	if err != nil {
+		return "", err
+	}
+	return peer.IDFromPublicKey(hostKey)
+}
@@ -0,0 +1,53 @@
+package hash
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+func TestPeerIDStableAcrossReload(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostKey, err := HostKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostID, err := peer.IDFromPrivateKey(hostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Reloading the key from its PEM files gives the same peer
+	reloaded, err := ParseRsaPrivateKeyFromPemStr(string(ExportRsaPrivateKeyAsPemStr(privateKey)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloadedKey, err := HostKey(reloaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloadedID, _ := peer.IDFromPrivateKey(reloadedKey)
+	if reloadedID != hostID {
+		t.Errorf("expected peer ID %s after reload, got %s", hostID, reloadedID)
+	}
+
+	// Anyone holding only the public key can tell which peer it is
+	publicID, err := PeerIDFromKey(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if publicID != hostID {
+		t.Errorf("expected peer ID %s from the public key, got %s", hostID, publicID)
+	}
+
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+	otherID, _ := PeerIDFromKey(&other.PublicKey)
+	if otherID == hostID {
+		t.Error("expected different keys to give different peer IDs")
+	}
+}
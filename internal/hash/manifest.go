@@ -0,0 +1,221 @@
+package hash
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Files are split into chunks of this size, the last chunk may be shorter
+const ChunkSize = 256 * 1024
+
+var ErrInvalidManifest = errors.New("manifest does not match its root")
+
+/*
+A manifest lists the hashes of every chunk of a file in order. The chunk
+hashes are the leaves of a Merkle tree whose root is the CID of the file, so a
+manifest can be checked against the CID before any chunk is requested and each
+chunk can be checked against the manifest as soon as it arrives.
+*/
+type Manifest struct {
+	Root      string   `json:"root"`
+	Size      int64    `json:"size"`
+	ChunkSize int64    `json:"chunk_size"`
+	Chunks    []string `json:"chunks"`
+}
+
+// HashChunk is the leaf hash of a chunk, the SHA-256 of the chunk prefixed
+// with 0. Inner nodes are prefixed with 1, so a chunk can never be passed off
+// as an inner node or the other way around.
+func HashChunk(data []byte) string {
+	leaf := sha256.New()
+	leaf.Write([]byte{0})
+	leaf.Write(data)
+	return hex.EncodeToString(leaf.Sum(nil))
+}
+
+// MerkleRoot combines chunk hashes pairwise until one hash is left. An odd
+// hash at the end of a level is carried up unchanged, so the root of a file
+// that fits in one chunk is the hash of that chunk.
+func MerkleRoot(chunkHashes []string) (string, error) {
+	if len(chunkHashes) == 0 {
+		return HashChunk(nil), nil
+	}
+	level := make([][]byte, len(chunkHashes))
+	for i, chunkHash := range chunkHashes {
+		decoded, err := hex.DecodeString(chunkHash)
+		if err != nil || len(decoded) != sha256.Size {
+			return "", fmt.Errorf("invalid chunk hash %q", chunkHash)
+		}
+		level[i] = decoded
+	}
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			node := sha256.New()
+			node.Write([]byte{1})
+			node.Write(level[i])
+			node.Write(level[i+1])
+			next = append(next, node.Sum(nil))
+		}
+		level = next
+	}
+	return hex.EncodeToString(level[0]), nil
+}
+
+// BuildManifest splits data into chunks and returns the manifest along with
+// the chunks in the same order as the manifest lists them.
+func BuildManifest(data []byte) (*Manifest, [][]byte, error) {
+	manifest := &Manifest{Size: int64(len(data)), ChunkSize: ChunkSize, Chunks: []string{}}
+	chunks := [][]byte{}
+	for offset := 0; offset < len(data); offset += ChunkSize {
+		end := offset + ChunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+		chunks = append(chunks, data[offset:end])
+		manifest.Chunks = append(manifest.Chunks, HashChunk(data[offset:end]))
+	}
+	root, err := MerkleRoot(manifest.Chunks)
+	if err != nil {
+		return nil, nil, err
+	}
+	manifest.Root = root
+	return manifest, chunks, nil
+}
+
+// ComputeCID reads everything from reader and returns the root its manifest
+// would have, without keeping the whole file in memory.
+func ComputeCID(reader io.Reader) (string, error) {
+	chunkHashes := []string{}
+	buffer := make([]byte, ChunkSize)
+	for {
+		n, err := io.ReadFull(reader, buffer)
+		if n > 0 {
+			chunkHashes = append(chunkHashes, HashChunk(buffer[:n]))
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return MerkleRoot(chunkHashes)
+}
+
+// Verify checks that the chunk list adds up to the root and the size. Files
+// are only ever split into chunks of ChunkSize, so no other size is accepted.
+func (manifest *Manifest) Verify() error {
+	if manifest.ChunkSize != ChunkSize {
+		return ErrInvalidManifest
+	}
+	expectedChunks := (manifest.Size + manifest.ChunkSize - 1) / manifest.ChunkSize
+	if int64(len(manifest.Chunks)) != expectedChunks {
+		return ErrInvalidManifest
+	}
+	root, err := MerkleRoot(manifest.Chunks)
+	if err != nil || root != manifest.Root {
+		return ErrInvalidManifest
+	}
+	return nil
+}
+
+// ChunkOffset returns where chunk i starts and how long it is.
+func (manifest *Manifest) ChunkOffset(i int) (int64, int64) {
+	offset := int64(i) * manifest.ChunkSize
+	length := manifest.ChunkSize
+	if offset+length > manifest.Size {
+		length = manifest.Size - offset
+	}
+	return offset, length
+}
+
+// VerifyChunk checks that data is chunk i of the file described by manifest.
+func (manifest *Manifest) VerifyChunk(i int, data []byte) error {
+	if i < 0 || i >= len(manifest.Chunks) {
+		return fmt.Errorf("chunk %d out of range", i)
+	}
+	_, length := manifest.ChunkOffset(i)
+	if int64(len(data)) != length || HashChunk(data) != manifest.Chunks[i] {
+		return fmt.Errorf("chunk %d does not match manifest", i)
+	}
+	return nil
+}
+
+// VerifySentChunk checks chunk i as it arrived from a peer, along with the
+// hash the peer sent for it.
+func (manifest *Manifest) VerifySentChunk(i int, chunkHash string, data []byte) error {
+	if i >= 0 && i < len(manifest.Chunks) && chunkHash != manifest.Chunks[i] {
+		return fmt.Errorf("chunk %d was sent with hash %s instead of %s", i, chunkHash, manifest.Chunks[i])
+	}
+	return manifest.VerifyChunk(i, data)
+}
+
+func (manifest *Manifest) HasChunk(chunkHash string) bool {
+	for _, c := range manifest.Chunks {
+		if c == chunkHash {
+			return true
+		}
+	}
+	return false
+}
+
+// chunkReader presents the chunks of a stored file as one seekable file.
+type chunkReader struct {
+	store    *DataStore
+	manifest *Manifest
+	offset   int64
+	current  []byte
+	index    int
+}
+
+func (reader *chunkReader) Read(p []byte) (int, error) {
+	if reader.offset >= reader.manifest.Size {
+		return 0, io.EOF
+	}
+	index := int(reader.offset / reader.manifest.ChunkSize)
+	if reader.current == nil || reader.index != index {
+		data, err := reader.store.GetChunk(reader.manifest.Chunks[index])
+		if err != nil {
+			return 0, err
+		}
+		reader.current = data
+		reader.index = index
+	}
+	start, _ := reader.manifest.ChunkOffset(index)
+	n := copy(p, reader.current[reader.offset-start:])
+	reader.offset += int64(n)
+	return n, nil
+}
+
+func (reader *chunkReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += reader.offset
+	case io.SeekEnd:
+		offset += reader.manifest.Size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	reader.offset = offset
+	return offset, nil
+}
+
+func (reader *chunkReader) Close() error {
+	return nil
+}
+
+func (manifest *Manifest) String() string {
+	return fmt.Sprintf("%s (%d bytes, %d chunks)", manifest.Root, manifest.Size, len(manifest.Chunks))
+}
@@ -0,0 +1,232 @@
+package hash
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func randomBytes(t *testing.T, size int) []byte {
+	data := make([]byte, size)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestMerkleRoot(t *testing.T) {
+	small := []byte("hello")
+	manifest, _, err := BuildManifest(small)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(append([]byte{0}, small...))
+	if manifest.Root != hex.EncodeToString(sum[:]) {
+		t.Errorf("expected the CID of a single chunk file to be its leaf hash")
+	}
+
+	data := randomBytes(t, 3*ChunkSize+1)
+	manifest, chunks, err := BuildManifest(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 4 || len(manifest.Chunks) != 4 {
+		t.Fatalf("expected 4 chunks, got %d", len(chunks))
+	}
+	cid, err := ComputeCID(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cid != manifest.Root {
+		t.Errorf("ComputeCID = %s, manifest root = %s", cid, manifest.Root)
+	}
+	if err := manifest.Verify(); err != nil {
+		t.Errorf("expected manifest to verify, got %s", err)
+	}
+	if err := manifest.VerifyChunk(3, chunks[3]); err != nil {
+		t.Error(err)
+	}
+	if err := manifest.VerifyChunk(2, chunks[3]); err == nil {
+		t.Error("expected chunk in the wrong position to be rejected")
+	}
+}
+
+func TestManifestVerifyRejectsTampering(t *testing.T) {
+	manifest, _, err := BuildManifest(randomBytes(t, 2*ChunkSize+5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest.Chunks[1], manifest.Chunks[0] = manifest.Chunks[0], manifest.Chunks[1]
+	if manifest.Verify() == nil {
+		t.Error("expected reordered chunks to be rejected")
+	}
+	manifest.Chunks[1], manifest.Chunks[0] = manifest.Chunks[0], manifest.Chunks[1]
+	manifest.Size += ChunkSize
+	if manifest.Verify() == nil {
+		t.Error("expected wrong size to be rejected")
+	}
+}
+
+func TestManifestVerifyRejectsForgedChunk(t *testing.T) {
+	manifest, _, err := BuildManifest(randomBytes(t, ChunkSize+5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A chunk that is the inner node of the real root must not hash to it
+	forged := []byte{1}
+	for _, chunkHash := range manifest.Chunks {
+		decoded, err := hex.DecodeString(chunkHash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		forged = append(forged, decoded...)
+	}
+	tests := []struct {
+		name      string
+		chunkSize int64
+	}{
+		{"chunk size of the forged chunk", int64(len(forged))},
+		{"regular chunk size", ChunkSize},
+	}
+	for _, test := range tests {
+		fake := &Manifest{Root: manifest.Root, Size: int64(len(forged)), ChunkSize: test.chunkSize, Chunks: []string{HashChunk(forged)}}
+		if fake.Verify() == nil {
+			t.Errorf("%s: expected a forged manifest to be rejected", test.name)
+		}
+	}
+}
+
+func TestDataStoreDeduplicatesChunks(t *testing.T) {
+	dir := t.TempDir()
+	ds := NewDataStore(dir)
+	shared := randomBytes(t, 2*ChunkSize)
+	first := append(append([]byte{}, shared...), randomBytes(t, 100)...)
+	second := append(append([]byte{}, shared...), randomBytes(t, 200)...)
+
+	firstCID, err := ds.PutFile(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secondCID, err := ds.PutFile(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "chunks"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 {
+		t.Errorf("expected 4 distinct chunks on disk, got %d", len(entries))
+	}
+
+	got, err := ds.GetFile(secondCID)
+	if err != nil || !bytes.Equal(got, second) {
+		t.Fatalf("expected second file back, got %v", err)
+	}
+	reader, manifest, err := ds.OpenFile(firstCID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reader.Seek(ChunkSize-10, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	tail, err := io.ReadAll(reader)
+	if err != nil || !bytes.Equal(tail, first[ChunkSize-10:]) {
+		t.Errorf("reading across chunks of %s returned the wrong data", manifest.String())
+	}
+}
+
+func TestDriveEvictKeepsSharedChunks(t *testing.T) {
+	ds := NewDataStore(t.TempDir())
+	shared := randomBytes(t, ChunkSize)
+	small := append(append([]byte{}, shared...), 1)
+	large := append(append([]byte{}, shared...), randomBytes(t, 2*ChunkSize)...)
+	smallCID, _ := ds.PutFile(small)
+	largeCID, _ := ds.PutFile(large)
+
+	if evicted, err := ds.DriveEvict(); !evicted || err != nil {
+		t.Fatalf("expected a file to be evicted, got %v", err)
+	}
+	if _, err := ds.GetManifest(largeCID); err == nil {
+		t.Error("expected the largest file to be evicted")
+	}
+	got, err := ds.GetFile(smallCID)
+	if err != nil || !bytes.Equal(got, small) {
+		t.Errorf("expected the remaining file to keep its shared chunk, got %v", err)
+	}
+}
+
+func TestStoreEvents(t *testing.T) {
+	ds := NewDataStore(t.TempDir())
+	put := []string{}
+	removed := []string{}
+	ds.OnPut(func(manifest *Manifest) { put = append(put, manifest.Root) })
+	ds.OnRemove(func(manifest *Manifest) { removed = append(removed, manifest.Root) })
+
+	cid, _ := ds.PutFile(randomBytes(t, 10))
+	// Storing the same file again adds nothing
+	ds.PutFile(mustGetFile(t, ds, cid))
+
+	// A manifest only adds its file once all of its chunks are stored
+	manifest, chunks, _ := BuildManifest(randomBytes(t, ChunkSize+10))
+	ds.PutChunk(chunks[0])
+	if err := ds.PutManifest(manifest); err != nil {
+		t.Fatal(err)
+	}
+	ds.RemoveFile(manifest.Root)
+	ds.PutChunk(chunks[0])
+	ds.PutChunk(chunks[1])
+	if err := ds.PutManifest(manifest); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(put) != 2 || put[0] != cid || put[1] != manifest.Root {
+		t.Errorf("expected puts of %s and %s, got %v", cid, manifest.Root, put)
+	}
+	if len(removed) != 1 || removed[0] != manifest.Root {
+		t.Errorf("expected %s to be removed, got %v", manifest.Root, removed)
+	}
+}
+
+func TestRemoveFileReportsErrors(t *testing.T) {
+	dir := t.TempDir()
+	ds := NewDataStore(dir)
+	removed := 0
+	ds.OnRemove(func(manifest *Manifest) { removed++ })
+	cid, err := ds.PutFile(randomBytes(t, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The manifests can no longer be listed
+	manifests := filepath.Join(dir, "manifests")
+	if err := os.Rename(manifests, manifests+".moved"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(manifests, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ds.RemoveFile(cid); err == nil || errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the failure to be returned, got %v", err)
+	}
+	if evicted, err := ds.DriveEvict(); evicted || err == nil {
+		t.Errorf("expected eviction to fail, got %v and %v", evicted, err)
+	}
+	if removed != 0 {
+		t.Errorf("expected no removal to be announced")
+	}
+}
+
+func mustGetFile(t *testing.T, ds *DataStore, cid string) []byte {
+	data, err := ds.GetFile(cid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
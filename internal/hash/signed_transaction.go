@@ -0,0 +1,180 @@
+package hash
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// How long a transaction can be handed in after it was signed
+const DefaultTransactionLifetime = 5 * time.Minute
+
+// Clocks of two peers may disagree by this much
+const clockSkew = 30 * time.Second
+
+var (
+	ErrBadTransactionSignature = errors.New("transaction signature is invalid")
+	ErrWrongPayer              = errors.New("transaction was not signed by its payer")
+	ErrWrongPayee              = errors.New("transaction is not addressed to this node")
+	ErrTransactionExpired      = errors.New("transaction has expired")
+	ErrTransactionNotYetValid  = errors.New("transaction is dated in the future")
+	ErrReusedNonce             = errors.New("transaction nonce was already used")
+	ErrInvalidAmount           = errors.New("transaction amount must be positive")
+)
+
+/*
+A transaction moves Amount from the payer to the payee, optionally for a file.
+Both parties are named by the fingerprint of their public key. The nonce makes
+every transaction unique so a payee can refuse to count the same one twice,
+and the expiry bounds how long the payee has to remember nonces.
+*/
+type Transaction struct {
+	Uuid      string  `json:"uuid"`
+	Payer     string  `json:"payer"`
+	Payee     string  `json:"payee"`
+	Amount    float64 `json:"amount"`
+	FileHash  string  `json:"file_hash"`
+	Nonce     string  `json:"nonce"`
+	Timestamp string  `json:"timestamp"`
+	Expires   string  `json:"expires"`
+}
+
+// SignedTransaction is what is sent over the wire. The signature is an
+// RSA-PSS signature over the exact transaction bytes.
+type SignedTransaction struct {
+	Transaction []byte `json:"transaction"`
+	Signature   []byte `json:"signature"`
+	PublicKey   string `json:"public_key"`
+}
+
+// Fingerprint is the hex SHA-256 of the DER encoding of a public key.
+func Fingerprint(publicKey *rsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	checksum := sha256.Sum256(der)
+	return hex.EncodeToString(checksum[:]), nil
+}
+
+func NewTransaction(payer *rsa.PublicKey, payee string, amount float64, fileHash string, lifetime time.Duration) (*Transaction, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	payerFingerprint, err := Fingerprint(payer)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	return &Transaction{
+		Uuid:      uuid.NewString(),
+		Payer:     payerFingerprint,
+		Payee:     payee,
+		Amount:    amount,
+		FileHash:  fileHash,
+		Nonce:     hex.EncodeToString(nonce),
+		Timestamp: now.Format(time.RFC3339),
+		Expires:   now.Add(lifetime).Format(time.RFC3339),
+	}, nil
+}
+
+func SignTransaction(transaction *Transaction, privateKey *rsa.PrivateKey) (*SignedTransaction, error) {
+	data, err := json.Marshal(transaction)
+	if err != nil {
+		return nil, err
+	}
+	hashed := sha256.Sum256(data)
+	signature, err := rsa.SignPSS(rand.Reader, privateKey, crypto.SHA256, hashed[:], nil)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := ExportRsaPublicKeyAsPemStr(&privateKey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &SignedTransaction{Transaction: data, Signature: signature, PublicKey: string(publicKey)}, nil
+}
+
+// Verifier checks transactions handed to a payee and remembers the nonces of
+// the ones it accepted until they expire.
+type Verifier struct {
+	// Fingerprint of the payee, transactions for anyone else are rejected
+	Payee string
+
+	mu     sync.Mutex
+	nonces map[string]time.Time
+	now    func() time.Time
+}
+
+func NewVerifier(payee string) *Verifier {
+	return &Verifier{Payee: payee, nonces: make(map[string]time.Time), now: time.Now}
+}
+
+func (verifier *Verifier) Verify(signed *SignedTransaction) (*Transaction, error) {
+	publicKey, err := ParseRsaPublicKeyFromPemStr(signed.PublicKey)
+	if err != nil {
+		return nil, ErrBadTransactionSignature
+	}
+	hashed := sha256.Sum256(signed.Transaction)
+	if err := rsa.VerifyPSS(publicKey, crypto.SHA256, hashed[:], signed.Signature, nil); err != nil {
+		return nil, ErrBadTransactionSignature
+	}
+	var transaction Transaction
+	if err := json.Unmarshal(signed.Transaction, &transaction); err != nil {
+		return nil, err
+	}
+	fingerprint, err := Fingerprint(publicKey)
+	if err != nil || fingerprint != transaction.Payer {
+		return nil, ErrWrongPayer
+	}
+	if verifier.Payee != "" && transaction.Payee != verifier.Payee {
+		return nil, ErrWrongPayee
+	}
+	if transaction.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	timestamp, err := time.Parse(time.RFC3339, transaction.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+	expires, err := time.Parse(time.RFC3339, transaction.Expires)
+	if err != nil {
+		return nil, err
+	}
+
+	verifier.mu.Lock()
+	defer verifier.mu.Unlock()
+	now := verifier.now()
+	// A nonce only has to be remembered while its transaction could still be
+	// accepted
+	for nonce, nonceExpires := range verifier.nonces {
+		if now.After(nonceExpires) {
+			delete(verifier.nonces, nonce)
+		}
+	}
+	if now.After(expires) {
+		return nil, ErrTransactionExpired
+	}
+	if timestamp.After(now.Add(clockSkew)) {
+		return nil, ErrTransactionNotYetValid
+	}
+	key := transaction.Payer + "/" + transaction.Nonce
+	if _, used := verifier.nonces[key]; used {
+		return nil, ErrReusedNonce
+	}
+	verifier.nonces[key] = expires
+	return &transaction, nil
+}
@@ -0,0 +1,125 @@
+package hash
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestVerifyTransaction(t *testing.T) {
+	payer := newTestKey(t)
+	other := newTestKey(t)
+	payee, _ := Fingerprint(&newTestKey(t).PublicKey)
+
+	sign := func(key *rsa.PrivateKey, edit func(*Transaction)) *SignedTransaction {
+		transaction, err := NewTransaction(&payer.PublicKey, payee, 2.5, "abc", time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if edit != nil {
+			edit(transaction)
+		}
+		signed, err := SignTransaction(transaction, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	replayed := sign(payer, nil)
+
+	tests := []struct {
+		name   string
+		signed *SignedTransaction
+		want   error
+	}{
+		{name: "valid", signed: sign(payer, nil)},
+		{name: "first use", signed: replayed},
+		{name: "reused nonce", signed: replayed, want: ErrReusedNonce},
+		{
+			name: "tampered amount",
+			signed: func() *SignedTransaction {
+				signed := sign(payer, nil)
+				var transaction Transaction
+				json.Unmarshal(signed.Transaction, &transaction)
+				transaction.Amount = 1000
+				signed.Transaction, _ = json.Marshal(transaction)
+				return signed
+			}(),
+			want: ErrBadTransactionSignature,
+		},
+		{
+			name: "signature from another key",
+			signed: func() *SignedTransaction {
+				signed := sign(payer, nil)
+				signed.Signature = sign(other, nil).Signature
+				return signed
+			}(),
+			want: ErrBadTransactionSignature,
+		},
+		{name: "signed by someone other than the payer", signed: sign(other, nil), want: ErrWrongPayer},
+		{name: "different payee", signed: sign(payer, func(transaction *Transaction) { transaction.Payee = "someone" }), want: ErrWrongPayee},
+		{
+			name: "expired",
+			signed: sign(payer, func(transaction *Transaction) {
+				transaction.Expires = time.Now().Add(-time.Minute).Format(time.RFC3339)
+			}),
+			want: ErrTransactionExpired,
+		},
+		{
+			name: "dated in the future",
+			signed: sign(payer, func(transaction *Transaction) {
+				transaction.Timestamp = time.Now().Add(time.Hour).Format(time.RFC3339)
+				transaction.Expires = time.Now().Add(2 * time.Hour).Format(time.RFC3339)
+			}),
+			want: ErrTransactionNotYetValid,
+		},
+		{name: "negative amount", signed: sign(payer, func(transaction *Transaction) { transaction.Amount = -1 }), want: ErrInvalidAmount},
+		{
+			name: "malformed public key",
+			signed: func() *SignedTransaction {
+				signed := sign(payer, nil)
+				signed.PublicKey = "not a key"
+				return signed
+			}(),
+			want: ErrBadTransactionSignature,
+		},
+	}
+
+	verifier := NewVerifier(payee)
+	for _, test := range tests {
+		_, err := verifier.Verify(test.signed)
+		if !errors.Is(err, test.want) {
+			t.Errorf("%s: Verify() error = %v, want %v", test.name, err, test.want)
+		}
+	}
+}
+
+func TestVerifierForgetsExpiredNonces(t *testing.T) {
+	payer := newTestKey(t)
+	payee, _ := Fingerprint(&newTestKey(t).PublicKey)
+	transaction, _ := NewTransaction(&payer.PublicKey, payee, 1, "", time.Minute)
+	signed, _ := SignTransaction(transaction, payer)
+
+	verifier := NewVerifier(payee)
+	if _, err := verifier.Verify(signed); err != nil {
+		t.Fatal(err)
+	}
+	verifier.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if _, err := verifier.Verify(signed); !errors.Is(err, ErrTransactionExpired) {
+		t.Errorf("expected replay after expiry to be rejected as expired, got %v", err)
+	}
+	if len(verifier.nonces) != 0 {
+		t.Errorf("expected expired nonces to be forgotten, %d left", len(verifier.nonces))
+	}
+}
@@ -6,46 +6,12 @@ import (
 	"crypto/rsa"
 	"crypto/sha256"
 	"crypto/x509"
-	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"fmt"
 	"os"
-	"time"
-
-	"github.com/google/uuid"
 )
 
-type Transaction struct {
-	Price     float64 `json:"price"`
-	Timestamp string  `json:"timestamp"`
-	Uuid      string  `json:"uuid"`
-}
-
-func GeneratePriceBytes(price float64) []byte {
-	timestamp := time.Now()
-	timestampStr := timestamp.Format(time.RFC3339)
-	uuidObj, err := uuid.NewRandom()
-	if err != nil {
-		fmt.Println("Error generating UUID:", err)
-		return nil
-	}
-
-	// Convert the UUID to a string
-	uuidStr := uuidObj.String()
-	jsonData := Transaction{
-		Price:     price,
-		Timestamp: timestampStr,
-		Uuid:      uuidStr,
-	}
-	// Marshal JSON object to byte array
-	jsonBytes, err := json.Marshal(jsonData)
-	if err != nil {
-		fmt.Println("Error marshaling JSON:", err)
-		return nil
-	}
-	return jsonBytes
-}
 func OpenTransactionFile(message []byte, pubKey *rsa.PublicKey) {
 
 }
@@ -174,14 +140,18 @@ func LoadInKeys() (*rsa.PublicKey, *rsa.PrivateKey) {
 			fmt.Println("Error generating public key as PEM str:", err)
 			os.Exit(1)
 		}
-		os.WriteFile("./config/key.pub", pubBytes, 0644)
+		// The peer ID is derived from this key, so it has to be kept for the
+		// next start
+		if err := os.WriteFile("./config/key.pub", pubBytes, 0644); err != nil {
+			fmt.Println("Error saving public key:", err)
+			os.Exit(1)
+		}
 
 		privBytes := ExportRsaPrivateKeyAsPemStr(privateKey)
-		if err != nil {
-			fmt.Println("Error generating public key as PEM str:", err)
+		if err := os.WriteFile("./config/key.priv", privBytes, 0600); err != nil {
+			fmt.Println("Error saving private key:", err)
 			os.Exit(1)
 		}
-		os.WriteFile("./config/key.priv", privBytes, 0644)
 	}
 
 	// Sign file
@@ -0,0 +1,330 @@
+package ledger
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	pb "orca-peer/internal/fileshare"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	blocksName = "blocks"
+
+	DefaultBatchSize     = 16
+	DefaultBatchInterval = 2 * time.Second
+)
+
+var (
+	ErrDuplicate     = errors.New("transaction is already in the ledger")
+	ErrBlockNotFound = errors.New("block not found")
+	ErrBrokenChain   = errors.New("block does not follow the one before it")
+	ErrClosed        = errors.New("ledger is closed")
+)
+
+type Config struct {
+	// A block is sealed once it holds this many transactions
+	BatchSize int `json:"batch_size"`
+	// or this many seconds after its first transaction arrived
+	BatchInterval int `json:"batch_interval"`
+}
+
+func (config Config) Validate() error {
+	if config.BatchSize < 0 || config.BatchInterval < 0 {
+		return fmt.Errorf("batch size and interval can not be negative")
+	}
+	return nil
+}
+
+// pending is a transaction waiting for its block, and whoever submitted it.
+type pending struct {
+	transaction *pb.FileRequestTransaction
+	sealed      chan *pb.Block
+}
+
+/*
+Ledger is an append-only chain of blocks of signed transactions. Submitted
+transactions are collected into a batch, and the batch is sealed into a block
+whose hash covers the hash of the block before it, so no block can be changed
+without changing every block after it. Blocks are appended to a journal on
+disk before anyone is told about them, and the chain is checked every time
+the journal is read.
+*/
+type Ledger struct {
+	mu            sync.Mutex
+	path          string
+	journal       *os.File
+	blocks        []*pb.Block
+	byHash        map[string]*pb.Block
+	known         map[string]bool
+	batch         []pending
+	batchSize     int
+	batchInterval time.Duration
+	timer         *time.Timer
+	closed        bool
+	now           func() time.Time
+}
+
+// Open loads the chain in dir, starting an empty one if there is none yet.
+func Open(dir string, config Config) (*Ledger, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	ledger := &Ledger{
+		path:          filepath.Join(dir, blocksName),
+		byHash:        make(map[string]*pb.Block),
+		known:         make(map[string]bool),
+		batchSize:     config.BatchSize,
+		batchInterval: time.Duration(config.BatchInterval) * time.Second,
+		now:           time.Now,
+	}
+	if ledger.batchSize == 0 {
+		ledger.batchSize = DefaultBatchSize
+	}
+	if ledger.batchInterval == 0 {
+		ledger.batchInterval = DefaultBatchInterval
+	}
+	if err := ledger.replay(); err != nil {
+		return nil, err
+	}
+	journal, err := os.OpenFile(ledger.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	ledger.journal = journal
+	return ledger, nil
+}
+
+func (ledger *Ledger) replay() error {
+	data, err := os.ReadFile(ledger.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// Only the last line can be cut short, by a crash while appending. Its
+	// transactions were never acknowledged, so dropping it loses nothing.
+	complete := bytes.LastIndexByte(data, '\n') + 1
+	if complete < len(data) {
+		fmt.Println("Dropping incomplete ledger block")
+		if err := os.Truncate(ledger.path, int64(complete)); err != nil {
+			return err
+		}
+	}
+	for _, line := range bytes.Split(data[:complete], []byte{'\n'}) {
+		if len(line) == 0 {
+			continue
+		}
+		block := &pb.Block{}
+		if err := protojson.Unmarshal(line, block); err != nil {
+			return fmt.Errorf("corrupt ledger block %d: %w", len(ledger.blocks), err)
+		}
+		if err := ledger.check(block); err != nil {
+			return fmt.Errorf("ledger block %d: %w", len(ledger.blocks), err)
+		}
+		ledger.add(block)
+	}
+	return nil
+}
+
+// check verifies that block comes right after the last block of the chain
+// and that its hash and transactions are what they claim to be. Callers must
+// hold ledger.mu
+func (ledger *Ledger) check(block *pb.Block) error {
+	previous := ""
+	if len(ledger.blocks) > 0 {
+		previous = ledger.blocks[len(ledger.blocks)-1].Hash
+	}
+	if block.Height != int64(len(ledger.blocks)) || block.PreviousHash != previous || block.Hash != BlockHash(block) {
+		return ErrBrokenChain
+	}
+	for _, transaction := range block.Transactions {
+		if err := VerifyTransaction(transaction); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// add appends block to the chain in memory. Callers must hold ledger.mu
+func (ledger *Ledger) add(block *pb.Block) {
+	ledger.blocks = append(ledger.blocks, block)
+	ledger.byHash[block.Hash] = block
+	for _, transaction := range block.Transactions {
+		ledger.known[TransactionID(transaction)] = true
+	}
+}
+
+/*
+BlockHash is the hex SHA-256 of the height, previous hash and timestamp of
+block followed by the IDs of its transactions. The signatures the IDs are
+made from cover everything else in the transactions.
+*/
+func BlockHash(block *pb.Block) string {
+	digest := sha256.New()
+	binary.Write(digest, binary.BigEndian, block.Height)
+	digest.Write([]byte(block.PreviousHash))
+	binary.Write(digest, binary.BigEndian, block.Timestamp)
+	for _, transaction := range block.Transactions {
+		digest.Write([]byte(TransactionID(transaction)))
+	}
+	return hex.EncodeToString(digest.Sum(nil))
+}
+
+/*
+Submit verifies transaction and adds it to the next block. It returns the
+block once it is sealed and on disk. A transaction that is already in the
+ledger, or waiting for a block, is refused with ErrDuplicate.
+*/
+func (ledger *Ledger) Submit(ctx context.Context, transaction *pb.FileRequestTransaction) (*pb.Block, error) {
+	if err := VerifyTransaction(transaction); err != nil {
+		return nil, err
+	}
+	id := TransactionID(transaction)
+	sealed := make(chan *pb.Block, 1)
+
+	ledger.mu.Lock()
+	if ledger.closed {
+		ledger.mu.Unlock()
+		return nil, ErrClosed
+	}
+	if ledger.known[id] {
+		ledger.mu.Unlock()
+		return nil, ErrDuplicate
+	}
+	ledger.known[id] = true
+	ledger.batch = append(ledger.batch, pending{transaction: transaction, sealed: sealed})
+	if len(ledger.batch) >= ledger.batchSize {
+		ledger.seal()
+	} else if ledger.timer == nil {
+		ledger.timer = time.AfterFunc(ledger.batchInterval, func() {
+			ledger.mu.Lock()
+			defer ledger.mu.Unlock()
+			ledger.seal()
+		})
+	}
+	ledger.mu.Unlock()
+
+	select {
+	case block := <-sealed:
+		if block == nil {
+			return nil, fmt.Errorf("sealing block failed")
+		}
+		return block, nil
+	case <-ctx.Done():
+		// The transaction still goes into the block, the caller just stops
+		// waiting for it
+		return nil, ctx.Err()
+	}
+}
+
+// seal turns the batch into a block and appends it to the journal. If that
+// fails, the transactions of the batch are dropped and their submitters get
+// nil. Callers must hold ledger.mu
+func (ledger *Ledger) seal() {
+	if ledger.timer != nil {
+		ledger.timer.Stop()
+		ledger.timer = nil
+	}
+	if len(ledger.batch) == 0 {
+		return
+	}
+	batch := ledger.batch
+	ledger.batch = nil
+
+	block := &pb.Block{Height: int64(len(ledger.blocks)), Timestamp: ledger.now().Unix()}
+	if len(ledger.blocks) > 0 {
+		block.PreviousHash = ledger.blocks[len(ledger.blocks)-1].Hash
+	}
+	for _, waiting := range batch {
+		block.Transactions = append(block.Transactions, waiting.transaction)
+	}
+	block.Hash = BlockHash(block)
+
+	err := ledger.append(block)
+	if err != nil {
+		fmt.Println("Error appending ledger block:", err)
+		for _, waiting := range batch {
+			delete(ledger.known, TransactionID(waiting.transaction))
+			waiting.sealed <- nil
+		}
+		return
+	}
+	ledger.add(block)
+	for _, waiting := range batch {
+		waiting.sealed <- block
+	}
+}
+
+// append writes block to the journal. Callers must hold ledger.mu
+func (ledger *Ledger) append(block *pb.Block) error {
+	line, err := protojson.Marshal(block)
+	if err != nil {
+		return err
+	}
+	// protojson may add spaces but never newlines without Multiline
+	if _, err := ledger.journal.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return ledger.journal.Sync()
+}
+
+// Block returns the block with hash.
+func (ledger *Ledger) Block(hash string) (*pb.Block, error) {
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+	block, ok := ledger.byHash[hash]
+	if !ok {
+		return nil, ErrBlockNotFound
+	}
+	return block, nil
+}
+
+// Height is the number of blocks in the chain.
+func (ledger *Ledger) Height() int {
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+	return len(ledger.blocks)
+}
+
+// Transactions returns every transaction in the chain that was sent by
+// sender or received by receiver, oldest first. Empty ids match nothing.
+func (ledger *Ledger) Transactions(sender string, receiver string) []*pb.FileRequestTransaction {
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+	transactions := []*pb.FileRequestTransaction{}
+	for _, block := range ledger.blocks {
+		for _, transaction := range block.Transactions {
+			if (sender != "" && transaction.SenderId == sender) || (receiver != "" && transaction.ReceiverId == receiver) {
+				transactions = append(transactions, transaction)
+			}
+		}
+	}
+	return transactions
+}
+
+// Close seals the transactions still waiting for a block and closes the
+// journal.
+func (ledger *Ledger) Close() error {
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+	if ledger.closed {
+		return nil
+	}
+	ledger.seal()
+	ledger.closed = true
+	return ledger.journal.Close()
+}
@@ -0,0 +1,131 @@
+package ledger
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	pb "orca-peer/internal/fileshare"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func signedTransaction(t *testing.T, key *rsa.PrivateKey, receiver string) *pb.FileRequestTransaction {
+	transaction := &pb.FileRequestTransaction{FileByteSize: 100, FileHashName: "file", CurrencyExchanged: 1, ReceiverId: receiver}
+	if err := SignTransaction(transaction, key); err != nil {
+		t.Fatal(err)
+	}
+	return transaction
+}
+
+func TestSubmitBatchesIntoLinkedBlocks(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	ledger, err := Open(dir, Config{BatchSize: 2, BatchInterval: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// Two transactions fill one block
+	blocks := make([]*pb.Block, 2)
+	errs := make([]error, 2)
+	var wg sync.WaitGroup
+	for i, receiver := range []string{"a", "b"} {
+		wg.Add(1)
+		go func(i int, transaction *pb.FileRequestTransaction) {
+			defer wg.Done()
+			blocks[i], errs[i] = ledger.Submit(ctx, transaction)
+		}(i, signedTransaction(t, key, receiver))
+	}
+	wg.Wait()
+	if errs[0] != nil || errs[1] != nil {
+		t.Fatal(errs)
+	}
+	if blocks[0] == nil || blocks[0] != blocks[1] || len(blocks[0].Transactions) != 2 {
+		t.Fatalf("expected both transactions in the same block, got %v and %v", blocks[0], blocks[1])
+	}
+
+	duplicate := blocks[0].Transactions[0]
+	if _, err := ledger.Submit(ctx, duplicate); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("expected a resubmitted transaction to be refused, got %v", err)
+	}
+	tampered := signedTransaction(t, key, "a")
+	tampered.CurrencyExchanged = 100
+	if _, err := ledger.Submit(ctx, tampered); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("expected a changed transaction to be refused, got %v", err)
+	}
+	if _, err := ledger.Submit(ctx, &pb.FileRequestTransaction{SenderId: "someone"}); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("expected an unsigned transaction to be refused, got %v", err)
+	}
+
+	// A lone transaction is sealed when the ledger closes, even if nobody
+	// waits for it any more
+	gone, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := ledger.Submit(gone, signedTransaction(t, key, "a")); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the submitter to stop waiting, got %v", err)
+	}
+	if err := ledger.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := Open(dir, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if reopened.Height() != 2 {
+		t.Fatalf("expected 2 blocks after reopening, got %d", reopened.Height())
+	}
+	second := reopened.blocks[1]
+	if second.PreviousHash != blocks[0].Hash {
+		t.Errorf("expected the second block to link to the first")
+	}
+	if block, err := reopened.Block(blocks[0].Hash); err != nil || len(block.Transactions) != 2 {
+		t.Errorf("expected to find the first block by hash, got %v (%v)", block, err)
+	}
+	sender := blocks[0].Transactions[0].SenderId
+	if got := reopened.Transactions(sender, ""); len(got) != 3 {
+		t.Errorf("expected 3 transactions from the sender, got %d", len(got))
+	}
+	if got := reopened.Transactions("", "a"); len(got) != 2 {
+		t.Errorf("expected 2 transactions to a, got %d", len(got))
+	}
+}
+
+func TestOpenRejectsTamperedChain(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	ledger, err := Open(dir, Config{BatchSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ledger.Submit(context.Background(), signedTransaction(t, key, "a")); err != nil {
+		t.Fatal(err)
+	}
+	ledger.Close()
+
+	path := filepath.Join(dir, blocksName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := strings.Replace(string(data), `"receiverId":"a"`, `"receiverId":"b"`, 1)
+	if tampered == string(data) {
+		t.Fatalf("test did not change the journal: %s", data)
+	}
+	os.WriteFile(path, []byte(tampered), 0644)
+	if _, err := Open(dir, Config{}); err == nil {
+		t.Errorf("expected a changed block to be refused")
+	}
+}
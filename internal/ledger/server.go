@@ -0,0 +1,69 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"net"
+	pb "orca-peer/internal/fileshare"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server answers the ledger calls of file_share.proto from a Ledger.
+type Server struct {
+	pb.UnimplementedFileShareServer
+	ledger *Ledger
+	// Sent back in every acknowledgement
+	id string
+}
+
+func NewServer(ledger *Ledger, id string) *Server {
+	return &Server{ledger: ledger, id: id}
+}
+
+// Serve answers ledger calls on listener until it is closed.
+func Serve(listener net.Listener, ledger *Ledger, id string) error {
+	grpcServer := grpc.NewServer()
+	pb.RegisterFileShareServer(grpcServer, NewServer(ledger, id))
+	return grpcServer.Serve(listener)
+}
+
+// RecordFileRequestTransaction answers once the transaction is in a sealed
+// block, with the hash and time of that block.
+func (server *Server) RecordFileRequestTransaction(ctx context.Context, transaction *pb.FileRequestTransaction) (*pb.TransactionACKResponse, error) {
+	block, err := server.ledger.Submit(ctx, transaction)
+	switch {
+	case errors.Is(err, ErrDuplicate):
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, ErrUnsigned), errors.Is(err, ErrBadSignature), errors.Is(err, ErrWrongSender):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrClosed):
+		return nil, status.Error(codes.Unavailable, err.Error())
+	case err != nil:
+		return nil, status.FromContextError(err).Err()
+	}
+	return &pb.TransactionACKResponse{
+		IsSuccess: true,
+		BlockHash: block.Hash,
+		Timestamp: float64(block.Timestamp),
+		MarketId:  server.id,
+	}, nil
+}
+
+func (server *Server) GetBlock(ctx context.Context, request *pb.BlockRequest) (*pb.Block, error) {
+	block, err := server.ledger.Block(request.GetHash())
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return block, nil
+}
+
+func (server *Server) GetTransactions(ctx context.Context, request *pb.TransactionsRequest) (*pb.TransactionsResponse, error) {
+	if request.GetSenderId() == "" && request.GetReceiverId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "a sender or receiver id is required")
+	}
+	transactions := server.ledger.Transactions(request.GetSenderId(), request.GetReceiverId())
+	return &pb.TransactionsResponse{Transactions: transactions}, nil
+}
@@ -0,0 +1,82 @@
+package ledger
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	pb "orca-peer/internal/fileshare"
+	"orca-peer/internal/hash"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	ErrUnsigned     = errors.New("transaction is not signed")
+	ErrBadSignature = errors.New("transaction signature is invalid")
+	ErrWrongSender  = errors.New("transaction was not signed by its sender")
+)
+
+// signingBytes are the bytes a signature covers: the transaction without its
+// signature, encoded the same way every time.
+func signingBytes(transaction *pb.FileRequestTransaction) ([]byte, error) {
+	unsigned := proto.Clone(transaction).(*pb.FileRequestTransaction)
+	unsigned.Signature = nil
+	return proto.MarshalOptions{Deterministic: true}.Marshal(unsigned)
+}
+
+// SignTransaction fills in the nonce, timestamp, sender and public key of
+// transaction and signs it with privateKey.
+func SignTransaction(transaction *pb.FileRequestTransaction, privateKey *rsa.PrivateKey) error {
+	publicKey, err := hash.ExportRsaPublicKeyAsPemStr(&privateKey.PublicKey)
+	if err != nil {
+		return err
+	}
+	fingerprint, err := hash.Fingerprint(&privateKey.PublicKey)
+	if err != nil {
+		return err
+	}
+	transaction.Nonce = uuid.NewString()
+	transaction.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	transaction.SenderId = fingerprint
+	transaction.PublicKey = string(publicKey)
+	data, err := signingBytes(transaction)
+	if err != nil {
+		return err
+	}
+	transaction.Signature, err = hash.SignFile(data, privateKey)
+	return err
+}
+
+// VerifyTransaction checks that transaction was signed with the key of its
+// sender.
+func VerifyTransaction(transaction *pb.FileRequestTransaction) error {
+	if len(transaction.GetSignature()) == 0 || transaction.GetPublicKey() == "" {
+		return ErrUnsigned
+	}
+	publicKey, err := hash.ParseRsaPublicKeyFromPemStr(transaction.GetPublicKey())
+	if err != nil {
+		return ErrBadSignature
+	}
+	fingerprint, err := hash.Fingerprint(publicKey)
+	if err != nil || fingerprint != transaction.GetSenderId() {
+		return ErrWrongSender
+	}
+	data, err := signingBytes(transaction)
+	if err != nil {
+		return err
+	}
+	if err := hash.VerifySignature(data, transaction.GetSignature(), publicKey); err != nil {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// TransactionID names a signed transaction. Transactions with the same ID are
+// the same transaction submitted twice.
+func TransactionID(transaction *pb.FileRequestTransaction) string {
+	checksum := sha256.Sum256(transaction.GetSignature())
+	return hex.EncodeToString(checksum[:])
+}
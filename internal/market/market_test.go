@@ -0,0 +1,113 @@
+package market
+
+import (
+	"context"
+	"net"
+	orcaClient "orca-peer/internal/client"
+	pb "orca-peer/internal/fileshare"
+	"testing"
+)
+
+const testPeerID = "QmS6bES2qGSCN1vTmxEjZGDwwLw5Lsm2ToAcpzf97S7BnR"
+
+func startMarket(t *testing.T, dir string) string {
+	registry, err := OpenRegistry(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go Serve(listener, registry)
+	t.Cleanup(func() { listener.Close() })
+	return listener.Addr().String()
+}
+
+func TestRegisterAndCheckHolders(t *testing.T) {
+	dir := t.TempDir()
+	address := startMarket(t, dir)
+	ctx := context.Background()
+
+	expensive, err := orcaClient.DialMarket(orcaClient.MarketConfig{Address: address}, &pb.User{Id: testPeerID, Port: 8000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer expensive.Close()
+	cheap, err := orcaClient.DialMarket(orcaClient.MarketConfig{Address: address}, &pb.User{Id: "plain-holder", Ip: "10.0.0.2", Port: 9000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cheap.Close()
+
+	if err := expensive.RegisterFile(ctx, "file", 2.5); err != nil {
+		t.Fatal(err)
+	}
+	if err := cheap.RegisterFile(ctx, "file", 1); err != nil {
+		t.Fatal(err)
+	}
+	holders, err := cheap.CheckHolders(ctx, "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []orcaClient.Holder{
+		{Address: "10.0.0.2:9000", Price: 1},
+		// Listed under the address it called from, with its price rounded up
+		{PeerID: testPeerID, Address: "127.0.0.1:8000", Price: 3},
+	}
+	if len(holders) != len(want) {
+		t.Fatalf("expected %v, got %v", want, holders)
+	}
+	for i := range want {
+		if holders[i] != want[i] {
+			t.Errorf("expected holder %d to be %v, got %v", i, want[i], holders[i])
+		}
+	}
+
+	// Holders survive a restart
+	restarted, err := orcaClient.DialMarket(orcaClient.MarketConfig{Address: startMarket(t, dir)}, &pb.User{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restarted.Close()
+	holders, err = restarted.CheckHolders(ctx, "file")
+	if err != nil || len(holders) != 2 {
+		t.Errorf("expected 2 holders after a restart, got %v (%v)", holders, err)
+	}
+}
+
+func TestNotifyFileStoreAndUnstore(t *testing.T) {
+	registry, err := OpenRegistry(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(registry)
+	ctx := context.Background()
+	file := &pb.FileDesc{FileNameHash: "file", FileName: "a.txt", FileSizeBytes: 10, FileOriginAddress: "10.0.0.1:8000", OriginUserId: "holder", FileCost: 1.5}
+	if ack, err := server.NotifyFileStore(ctx, file); err != nil || !ack.IsAcknowledged {
+		t.Fatalf("expected the file to be acknowledged, got %v (%v)", ack, err)
+	}
+	files := registry.Files()
+	if len(files) != 1 || files[0].Name != "a.txt" || files[0].Size != 10 {
+		t.Errorf("expected the description to be kept, got %+v", files)
+	}
+	if holders := registry.Holders("file"); len(holders) != 1 || holders[0].Price != 2 || holders[0].Port != 8000 {
+		t.Errorf("expected the origin to be a holder, got %+v", holders)
+	}
+	if held := registry.FilesHeldBy("holder"); len(held) != 1 || held[0].Hash != "file" {
+		t.Errorf("expected the file to be listed for its holder, got %+v", held)
+	}
+	if held := registry.FilesHeldBy("someone else"); len(held) != 0 {
+		t.Errorf("expected no files for another holder, got %+v", held)
+	}
+
+	if _, err := server.NotifyFileUnstore(ctx, file); err != nil {
+		t.Fatal(err)
+	}
+	if files := registry.Files(); len(files) != 0 {
+		t.Errorf("expected a file without holders to be forgotten, got %+v", files)
+	}
+	if _, err := server.RegisterFile(ctx, &pb.RegisterFileRequest{FileHash: "file"}); err == nil {
+		t.Errorf("expected a registration without a user to be refused")
+	}
+}
@@ -0,0 +1,182 @@
+package market
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const registryName = "registry.json"
+
+// Holder is a peer that said it stores a file.
+type Holder struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	IP   string `json:"ip"`
+	Port int32  `json:"port"`
+	// Price per MB
+	Price   int64     `json:"price"`
+	Updated time.Time `json:"updated"`
+}
+
+// File is everything the market knows about one file.
+type File struct {
+	Hash    string             `json:"hash"`
+	Name    string             `json:"name"`
+	Size    int64              `json:"size"`
+	Cost    float32            `json:"cost"`
+	Holders map[string]*Holder `json:"holders"`
+}
+
+/*
+Registry keeps the holders of every file registered with the market. Holders
+are named by their ID, so a peer that registers a file again replaces its old
+entry. The registry is written to disk after every change.
+*/
+type Registry struct {
+	mu    sync.Mutex
+	path  string
+	files map[string]*File
+}
+
+// OpenRegistry loads the registry in dir, starting empty if there is none yet.
+func OpenRegistry(dir string) (*Registry, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	registry := &Registry{
+		path:  filepath.Join(dir, registryName),
+		files: make(map[string]*File),
+	}
+	data, err := os.ReadFile(registry.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return registry, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	files := []*File{}
+	if err := json.Unmarshal(data, &files); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", registry.path, err)
+	}
+	for _, file := range files {
+		registry.files[file.Hash] = file
+	}
+	return registry, nil
+}
+
+// file returns the entry of hash, adding it if needed. Callers must hold
+// registry.mu
+func (registry *Registry) file(hash string) *File {
+	file, ok := registry.files[hash]
+	if !ok {
+		file = &File{Hash: hash, Holders: make(map[string]*Holder)}
+		registry.files[hash] = file
+	}
+	return file
+}
+
+// Register records that holder stores the file with hash.
+func (registry *Registry) Register(hash string, holder Holder) error {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	holder.Updated = time.Now()
+	registry.file(hash).Holders[holder.ID] = &holder
+	return registry.save()
+}
+
+// Describe records the name, size and cost of a file.
+func (registry *Registry) Describe(hash string, name string, size int64, cost float32) error {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	file := registry.file(hash)
+	file.Name = name
+	file.Size = size
+	file.Cost = cost
+	return registry.save()
+}
+
+// Unregister forgets that the holder with id stores the file with hash. A
+// file without holders is forgotten as well.
+func (registry *Registry) Unregister(hash string, id string) error {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	file, ok := registry.files[hash]
+	if !ok {
+		return nil
+	}
+	delete(file.Holders, id)
+	if len(file.Holders) == 0 {
+		delete(registry.files, hash)
+	}
+	return registry.save()
+}
+
+// Holders returns the holders of the file with hash, cheapest first.
+func (registry *Registry) Holders(hash string) []Holder {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	file, ok := registry.files[hash]
+	if !ok {
+		return nil
+	}
+	holders := make([]Holder, 0, len(file.Holders))
+	for _, holder := range file.Holders {
+		holders = append(holders, *holder)
+	}
+	sort.Slice(holders, func(i, j int) bool {
+		if holders[i].Price != holders[j].Price {
+			return holders[i].Price < holders[j].Price
+		}
+		return holders[i].ID < holders[j].ID
+	})
+	return holders
+}
+
+// Files returns every file that has holders, ordered by hash. The holders
+// are left out.
+func (registry *Registry) Files() []File {
+	return registry.filesWhere(func(file *File) bool { return len(file.Holders) > 0 })
+}
+
+// FilesHeldBy returns the files the holder with id stores, ordered by hash.
+func (registry *Registry) FilesHeldBy(id string) []File {
+	return registry.filesWhere(func(file *File) bool { return file.Holders[id] != nil })
+}
+
+func (registry *Registry) filesWhere(keep func(file *File) bool) []File {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	files := make([]File, 0, len(registry.files))
+	for _, file := range registry.files {
+		if !keep(file) {
+			continue
+		}
+		files = append(files, File{Hash: file.Hash, Name: file.Name, Size: file.Size, Cost: file.Cost})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Hash < files[j].Hash })
+	return files
+}
+
+// save writes every file to disk. Callers must hold registry.mu
+func (registry *Registry) save() error {
+	files := make([]*File, 0, len(registry.files))
+	for _, file := range registry.files {
+		files = append(files, file)
+	}
+	data, err := json.Marshal(files)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first so a crash never leaves half a registry
+	if err := os.WriteFile(registry.path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(registry.path+".tmp", registry.path)
+}
@@ -0,0 +1,151 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	pb "orca-peer/internal/fileshare"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// Server answers the market calls of file_share.proto from a Registry.
+type Server struct {
+	pb.UnimplementedFileShareServer
+	registry *Registry
+}
+
+func NewServer(registry *Registry) *Server {
+	return &Server{registry: registry}
+}
+
+// Serve answers market calls on listener until it is closed.
+func Serve(listener net.Listener, registry *Registry) error {
+	grpcServer := grpc.NewServer()
+	pb.RegisterFileShareServer(grpcServer, NewServer(registry))
+	return grpcServer.Serve(listener)
+}
+
+// callerIP is the IP the call in ctx came from, which holders that do not
+// know their own public address are listed under.
+func callerIP(ctx context.Context) string {
+	caller, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(caller.Addr.String())
+	if err != nil {
+		return ""
+	}
+	return host
+}
+
+func (server *Server) RegisterFile(ctx context.Context, request *pb.RegisterFileRequest) (*emptypb.Empty, error) {
+	user := request.GetUser()
+	if request.GetFileHash() == "" || user.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "a file hash and a user id are required")
+	}
+	if user.GetPort() <= 0 || user.GetPort() > math.MaxUint16 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid port %d", user.GetPort())
+	}
+	holder := Holder{ID: user.GetId(), Name: user.GetName(), IP: user.GetIp(), Port: user.GetPort(), Price: user.GetPrice()}
+	if holder.IP == "" {
+		holder.IP = callerIP(ctx)
+	}
+	if err := server.registry.Register(request.GetFileHash(), holder); err != nil {
+		return nil, status.Errorf(codes.Internal, "saving registry: %s", err)
+	}
+	fmt.Printf("%s registered %s at %s:%d for %d per MB\n", holder.ID, request.GetFileHash(), holder.IP, holder.Port, holder.Price)
+	return &emptypb.Empty{}, nil
+}
+
+func (server *Server) CheckHolders(ctx context.Context, request *pb.CheckHoldersRequest) (*pb.HoldersResponse, error) {
+	if request.GetFileHash() == "" {
+		return nil, status.Error(codes.InvalidArgument, "a file hash is required")
+	}
+	response := &pb.HoldersResponse{}
+	for _, holder := range server.registry.Holders(request.GetFileHash()) {
+		response.Holders = append(response.Holders, &pb.User{
+			Id:    holder.ID,
+			Name:  holder.Name,
+			Ip:    holder.IP,
+			Port:  holder.Port,
+			Price: holder.Price,
+		})
+	}
+	return response, nil
+}
+
+// RequestAllAvailableFileNames lists every file with holders, or only the
+// files of the holder whose id is in request.
+func (server *Server) RequestAllAvailableFileNames(request *pb.StorageIP, stream pb.FileShare_RequestAllAvailableFileNamesServer) error {
+	files := server.registry.Files()
+	if request.GetUserId() != "" {
+		files = server.registry.FilesHeldBy(request.GetUserId())
+	}
+	for _, file := range files {
+		description := &pb.FileDesc{FileNameHash: file.Hash, FileName: file.Name, FileSizeBytes: file.Size, FileCost: file.Cost}
+		if err := stream.Send(description); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NotifyFileStore registers the origin of file as one of its holders and
+// keeps its description for RequestAllAvailableFileNames.
+func (server *Server) NotifyFileStore(ctx context.Context, file *pb.FileDesc) (*pb.StorageACKResponse, error) {
+	if file.GetFileNameHash() == "" || file.GetOriginUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "a file hash and an origin user id are required")
+	}
+	host, portText, err := net.SplitHostPort(file.GetFileOriginAddress())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "origin address: %s", err)
+	}
+	port, err := strconv.ParseUint(portText, 10, 16)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "origin port: %s", err)
+	}
+	if host == "" {
+		host = callerIP(ctx)
+	}
+	holder := Holder{
+		ID:    file.GetOriginUserId(),
+		IP:    host,
+		Port:  int32(port),
+		Price: int64(math.Ceil(float64(file.GetFileCost()))),
+	}
+	if err := server.registry.Register(file.GetFileNameHash(), holder); err != nil {
+		return nil, status.Errorf(codes.Internal, "saving registry: %s", err)
+	}
+	err = server.registry.Describe(file.GetFileNameHash(), file.GetFileName(), file.GetFileSizeBytes(), file.GetFileCost())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "saving registry: %s", err)
+	}
+	return acknowledge(file), nil
+}
+
+func (server *Server) NotifyFileUnstore(ctx context.Context, file *pb.FileDesc) (*pb.StorageACKResponse, error) {
+	if file.GetFileNameHash() == "" || file.GetOriginUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "a file hash and an origin user id are required")
+	}
+	if err := server.registry.Unregister(file.GetFileNameHash(), file.GetOriginUserId()); err != nil {
+		return nil, status.Errorf(codes.Internal, "saving registry: %s", err)
+	}
+	return acknowledge(file), nil
+}
+
+func acknowledge(file *pb.FileDesc) *pb.StorageACKResponse {
+	return &pb.StorageACKResponse{
+		IsAcknowledged: true,
+		FileName:       file.GetFileName(),
+		FileHash:       file.GetFileNameHash(),
+		FileByteSize:   file.GetFileSizeBytes(),
+	}
+}
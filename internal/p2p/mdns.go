@@ -0,0 +1,54 @@
+package p2p
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/p2p/discovery/mdns"
+)
+
+// MDNSService is the name nodes announce themselves under on the local
+// network, so they only find other orcanet nodes.
+const MDNSService = "orcanet-market"
+
+// How long connecting to a peer found on the local network may take
+const mdnsConnectTimeout = 10 * time.Second
+
+// localNotifee connects to every peer mDNS finds.
+type localNotifee struct {
+	host  host.Host
+	found func(peer.AddrInfo)
+}
+
+func (notifee *localNotifee) HandlePeerFound(info peer.AddrInfo) {
+	if info.ID == notifee.host.ID() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), mdnsConnectTimeout)
+	defer cancel()
+	if err := notifee.host.Connect(ctx, info); err != nil {
+		fmt.Printf("\nFailed connecting to local peer %s: %s\n> ", info.ID, err)
+		return
+	}
+	if notifee.found != nil {
+		notifee.found(info)
+	}
+}
+
+/*
+StartMDNS announces h on the local network and connects to the other nodes
+announcing themselves there, which lets nodes on the same LAN or machine find
+each other without a bootstrap peer. found, which may be nil, is called for
+every peer that was connected to. Closing the result stops the service.
+*/
+func StartMDNS(h host.Host, found func(peer.AddrInfo)) (io.Closer, error) {
+	service := mdns.NewMdnsService(h, MDNSService, &localNotifee{host: h, found: found})
+	if err := service.Start(); err != nil {
+		return nil, err
+	}
+	return service, nil
+}
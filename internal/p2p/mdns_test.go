@@ -0,0 +1,37 @@
+package p2p
+
+import (
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+func TestMDNSConnectsLocalPeers(t *testing.T) {
+	first := newHost(t)
+	second := newHost(t)
+
+	found := make(chan peer.ID, 4)
+	service, err := StartMDNS(first, func(info peer.AddrInfo) { found <- info.ID })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer service.Close()
+	other, err := StartMDNS(second, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+
+	select {
+	case id := <-found:
+		if id != second.ID() {
+			t.Errorf("expected to find %s, got %s", second.ID(), id)
+		}
+	case <-time.After(20 * time.Second):
+		t.Skip("no multicast on this network")
+	}
+	if len(first.Network().ConnsToPeer(second.ID())) == 0 {
+		t.Error("expected the found peer to be connected")
+	}
+}
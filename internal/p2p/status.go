@@ -0,0 +1,66 @@
+package p2p
+
+import (
+	"sync"
+
+	"github.com/libp2p/go-libp2p/core/event"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// Status keeps track of whether other peers can reach this host.
+type Status struct {
+	host         host.Host
+	mu           sync.Mutex
+	reachability network.Reachability
+}
+
+// NewStatus starts following the reachability AutoNAT finds for h.
+func NewStatus(h host.Host) (*Status, error) {
+	status := &Status{host: h, reachability: network.ReachabilityUnknown}
+	subscription, err := h.EventBus().Subscribe(new(event.EvtLocalReachabilityChanged))
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		defer subscription.Close()
+		for e := range subscription.Out() {
+			changed := e.(event.EvtLocalReachabilityChanged)
+			status.mu.Lock()
+			status.reachability = changed.Reachability
+			status.mu.Unlock()
+		}
+	}()
+	return status, nil
+}
+
+func (status *Status) Reachability() network.Reachability {
+	status.mu.Lock()
+	defer status.mu.Unlock()
+	return status.reachability
+}
+
+// Addrs returns the addresses the host advertises, split into direct ones
+// and ones through a relay.
+func (status *Status) Addrs() (direct []ma.Multiaddr, relayed []ma.Multiaddr) {
+	for _, addr := range status.host.Addrs() {
+		if IsRelayed(addr) {
+			relayed = append(relayed, addr)
+		} else {
+			direct = append(direct, addr)
+		}
+	}
+	return direct, relayed
+}
+
+// Peers is the number of peers the host is connected to.
+func (status *Status) Peers() int {
+	return len(status.host.Network().Peers())
+}
+
+// IsRelayed reports whether addr goes through a circuit relay.
+func IsRelayed(addr ma.Multiaddr) bool {
+	_, err := addr.ValueForProtocol(ma.P_CIRCUIT)
+	return err == nil
+}
@@ -0,0 +1,146 @@
+package p2p
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/libp2p/go-libp2p/core/routing"
+)
+
+/*
+FileProtocol carries the HTTP file routes over libp2p streams. Every stream is
+one HTTP/1.1 connection, so requests, ranges and payment headers work exactly
+as they do over TCP, while libp2p takes care of encryption, multiplexing and
+reaching peers by their ID instead of an ip:port.
+*/
+const FileProtocol protocol.ID = "/orcanet/file/1.0.0"
+
+// Addr is the address of one end of a stream, which is just a peer ID.
+type Addr struct {
+	ID peer.ID
+}
+
+func (addr Addr) Network() string {
+	return "libp2p"
+}
+
+func (addr Addr) String() string {
+	return addr.ID.String()
+}
+
+// conn makes a stream usable where a net.Conn is expected.
+type conn struct {
+	network.Stream
+}
+
+func (c conn) LocalAddr() net.Addr {
+	return Addr{c.Stream.Conn().LocalPeer()}
+}
+
+func (c conn) RemoteAddr() net.Addr {
+	return Addr{c.Stream.Conn().RemotePeer()}
+}
+
+// listener hands out the streams other peers open to this host.
+type listener struct {
+	host   host.Host
+	conns  chan net.Conn
+	closed chan struct{}
+	once   sync.Once
+}
+
+// Listen accepts FileProtocol streams on h until the listener is closed. The
+// remote address of every connection is the peer ID of the other side.
+func Listen(h host.Host) net.Listener {
+	l := &listener{host: h, conns: make(chan net.Conn), closed: make(chan struct{})}
+	h.SetStreamHandler(FileProtocol, func(stream network.Stream) {
+		select {
+		case l.conns <- conn{stream}:
+		case <-l.closed:
+			stream.Reset()
+		}
+	})
+	return l
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *listener) Close() error {
+	l.once.Do(func() {
+		l.host.RemoveStreamHandler(FileProtocol)
+		close(l.closed)
+	})
+	return nil
+}
+
+func (l *listener) Addr() net.Addr {
+	return Addr{l.host.ID()}
+}
+
+// IsPeerAddress reports whether the host of an address is a peer ID, which is
+// how holders that are reached over libp2p are named.
+func IsPeerAddress(address string) bool {
+	hostname, _, err := net.SplitHostPort(address)
+	if err != nil {
+		hostname = address
+	}
+	_, err = peer.Decode(hostname)
+	return err == nil
+}
+
+/*
+NewTransport returns an HTTP transport that opens a FileProtocol stream for
+URLs whose host is a peer ID, like http://<peer ID>/manifest/<cid>, and dials
+TCP for everything else. Peers whose addresses are not known yet are looked up
+with router, which may be nil.
+*/
+func NewTransport(h host.Host, router routing.PeerRouting) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	proxy := transport.Proxy
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		if IsPeerAddress(req.URL.Host) || proxy == nil {
+			return nil, nil
+		}
+		return proxy(req)
+	}
+	transport.DialContext = func(ctx context.Context, network string, address string) (net.Conn, error) {
+		if !IsPeerAddress(address) {
+			return dialer.DialContext(ctx, network, address)
+		}
+		hostname, _, err := net.SplitHostPort(address)
+		if err != nil {
+			hostname = address
+		}
+		id, _ := peer.Decode(hostname)
+		if len(h.Peerstore().Addrs(id)) == 0 && router != nil {
+			info, err := router.FindPeer(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			h.Peerstore().AddAddrs(id, info.Addrs, peerstore.TempAddrTTL)
+		}
+		stream, err := h.NewStream(ctx, id, FileProtocol)
+		if err != nil {
+			return nil, err
+		}
+		return conn{stream}, nil
+	}
+	return transport
+}
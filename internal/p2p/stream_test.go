@@ -0,0 +1,119 @@
+package p2p
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/event"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+func newHost(t *testing.T) host.Host {
+	h, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { h.Close() })
+	return h
+}
+
+func TestHTTPOverStreams(t *testing.T) {
+	server := newHost(t)
+	consumer := newHost(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/whoami", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.RemoteAddr)
+	})
+	mux.HandleFunc("/data", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "data", time.Time{}, strings.NewReader("0123456789"))
+	})
+	listener := Listen(server)
+	defer listener.Close()
+	go http.Serve(listener, mux)
+
+	consumer.Peerstore().AddAddrs(server.ID(), server.Addrs(), peerstore.PermanentAddrTTL)
+	client := &http.Client{Transport: NewTransport(consumer, nil)}
+
+	resp, err := client.Get("http://" + server.ID().String() + "/whoami")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != consumer.ID().String() {
+		t.Errorf("expected the server to see peer %s, got %q", consumer.ID(), body)
+	}
+
+	// Ranges work as they do over TCP
+	req, _ := http.NewRequest(http.MethodGet, "http://"+server.ID().String()+"/data", nil)
+	req.Header.Set("Range", "bytes=3-5")
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent || string(body) != "345" {
+		t.Errorf("expected 206 with 345, got %d with %q", resp.StatusCode, body)
+	}
+
+	// Once the listener is closed, new streams are refused
+	listener.Close()
+	client.CloseIdleConnections()
+	if _, err := client.Get("http://" + server.ID().String() + "/whoami"); err == nil {
+		t.Error("expected request to fail once the listener is closed")
+	}
+}
+
+func TestIsPeerAddress(t *testing.T) {
+	h := newHost(t)
+	for address, want := range map[string]bool{
+		h.ID().String():         true,
+		h.ID().String() + ":80": true,
+		"localhost:8080":        false,
+		"127.0.0.1:8080":        false,
+		"":                      false,
+	} {
+		if IsPeerAddress(address) != want {
+			t.Errorf("expected IsPeerAddress(%q) to be %v", address, want)
+		}
+	}
+}
+
+func TestStatusFollowsReachability(t *testing.T) {
+	h := newHost(t)
+	status, err := NewStatus(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	emitter, err := h.EventBus().Emitter(new(event.EvtLocalReachabilityChanged))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer emitter.Close()
+	emitter.Emit(event.EvtLocalReachabilityChanged{Reachability: network.ReachabilityPrivate})
+	deadline := time.Now().Add(5 * time.Second)
+	for status.Reachability() != network.ReachabilityPrivate {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected reachability to become private, got %s", status.Reachability())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	relayed := ma.StringCast("/ip4/1.2.3.4/tcp/4001/p2p/" + h.ID().String() + "/p2p-circuit")
+	if !IsRelayed(relayed) || IsRelayed(h.Addrs()[0]) {
+		t.Error("expected only the circuit address to count as relayed")
+	}
+	if direct, _ := status.Addrs(); len(direct) == 0 {
+		t.Error("expected the listen address to be reported")
+	}
+}
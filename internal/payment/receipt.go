@@ -0,0 +1,149 @@
+package payment
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"orca-peer/internal/hash"
+	"sync"
+	"time"
+)
+
+// Headers used to agree on a paid transfer
+const (
+	HeaderTransferID = "X-Transfer-Id"
+	HeaderFileHash   = "X-File-Hash"
+	HeaderPublicKey  = "X-Public-Key"
+	HeaderPrice      = "X-Price-Per-MB"
+	HeaderInterval   = "X-Payment-Interval"
+)
+
+const MB = 1024 * 1024
+
+var (
+	ErrBadSignature    = errors.New("receipt signature does not match the transfer's key")
+	ErrWrongTransfer   = errors.New("receipt is for a different transfer or file")
+	ErrStaleReceipt    = errors.New("receipt does not pay for more than the previous one")
+	ErrUnderpaid       = errors.New("receipt amount does not cover its bytes at the agreed price")
+	ErrUnknownTransfer = errors.New("no payment session for transfer")
+	ErrPaymentTimeout  = errors.New("payment did not arrive in time")
+)
+
+/*
+A receipt is the consumer's promise to pay for the first Bytes bytes of a
+transfer. Receipts are cumulative, each new one replaces the previous one, so
+the producer only has to keep the latest.
+*/
+type Receipt struct {
+	TransferID string  `json:"transfer_id"`
+	FileHash   string  `json:"file_hash"`
+	Bytes      int64   `json:"bytes"`
+	Amount     float64 `json:"amount"`
+	Timestamp  string  `json:"timestamp"`
+}
+
+type SignedReceipt struct {
+	Receipt   []byte `json:"receipt"`
+	Signature []byte `json:"signature"`
+}
+
+// Open checks the signature and returns the receipt inside.
+func (signed *SignedReceipt) Open(publicKey *rsa.PublicKey) (*Receipt, error) {
+	if err := hash.VerifySignature(signed.Receipt, signed.Signature, publicKey); err != nil {
+		return nil, ErrBadSignature
+	}
+	var receipt Receipt
+	if err := json.Unmarshal(signed.Receipt, &receipt); err != nil {
+		return nil, err
+	}
+	return &receipt, nil
+}
+
+// TransferID reads which transfer a receipt is for without checking it, so
+// the session holding the key to check it with can be found.
+func (signed *SignedReceipt) TransferID() string {
+	var receipt Receipt
+	json.Unmarshal(signed.Receipt, &receipt)
+	return receipt.TransferID
+}
+
+// Cost is what size bytes cost at pricePerMB.
+func Cost(size int64, pricePerMB float64) float64 {
+	return float64(size) * pricePerMB / MB
+}
+
+// EncodePublicKey turns a key into something that fits in a header.
+func EncodePublicKey(publicKey *rsa.PublicKey) (string, error) {
+	pem, err := hash.ExportRsaPublicKeyAsPemStr(publicKey)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(pem), nil
+}
+
+func DecodePublicKey(header string) (*rsa.PublicKey, error) {
+	pem, err := base64.StdEncoding.DecodeString(header)
+	if err != nil {
+		return nil, err
+	}
+	return hash.ParseRsaPublicKeyFromPemStr(string(pem))
+}
+
+// Payer signs the receipts for one transfer on the consumer's side.
+type Payer struct {
+	TransferID string
+	FileHash   string
+	PricePerMB float64
+
+	mu   sync.Mutex
+	key  *rsa.PrivateKey
+	paid int64
+}
+
+func NewPayer(transferID string, fileHash string, pricePerMB float64, key *rsa.PrivateKey) *Payer {
+	return &Payer{TransferID: transferID, FileHash: fileHash, PricePerMB: pricePerMB, key: key}
+}
+
+// Paid returns how many bytes the receipts the holder accepted so far cover.
+func (payer *Payer) Paid() int64 {
+	payer.mu.Lock()
+	defer payer.mu.Unlock()
+	return payer.paid
+}
+
+// PayUpTo signs a receipt for the first size bytes of the transfer. It
+// returns nil if an accepted receipt already covers them. Nothing counts as
+// paid until Accepted is called, so a receipt that never reached the holder
+// is signed again on the next call.
+func (payer *Payer) PayUpTo(size int64) (*SignedReceipt, error) {
+	payer.mu.Lock()
+	defer payer.mu.Unlock()
+	if size <= payer.paid {
+		return nil, nil
+	}
+	receipt := Receipt{
+		TransferID: payer.TransferID,
+		FileHash:   payer.FileHash,
+		Bytes:      size,
+		Amount:     Cost(size, payer.PricePerMB),
+		Timestamp:  time.Now().Format(time.RFC3339),
+	}
+	data, err := json.Marshal(receipt)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := hash.SignFile(data, payer.key)
+	if err != nil {
+		return nil, err
+	}
+	return &SignedReceipt{Receipt: data, Signature: signature}, nil
+}
+
+// Accepted records that the holder accepted the receipt for the first size
+// bytes of the transfer.
+func (payer *Payer) Accepted(size int64) {
+	payer.mu.Lock()
+	defer payer.mu.Unlock()
+	payer.paid = max(payer.paid, size)
+}
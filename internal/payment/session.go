@@ -0,0 +1,235 @@
+package payment
+
+import (
+	"context"
+	"crypto/rsa"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultInterval   = 1024 * 1024
+	DefaultAbortAfter = 60 * time.Second
+	// Sessions nobody has used for this long are forgotten
+	sessionLifetime = 30 * time.Minute
+)
+
+type Config struct {
+	// How many bytes the consumer pays for at a time
+	Interval int64 `json:"interval"`
+	// How long a transfer stays paused waiting for a payment before it is
+	// aborted, in seconds
+	AbortAfter int `json:"abort_after"`
+}
+
+/*
+Session tracks what the consumer of one transfer has paid for and what the
+producer has sent. The producer calls Reserve before sending anything, which
+pauses the transfer until a receipt covers the bytes. Every transfer has its
+own session, so a payment for one transfer never lets another one continue.
+*/
+type Session struct {
+	ID         string
+	FileHash   string
+	PricePerMB float64
+
+	publicKey *rsa.PublicKey
+	mu        sync.Mutex
+	paid      chan struct{}
+	receipt   *Receipt
+	signed    *SignedReceipt
+	sent      int64
+	lastUsed  time.Time
+}
+
+// Free reports whether the transfer can go ahead without any receipts.
+func (session *Session) Free() bool {
+	return session.PricePerMB <= 0
+}
+
+// Apply checks a receipt from the consumer and, if it pays for more than the
+// previous one, lets the transfer continue up to its byte count.
+func (session *Session) Apply(signed *SignedReceipt) error {
+	if session.publicKey == nil {
+		return ErrBadSignature
+	}
+	receipt, err := signed.Open(session.publicKey)
+	if err != nil {
+		return err
+	}
+	if receipt.TransferID != session.ID || receipt.FileHash != session.FileHash {
+		return ErrWrongTransfer
+	}
+	// Allow for rounding, the consumer computes the amount the same way
+	if receipt.Amount+1e-9 < Cost(receipt.Bytes, session.PricePerMB) {
+		return ErrUnderpaid
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if session.receipt != nil && (receipt.Bytes <= session.receipt.Bytes || receipt.Amount < session.receipt.Amount) {
+		return ErrStaleReceipt
+	}
+	session.receipt = receipt
+	session.signed = signed
+	session.lastUsed = time.Now()
+	close(session.paid)
+	session.paid = make(chan struct{})
+	return nil
+}
+
+// Reserve waits until the consumer has paid for size more bytes and counts
+// them as sent. It gives up with ErrPaymentTimeout if no payment covering
+// them arrives within abortAfter.
+func (session *Session) Reserve(ctx context.Context, size int64, abortAfter time.Duration) error {
+	if session.Free() {
+		session.mu.Lock()
+		session.sent += size
+		session.mu.Unlock()
+		return nil
+	}
+	var deadline <-chan time.Time
+	paused := false
+	for {
+		session.mu.Lock()
+		if session.receipt != nil && session.sent+size <= session.receipt.Bytes {
+			session.sent += size
+			session.lastUsed = time.Now()
+			session.mu.Unlock()
+			if paused {
+				fmt.Printf("\nTransfer %s resumed\n> ", session.ID)
+			}
+			return nil
+		}
+		paid := session.paid
+		session.mu.Unlock()
+
+		if deadline == nil {
+			timer := time.NewTimer(abortAfter)
+			defer timer.Stop()
+			deadline = timer.C
+		}
+		if !paused && session.sent > 0 {
+			fmt.Printf("\nTransfer %s paused waiting for payment\n> ", session.ID)
+			paused = true
+		}
+		select {
+		case <-paid:
+		case <-deadline:
+			return ErrPaymentTimeout
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Sent returns how many bytes have been reserved so far.
+func (session *Session) Sent() int64 {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return session.sent
+}
+
+// LastReceipt is the latest receipt the consumer signed, or nil.
+func (session *Session) LastReceipt() (*Receipt, *SignedReceipt) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return session.receipt, session.signed
+}
+
+// Pricer decides what each file costs per MB.
+type Pricer interface {
+	Price(fileHash string) float64
+	// Requested is called for every transfer that has been approved
+	Requested(fileHash string)
+}
+
+// Manager keeps the payment session of every transfer this node is serving.
+type Manager struct {
+	Interval   int64
+	AbortAfter time.Duration
+	Pricer     Pricer
+
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+// NewManager returns a manager that charges what pricer asks for each file.
+func NewManager(config Config, pricer Pricer) *Manager {
+	manager := &Manager{
+		Interval:   config.Interval,
+		AbortAfter: time.Duration(config.AbortAfter) * time.Second,
+		Pricer:     pricer,
+		sessions:   make(map[string]*Session),
+	}
+	if manager.Interval <= 0 {
+		manager.Interval = DefaultInterval
+	}
+	if manager.AbortAfter <= 0 {
+		manager.AbortAfter = DefaultAbortAfter
+	}
+	return manager
+}
+
+// Price is what a transfer of fileHash opened now would cost per MB.
+func (manager *Manager) Price(fileHash string) float64 {
+	return manager.Pricer.Price(fileHash)
+}
+
+// Requested counts a transfer of fileHash toward its demand. It should only
+// be called once the transfer has been approved.
+func (manager *Manager) Requested(fileHash string) {
+	manager.Pricer.Requested(fileHash)
+}
+
+// Open starts a session for a transfer of fileHash at the current price,
+// which stays the same for the whole transfer. Receipts have to be signed
+// with publicKey, which may only be nil if the transfer is free.
+func (manager *Manager) Open(fileHash string, publicKey *rsa.PublicKey) *Session {
+	price := manager.Price(fileHash)
+	session := &Session{
+		ID:         uuid.NewString(),
+		FileHash:   fileHash,
+		PricePerMB: price,
+		publicKey:  publicKey,
+		paid:       make(chan struct{}),
+		lastUsed:   time.Now(),
+	}
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	for id, old := range manager.sessions {
+		old.mu.Lock()
+		expired := time.Since(old.lastUsed) > sessionLifetime
+		old.mu.Unlock()
+		if expired {
+			delete(manager.sessions, id)
+		}
+	}
+	manager.sessions[session.ID] = session
+	return session
+}
+
+func (manager *Manager) Get(id string) (*Session, bool) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	session, ok := manager.sessions[id]
+	return session, ok
+}
+
+func (manager *Manager) Close(id string) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	delete(manager.sessions, id)
+}
+
+// Apply hands a receipt to the session it belongs to.
+func (manager *Manager) Apply(signed *SignedReceipt) (*Session, error) {
+	session, ok := manager.Get(signed.TransferID())
+	if !ok {
+		return nil, ErrUnknownTransfer
+	}
+	return session, session.Apply(signed)
+}
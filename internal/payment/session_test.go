@@ -0,0 +1,152 @@
+package payment
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+	"time"
+)
+
+func testKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestSessionApply(t *testing.T) {
+	key := testKey(t)
+	manager := NewManager(Config{}, flatPrice(2))
+	session := manager.Open("file", &key.PublicKey)
+
+	payer := NewPayer(session.ID, "file", 2, key)
+	first, _ := payer.PayUpTo(MB)
+	if _, err := manager.Apply(first); err != nil {
+		t.Fatalf("expected receipt to be accepted, got %s", err)
+	}
+	if err := session.Apply(first); !errors.Is(err, ErrStaleReceipt) {
+		t.Errorf("expected replayed receipt to be stale, got %v", err)
+	}
+
+	cheap, _ := NewPayer(session.ID, "file", 1, key).PayUpTo(2 * MB)
+	if err := session.Apply(cheap); !errors.Is(err, ErrUnderpaid) {
+		t.Errorf("expected receipt below the price to be rejected, got %v", err)
+	}
+	otherFile, _ := NewPayer(session.ID, "other", 2, key).PayUpTo(2 * MB)
+	if err := session.Apply(otherFile); !errors.Is(err, ErrWrongTransfer) {
+		t.Errorf("expected receipt for another file to be rejected, got %v", err)
+	}
+	forged, _ := NewPayer(session.ID, "file", 2, testKey(t)).PayUpTo(2 * MB)
+	if err := session.Apply(forged); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("expected receipt signed by someone else to be rejected, got %v", err)
+	}
+	if _, err := manager.Apply(&SignedReceipt{Receipt: []byte(`{"transfer_id":"nope"}`)}); !errors.Is(err, ErrUnknownTransfer) {
+		t.Errorf("expected receipt for unknown transfer to be rejected, got %v", err)
+	}
+}
+
+func TestPayerSignsAgainUntilAccepted(t *testing.T) {
+	payer := NewPayer("transfer", "file", 2, testKey(t))
+	if first, _ := payer.PayUpTo(MB); first == nil {
+		t.Fatal("expected a receipt")
+	}
+	// The first receipt never reached the holder
+	if again, _ := payer.PayUpTo(MB); again == nil {
+		t.Fatal("expected a receipt that was not accepted to be signed again")
+	}
+	payer.Accepted(MB)
+	if payer.Paid() != MB {
+		t.Errorf("expected %d bytes to be paid, got %d", MB, payer.Paid())
+	}
+	if covered, _ := payer.PayUpTo(MB); covered != nil {
+		t.Errorf("expected no receipt for bytes that are already paid")
+	}
+}
+
+func TestReservePausesUntilPaid(t *testing.T) {
+	key := testKey(t)
+	manager := NewManager(Config{}, flatPrice(1))
+	session := manager.Open("file", &key.PublicKey)
+	payer := NewPayer(session.ID, "file", 1, key)
+
+	reserved := make(chan error, 1)
+	go func() {
+		reserved <- session.Reserve(context.Background(), 100, time.Second)
+	}()
+	select {
+	case <-reserved:
+		t.Fatal("expected unpaid bytes to wait for a receipt")
+	case <-time.After(20 * time.Millisecond):
+	}
+	receipt, _ := payer.PayUpTo(100)
+	if err := session.Apply(receipt); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-reserved; err != nil {
+		t.Fatalf("expected paid bytes to be reserved, got %s", err)
+	}
+
+	err := session.Reserve(context.Background(), 1, 20*time.Millisecond)
+	if !errors.Is(err, ErrPaymentTimeout) {
+		t.Errorf("expected transfer to abort without payment, got %v", err)
+	}
+}
+
+func TestSessionsDoNotUnblockEachOther(t *testing.T) {
+	key := testKey(t)
+	manager := NewManager(Config{}, flatPrice(1))
+	first := manager.Open("file", &key.PublicKey)
+	second := manager.Open("file", &key.PublicKey)
+
+	receipt, _ := NewPayer(first.ID, "file", 1, key).PayUpTo(1000)
+	if _, err := manager.Apply(receipt); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Reserve(context.Background(), 10, 20*time.Millisecond); !errors.Is(err, ErrPaymentTimeout) {
+		t.Errorf("expected payment for one transfer not to cover another, got %v", err)
+	}
+	if err := second.Apply(receipt); !errors.Is(err, ErrWrongTransfer) {
+		t.Errorf("expected receipt to be bound to its transfer, got %v", err)
+	}
+	if err := first.Reserve(context.Background(), 1000, time.Second); err != nil {
+		t.Errorf("expected paid transfer to go ahead, got %s", err)
+	}
+}
+
+// flatPrice charges the same for every file.
+type flatPrice float64
+
+func (flat flatPrice) Price(string) float64 { return float64(flat) }
+func (flat flatPrice) Requested(string)     {}
+
+type fixedPrices struct {
+	prices    map[string]float64
+	requested []string
+}
+
+func (fixed *fixedPrices) Price(fileHash string) float64 { return fixed.prices[fileHash] }
+func (fixed *fixedPrices) Requested(fileHash string) {
+	fixed.requested = append(fixed.requested, fileHash)
+}
+
+func TestOpenUsesPricer(t *testing.T) {
+	prices := &fixedPrices{prices: map[string]float64{"paid": 3}}
+	manager := NewManager(Config{}, prices)
+
+	if session := manager.Open("paid", nil); session.PricePerMB != 3 {
+		t.Errorf("expected the pricer's price, got %f", session.PricePerMB)
+	}
+	if session := manager.Open("free", nil); !session.Free() {
+		t.Errorf("expected a file without a price to be free")
+	}
+	if len(prices.requested) != 0 {
+		t.Errorf("expected opening a transfer not to count as a request, got %v", prices.requested)
+	}
+	manager.Requested("paid")
+	if len(prices.requested) != 1 {
+		t.Errorf("expected the approved transfer to count as a request, got %v", prices.requested)
+	}
+}
@@ -0,0 +1,142 @@
+package pricing
+
+import (
+	"fmt"
+	"orca-peer/internal/hash"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultWindow        = time.Hour
+	DefaultMaxMultiplier = 2
+)
+
+type Config struct {
+	// What every file costs per MB unless it has a price of its own
+	PricePerMB float64 `json:"price_per_mb"`
+	// Prices per MB by file hash
+	Files  map[string]float64 `json:"files"`
+	Demand DemandConfig       `json:"demand"`
+}
+
+/*
+DemandConfig raises the price of files that are requested often. Every request
+for a file within the last Window seconds beyond the first Threshold adds Step
+to the price multiplier, up to MaxMultiplier. A Step of 0 turns it off.
+*/
+type DemandConfig struct {
+	Window        int     `json:"window"`
+	Threshold     int     `json:"threshold"`
+	Step          float64 `json:"step"`
+	MaxMultiplier float64 `json:"max_multiplier"`
+}
+
+func (config Config) Validate() error {
+	if config.PricePerMB < 0 {
+		return fmt.Errorf("price per MB can not be negative")
+	}
+	for file, price := range config.Files {
+		if !hash.IsValidHash(file) {
+			return fmt.Errorf("files must be priced by their hash, %q is not one", file)
+		}
+		if price < 0 {
+			return fmt.Errorf("price of %s can not be negative", file)
+		}
+	}
+	demand := config.Demand
+	if demand.Window < 0 || demand.Threshold < 0 || demand.Step < 0 {
+		return fmt.Errorf("demand window, threshold and step can not be negative")
+	}
+	if demand.MaxMultiplier != 0 && demand.MaxMultiplier < 1 {
+		return fmt.Errorf("demand max multiplier can not be below 1")
+	}
+	return nil
+}
+
+/*
+Engine decides what this node charges for each file. The price of a file is
+its own price from the config, or the default price, times the demand
+multiplier. Requests only count toward demand once a transfer is approved, so
+peers asking for the price, or being turned away, do not raise it.
+*/
+type Engine struct {
+	config   Config
+	window   time.Duration
+	mu       sync.Mutex
+	requests map[string][]time.Time
+	now      func() time.Time
+}
+
+func NewEngine(config Config) (*Engine, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	engine := &Engine{
+		config:   config,
+		window:   time.Duration(config.Demand.Window) * time.Second,
+		requests: make(map[string][]time.Time),
+		now:      time.Now,
+	}
+	if engine.window == 0 {
+		engine.window = DefaultWindow
+	}
+	if engine.config.Demand.MaxMultiplier == 0 {
+		engine.config.Demand.MaxMultiplier = DefaultMaxMultiplier
+	}
+	return engine, nil
+}
+
+// Base is the price per MB of fileHash before demand is taken into account.
+func (engine *Engine) Base(fileHash string) float64 {
+	if price, ok := engine.config.Files[fileHash]; ok {
+		return price
+	}
+	return engine.config.PricePerMB
+}
+
+// Price is what fileHash costs per MB right now.
+func (engine *Engine) Price(fileHash string) float64 {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	return engine.Base(fileHash) * engine.multiplier(fileHash, engine.now())
+}
+
+// Requested counts a transfer of fileHash toward its demand.
+func (engine *Engine) Requested(fileHash string) {
+	if engine.config.Demand.Step == 0 {
+		return
+	}
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	now := engine.now()
+	engine.expire(fileHash, now)
+	engine.requests[fileHash] = append(engine.requests[fileHash], now)
+}
+
+// multiplier is the demand multiplier of fileHash at now. Callers must hold
+// engine.mu
+func (engine *Engine) multiplier(fileHash string, now time.Time) float64 {
+	demand := engine.config.Demand
+	engine.expire(fileHash, now)
+	extra := len(engine.requests[fileHash]) - demand.Threshold
+	if demand.Step == 0 || extra <= 0 {
+		return 1
+	}
+	return min(1+float64(extra)*demand.Step, demand.MaxMultiplier)
+}
+
+// expire forgets the requests of fileHash that left the window. Callers must
+// hold engine.mu
+func (engine *Engine) expire(fileHash string, now time.Time) {
+	requests := engine.requests[fileHash]
+	expired := 0
+	for expired < len(requests) && now.Sub(requests[expired]) >= engine.window {
+		expired++
+	}
+	if expired == len(requests) {
+		delete(engine.requests, fileHash)
+		return
+	}
+	engine.requests[fileHash] = requests[expired:]
+}
@@ -0,0 +1,74 @@
+package pricing
+
+import (
+	"math"
+	"orca-peer/internal/hash"
+	"testing"
+	"time"
+)
+
+func TestPrice(t *testing.T) {
+	popular := hash.HashChunk([]byte("popular"))
+	free := hash.HashChunk([]byte("free"))
+	other := hash.HashChunk([]byte("other"))
+	engine, err := NewEngine(Config{
+		PricePerMB: 2,
+		Files:      map[string]float64{popular: 4, free: 0},
+		Demand:     DemandConfig{Window: 60, Threshold: 2, Step: 0.25, MaxMultiplier: 1.5},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC)
+	engine.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		engine.Requested(popular)
+		engine.Requested(free)
+	}
+	tests := []struct {
+		file string
+		want float64
+	}{
+		// One request beyond the threshold
+		{popular, 5},
+		{free, 0},
+		{other, 2},
+	}
+	for _, test := range tests {
+		if got := engine.Price(test.file); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("price of %s: expected %f, got %f", test.file, test.want, got)
+		}
+	}
+
+	for i := 0; i < 5; i++ {
+		engine.Requested(popular)
+	}
+	if got := engine.Price(popular); got != 6 {
+		t.Errorf("expected the multiplier to stop at 1.5, got a price of %f", got)
+	}
+	now = now.Add(time.Minute)
+	if got := engine.Price(popular); got != 4 {
+		t.Errorf("expected requests outside the window to be forgotten, got a price of %f", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		valid  bool
+	}{
+		{"default", Config{PricePerMB: 1}, true},
+		{"negative price", Config{PricePerMB: -1}, false},
+		{"negative file price", Config{Files: map[string]float64{hash.HashChunk(nil): -1}}, false},
+		{"file name instead of hash", Config{Files: map[string]float64{"song.mp3": 1}}, false},
+		{"negative step", Config{Demand: DemandConfig{Step: -0.1}}, false},
+		{"multiplier below 1", Config{Demand: DemandConfig{Step: 0.1, MaxMultiplier: 0.5}}, false},
+	}
+	for _, test := range tests {
+		if err := test.config.Validate(); (err == nil) != test.valid {
+			t.Errorf("%s: expected valid %v, got %v", test.name, test.valid, err)
+		}
+	}
+}
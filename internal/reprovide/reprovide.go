@@ -0,0 +1,178 @@
+package reprovide
+
+import (
+	"context"
+	"fmt"
+	"orca-peer/internal/hash"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultInterval = time.Hour
+	DefaultTTL      = 6 * time.Hour
+	// How long a single announcement may take
+	announceTimeout = time.Minute
+)
+
+type Config struct {
+	// How often every stored file is announced again, in seconds
+	Interval int `json:"interval"`
+	// How long an announcement stays valid, in seconds. It has to be longer
+	// than the interval so records are refreshed before they expire.
+	TTL int `json:"ttl"`
+}
+
+func (config Config) Validate() error {
+	if config.Interval <= 0 {
+		return fmt.Errorf("reprovide interval must be positive")
+	}
+	if config.TTL <= config.Interval {
+		return fmt.Errorf("reprovide ttl must be longer than the interval")
+	}
+	return nil
+}
+
+// Announcer puts records for files in the network and takes them back.
+type Announcer interface {
+	Announce(ctx context.Context, fileHash string, ttl time.Duration) error
+	Withdraw(ctx context.Context, fileHash string) error
+}
+
+// Stats counts what the reprovider did since it started.
+type Stats struct {
+	Providing int       `json:"providing"`
+	Announced int       `json:"announced"`
+	Failed    int       `json:"failed"`
+	Withdrawn int       `json:"withdrawn"`
+	Runs      int       `json:"runs"`
+	LastRun   time.Time `json:"last_run"`
+}
+
+/*
+Reprovider announces every file in a DataStore again on an interval, so the
+records of files this node still holds never expire. Files that leave the store
+are withdrawn right away and are not refreshed anymore, so consumers stop being
+sent to this node for them.
+*/
+type Reprovider struct {
+	storage   *hash.DataStore
+	announcer Announcer
+	interval  time.Duration
+	ttl       time.Duration
+
+	removed chan string
+
+	mu        sync.Mutex
+	providing map[string]bool
+	stats     Stats
+}
+
+func NewReprovider(storage *hash.DataStore, announcer Announcer, config Config) *Reprovider {
+	reprovider := &Reprovider{
+		storage:   storage,
+		announcer: announcer,
+		interval:  time.Duration(config.Interval) * time.Second,
+		ttl:       time.Duration(config.TTL) * time.Second,
+		removed:   make(chan string, 64),
+		providing: make(map[string]bool),
+	}
+	storage.OnRemove(reprovider.fileRemoved)
+	return reprovider
+}
+
+// fileRemoved is called by the store with its lock held, so it only queues
+// the withdrawal. If the queue is full the next run withdraws the file.
+func (reprovider *Reprovider) fileRemoved(manifest *hash.Manifest) {
+	select {
+	case reprovider.removed <- manifest.Root:
+	default:
+	}
+}
+
+// Run announces every stored file, then again on every interval, until ctx
+// is done.
+func (reprovider *Reprovider) Run(ctx context.Context) {
+	ticker := time.NewTicker(reprovider.interval)
+	defer ticker.Stop()
+	reprovider.Reprovide(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reprovider.Reprovide(ctx)
+		case cid := <-reprovider.removed:
+			reprovider.withdraw(ctx, cid)
+		}
+	}
+}
+
+// Reprovide announces every file in the store and withdraws the ones that
+// were announced before but are gone now.
+func (reprovider *Reprovider) Reprovide(ctx context.Context) {
+	manifests, err := reprovider.storage.ListFiles()
+	if err != nil {
+		fmt.Println("Error listing files to reprovide:", err)
+		return
+	}
+	stored := make(map[string]bool, len(manifests))
+	for _, manifest := range manifests {
+		stored[manifest.Root] = true
+		announceCtx, cancel := context.WithTimeout(ctx, announceTimeout)
+		err := reprovider.announcer.Announce(announceCtx, manifest.Root, reprovider.ttl)
+		cancel()
+
+		reprovider.mu.Lock()
+		if err != nil {
+			reprovider.stats.Failed++
+		} else {
+			reprovider.stats.Announced++
+			reprovider.providing[manifest.Root] = true
+		}
+		reprovider.mu.Unlock()
+		if err != nil {
+			fmt.Printf("Error reproviding %s: %s\n", manifest.Root, err)
+		}
+	}
+
+	reprovider.mu.Lock()
+	gone := []string{}
+	for cid := range reprovider.providing {
+		if !stored[cid] {
+			gone = append(gone, cid)
+		}
+	}
+	reprovider.stats.Runs++
+	reprovider.stats.LastRun = time.Now()
+	reprovider.mu.Unlock()
+	for _, cid := range gone {
+		reprovider.withdraw(ctx, cid)
+	}
+}
+
+func (reprovider *Reprovider) withdraw(ctx context.Context, cid string) {
+	// Stop refreshing it even if the withdrawal fails, the record then
+	// expires on its own
+	reprovider.mu.Lock()
+	delete(reprovider.providing, cid)
+	reprovider.mu.Unlock()
+
+	withdrawCtx, cancel := context.WithTimeout(ctx, announceTimeout)
+	defer cancel()
+	if err := reprovider.announcer.Withdraw(withdrawCtx, cid); err != nil {
+		fmt.Printf("Error withdrawing %s: %s\n", cid, err)
+		return
+	}
+	reprovider.mu.Lock()
+	reprovider.stats.Withdrawn++
+	reprovider.mu.Unlock()
+}
+
+func (reprovider *Reprovider) Stats() Stats {
+	reprovider.mu.Lock()
+	defer reprovider.mu.Unlock()
+	stats := reprovider.stats
+	stats.Providing = len(reprovider.providing)
+	return stats
+}
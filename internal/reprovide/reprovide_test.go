@@ -0,0 +1,132 @@
+package reprovide
+
+import (
+	"context"
+	"errors"
+	"orca-peer/internal/hash"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeAnnouncer struct {
+	mu        sync.Mutex
+	announced map[string]int
+	withdrawn chan string
+	fail      map[string]bool
+}
+
+func newFakeAnnouncer() *fakeAnnouncer {
+	return &fakeAnnouncer{
+		announced: map[string]int{},
+		withdrawn: make(chan string, 16),
+		fail:      map[string]bool{},
+	}
+}
+
+func (fake *fakeAnnouncer) Announce(ctx context.Context, fileHash string, ttl time.Duration) error {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if fake.fail[fileHash] {
+		return errors.New("no peers")
+	}
+	fake.announced[fileHash]++
+	return nil
+}
+
+func (fake *fakeAnnouncer) Withdraw(ctx context.Context, fileHash string) error {
+	fake.withdrawn <- fileHash
+	return nil
+}
+
+func (fake *fakeAnnouncer) count(fileHash string) int {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return fake.announced[fileHash]
+}
+
+func TestReprovideAnnouncesStoredFiles(t *testing.T) {
+	storage := hash.NewDataStore(t.TempDir())
+	kept, _ := storage.PutFile([]byte("kept"))
+	failing, _ := storage.PutFile([]byte("failing"))
+	fake := newFakeAnnouncer()
+	fake.fail[failing] = true
+	reprovider := NewReprovider(storage, fake, Config{Interval: 60, TTL: 120})
+
+	reprovider.Reprovide(context.Background())
+	reprovider.Reprovide(context.Background())
+	if fake.count(kept) != 2 {
+		t.Errorf("expected the file to be announced on every run, got %d", fake.count(kept))
+	}
+	stats := reprovider.Stats()
+	if stats.Providing != 1 || stats.Announced != 2 || stats.Failed != 2 || stats.Runs != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestReprovideWithdrawsRemovedFiles(t *testing.T) {
+	storage := hash.NewDataStore(t.TempDir())
+	cid, _ := storage.PutFile([]byte("removed later"))
+	fake := newFakeAnnouncer()
+	reprovider := NewReprovider(storage, fake, Config{Interval: 3600, TTL: 7200})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go reprovider.Run(ctx)
+	for fake.count(cid) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := storage.RemoveFile(cid); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case withdrawn := <-fake.withdrawn:
+		if withdrawn != cid {
+			t.Errorf("expected %s to be withdrawn, got %s", cid, withdrawn)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("removed file was not withdrawn")
+	}
+
+	// A later run must not announce it again
+	reprovider.Reprovide(ctx)
+	if fake.count(cid) != 1 {
+		t.Errorf("expected removed file not to be announced again, got %d", fake.count(cid))
+	}
+}
+
+func TestReprovideWithdrawsEvictedFiles(t *testing.T) {
+	storage := hash.NewDataStore(t.TempDir())
+	cid, _ := storage.PutFile([]byte("evicted"))
+	fake := newFakeAnnouncer()
+	reprovider := NewReprovider(storage, fake, Config{Interval: 3600, TTL: 7200})
+	reprovider.Reprovide(context.Background())
+
+	// Eviction happens inside the store, the withdrawal is queued for Run
+	storage.DriveEvict()
+	reprovider.Reprovide(context.Background())
+	select {
+	case withdrawn := <-fake.withdrawn:
+		if withdrawn != cid {
+			t.Errorf("expected %s to be withdrawn, got %s", cid, withdrawn)
+		}
+	default:
+		t.Fatal("evicted file was not withdrawn")
+	}
+	if stats := reprovider.Stats(); stats.Providing != 0 || stats.Withdrawn != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	if err := (Config{Interval: 60, TTL: 60}).Validate(); err == nil {
+		t.Error("expected a ttl no longer than the interval to be refused")
+	}
+	if err := (Config{Interval: 0, TTL: 60}).Validate(); err == nil {
+		t.Error("expected a zero interval to be refused")
+	}
+	if err := (Config{Interval: 60, TTL: 120}).Validate(); err != nil {
+		t.Error(err)
+	}
+}
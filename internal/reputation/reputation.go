@@ -0,0 +1,242 @@
+package reputation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	scoresName      = "scores.json"
+	DefaultHalfLife = 7 * 24 * time.Hour
+)
+
+// Event is something a peer did that changes how much it is trusted.
+type Event string
+
+const (
+	// Seen from the downloading side
+	ServedPiece  Event = "served_piece"
+	CorruptPiece Event = "corrupt_piece"
+	TimedOut     Event = "timed_out"
+	Failed       Event = "failed"
+	// Seen from the serving side
+	Paid           Event = "paid"
+	BadReceipt     Event = "bad_receipt"
+	PaymentTimeout Event = "payment_timeout"
+	// Transactions sent to this node
+	ValidTransaction   Event = "valid_transaction"
+	InvalidTransaction Event = "invalid_transaction"
+)
+
+// Weights is how much each event adds to a score. Sending bad data or
+// forged payments costs far more than an honest peer can lose by being slow.
+var Weights = map[Event]float64{
+	ServedPiece:        1,
+	CorruptPiece:       -10,
+	TimedOut:           -2,
+	Failed:             -1,
+	Paid:               1,
+	BadReceipt:         -10,
+	PaymentTimeout:     -5,
+	ValidTransaction:   2,
+	InvalidTransaction: -20,
+}
+
+type Config struct {
+	// After how many seconds a score has lost half its weight
+	HalfLife int `json:"half_life"`
+}
+
+func (config Config) Validate() error {
+	if config.HalfLife < 0 {
+		return fmt.Errorf("reputation half life can not be negative")
+	}
+	return nil
+}
+
+func (config Config) halfLife() time.Duration {
+	if config.HalfLife == 0 {
+		return DefaultHalfLife
+	}
+	return time.Duration(config.HalfLife) * time.Second
+}
+
+// Entry is the standing of one peer. Score is as of Updated, Events counts
+// everything the peer did since it was first seen.
+type Entry struct {
+	Peer    string        `json:"peer"`
+	Score   float64       `json:"score"`
+	Updated time.Time     `json:"updated"`
+	Events  map[Event]int `json:"events"`
+}
+
+/*
+Book keeps a score for every peer this node dealt with. Events add their weight
+to the score, and scores decay toward zero with the configured half life, so
+old mistakes are forgiven and old merit has to be earned again. Peers are
+named by peer ID, or by host for peers only known by an ip:port, see Key. The
+book is written to disk after every change.
+*/
+type Book struct {
+	mu       sync.Mutex
+	path     string
+	halfLife time.Duration
+	entries  map[string]*Entry
+	now      func() time.Time
+}
+
+// Open loads the scores in dir, starting empty if there are none yet.
+func Open(dir string, config Config) (*Book, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	book := &Book{
+		path:     filepath.Join(dir, scoresName),
+		halfLife: config.halfLife(),
+		entries:  make(map[string]*Entry),
+		now:      time.Now,
+	}
+	data, err := os.ReadFile(book.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return book, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries := []*Entry{}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", book.path, err)
+	}
+	for _, entry := range entries {
+		book.entries[entry.Peer] = entry
+	}
+	return book, nil
+}
+
+/*
+Key names the peer behind an address. Peer IDs are used as they are. For an
+ip:port the port is dropped, since a peer that connects to us comes from a new
+port every time.
+*/
+func Key(address string) string {
+	if _, err := peer.Decode(address); err == nil {
+		return address
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	return host
+}
+
+// decayed is the score of entry at now. Callers must hold book.mu
+func (book *Book) decayed(entry *Entry, now time.Time) float64 {
+	elapsed := now.Sub(entry.Updated)
+	if elapsed <= 0 {
+		return entry.Score
+	}
+	return entry.Score * math.Pow(0.5, float64(elapsed)/float64(book.halfLife))
+}
+
+// Record adds event to the score of the peer at address.
+func (book *Book) Record(address string, event Event) error {
+	if book == nil || address == "" {
+		return nil
+	}
+	key := Key(address)
+	book.mu.Lock()
+	defer book.mu.Unlock()
+	now := book.now()
+	entry, ok := book.entries[key]
+	if !ok {
+		entry = &Entry{Peer: key, Updated: now, Events: make(map[Event]int)}
+		book.entries[key] = entry
+	}
+	entry.Score = book.decayed(entry, now) + Weights[event]
+	entry.Updated = now
+	entry.Events[event]++
+	return book.save()
+}
+
+// save writes every entry to disk. Callers must hold book.mu
+func (book *Book) save() error {
+	entries := make([]*Entry, 0, len(book.entries))
+	for _, entry := range book.entries {
+		entries = append(entries, entry)
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first so a crash never leaves half a book
+	if err := os.WriteFile(book.path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(book.path+".tmp", book.path)
+}
+
+// Score is the current score of the peer at address. Unknown peers have 0.
+func (book *Book) Score(address string) float64 {
+	if book == nil {
+		return 0
+	}
+	book.mu.Lock()
+	defer book.mu.Unlock()
+	entry, ok := book.entries[Key(address)]
+	if !ok {
+		return 0
+	}
+	return book.decayed(entry, book.now())
+}
+
+// Ranking returns every known peer with its current score, best first.
+func (book *Book) Ranking() []Entry {
+	book.mu.Lock()
+	defer book.mu.Unlock()
+	now := book.now()
+	ranking := make([]Entry, 0, len(book.entries))
+	for _, entry := range book.entries {
+		current := *entry
+		current.Score = book.decayed(entry, now)
+		current.Events = make(map[Event]int, len(entry.Events))
+		for event, count := range entry.Events {
+			current.Events[event] = count
+		}
+		ranking = append(ranking, current)
+	}
+	sort.Slice(ranking, func(i, j int) bool {
+		if ranking[i].Score != ranking[j].Score {
+			return ranking[i].Score > ranking[j].Score
+		}
+		return ranking[i].Peer < ranking[j].Peer
+	})
+	return ranking
+}
+
+// Rank orders addresses by the score of their peers, best first. Addresses
+// with the same score keep their order.
+func (book *Book) Rank(addresses []string) []string {
+	ranked := append([]string{}, addresses...)
+	scores := make(map[string]float64, len(ranked))
+	for _, address := range ranked {
+		scores[address] = book.Score(address)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return scores[ranked[i]] > scores[ranked[j]]
+	})
+	return ranked
+}
@@ -0,0 +1,79 @@
+package reputation
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func openBook(t *testing.T, dir string, now *time.Time) *Book {
+	book, err := Open(dir, Config{HalfLife: 3600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	book.now = func() time.Time { return *now }
+	return book
+}
+
+func TestScoresDecayAndPersist(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC)
+	book := openBook(t, dir, &now)
+
+	for i := 0; i < 4; i++ {
+		book.Record("10.0.0.1:5000", ServedPiece)
+	}
+	book.Record("10.0.0.2:6000", CorruptPiece)
+	// The port a peer connects from does not matter
+	if score := book.Score("10.0.0.1:41234"); score != 4 {
+		t.Errorf("expected a score of 4, got %f", score)
+	}
+
+	// One half life later, everything counts half
+	now = now.Add(time.Hour)
+	if score := book.Score("10.0.0.1"); math.Abs(score-2) > 1e-9 {
+		t.Errorf("expected the score to halve, got %f", score)
+	}
+	book.Record("10.0.0.1", ServedPiece)
+	if score := book.Score("10.0.0.1"); math.Abs(score-3) > 1e-9 {
+		t.Errorf("expected new events on top of the decayed score, got %f", score)
+	}
+
+	reopened := openBook(t, dir, &now)
+	ranking := reopened.Ranking()
+	if len(ranking) != 2 || ranking[0].Peer != "10.0.0.1" || ranking[1].Peer != "10.0.0.2" {
+		t.Fatalf("unexpected ranking %+v", ranking)
+	}
+	if ranking[0].Events[ServedPiece] != 5 || math.Abs(ranking[1].Score+5) > 1e-9 {
+		t.Errorf("scores were not persisted, got %+v", ranking)
+	}
+}
+
+func TestRankKeepsOrderOfEqualPeers(t *testing.T) {
+	now := time.Now()
+	book := openBook(t, t.TempDir(), &now)
+	book.Record("good:1", Paid)
+	book.Record("bad:1", PaymentTimeout)
+
+	ranked := book.Rank([]string{"bad:1", "new-a:1", "good:1", "new-b:1"})
+	want := []string{"good:1", "new-a:1", "new-b:1", "bad:1"}
+	for i := range want {
+		if ranked[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, ranked)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	id := "QmS6bES2qGSCN1vTmxEjZGDwwLw5Lsm2ToAcpzf97S7BnR"
+	for address, want := range map[string]string{
+		id:               id,
+		"10.0.0.1:5000":  "10.0.0.1",
+		"[::1]:5000":     "::1",
+		"no-port-at-all": "no-port-at-all",
+	} {
+		if got := Key(address); got != want {
+			t.Errorf("Key(%q) = %q, want %q", address, got, want)
+		}
+	}
+}
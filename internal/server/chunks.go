@@ -0,0 +1,114 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"orca-peer/internal/approval"
+	"orca-peer/internal/hash"
+	"orca-peer/internal/payment"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// How long an accepted manifest request lets the requester fetch chunks
+const grantLifetime = 30 * time.Minute
+
+// A grant is handed out with a manifest once the request for a file has been
+// accepted. Chunks are only served to requests that carry a grant for a file
+// containing that chunk, so nobody can skip the approval policy by asking for
+// chunks directly.
+type grant struct {
+	manifest *hash.Manifest
+	session  *payment.Session
+	expires  time.Time
+}
+
+type grantTable struct {
+	mu     sync.Mutex
+	grants map[string]*grant
+}
+
+func newGrantTable() *grantTable {
+	return &grantTable{grants: make(map[string]*grant)}
+}
+
+// add grants access to the chunks of manifest under the id of the transfer's
+// payment session.
+func (table *grantTable) add(manifest *hash.Manifest, session *payment.Session) {
+	table.mu.Lock()
+	defer table.mu.Unlock()
+	now := time.Now()
+	for id, g := range table.grants {
+		if now.After(g.expires) {
+			delete(table.grants, id)
+		}
+	}
+	table.grants[session.ID] = &grant{manifest: manifest, session: session, expires: now.Add(grantLifetime)}
+}
+
+// allows returns the payment session of transfer id if it covers chunkHash.
+func (table *grantTable) allows(id string, chunkHash string) (*payment.Session, bool) {
+	table.mu.Lock()
+	defer table.mu.Unlock()
+	g, ok := table.grants[id]
+	if !ok || time.Now().After(g.expires) || !g.manifest.HasChunk(chunkHash) {
+		return nil, false
+	}
+	return g.session, true
+}
+
+// sendManifest answers GET /manifest/:cid with the chunk list of a stored
+// file and a transfer id in the X-Transfer-Id header for fetching its chunks.
+// The payment terms for the chunks are sent in the same headers.
+func (server *Server) sendManifest(w http.ResponseWriter, r *http.Request) {
+	cid := r.URL.Path[len("/manifest/"):]
+	manifest, err := server.storage.GetManifest(cid)
+	if err != nil {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+
+	session, ok := server.openSession(w, r, cid)
+	if !ok {
+		return
+	}
+	request := approval.Request{Kind: approval.SendFile, Peer: r.RemoteAddr, FileHash: cid, Size: manifest.Size}
+	if !approval.Approve(r.Context(), server.policy, request) {
+		server.payments.Close(session.ID)
+		http.Error(w, fmt.Sprintf("Client declined to send file '%s'.", cid), http.StatusUnauthorized)
+		return
+	}
+
+	server.payments.Requested(cid)
+	server.grants.add(manifest, session)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(manifest)
+}
+
+// sendChunk answers GET /chunk/:hash?transfer=id with the raw chunk.
+func (server *Server) sendChunk(w http.ResponseWriter, r *http.Request) {
+	chunkHash := r.URL.Path[len("/chunk/"):]
+	if !hash.IsValidHash(chunkHash) {
+		http.Error(w, "Invalid chunk hash", http.StatusBadRequest)
+		return
+	}
+	session, ok := server.grants.allows(r.URL.Query().Get("transfer"), chunkHash)
+	if !ok {
+		http.Error(w, "No accepted request covers this chunk", http.StatusForbidden)
+		return
+	}
+	data, err := server.storage.GetChunk(chunkHash)
+	if err != nil {
+		http.Error(w, "Chunk not found", http.StatusNotFound)
+		return
+	}
+	if err := server.reserve(r.Context(), r.RemoteAddr, session, int64(len(data))); err != nil {
+		http.Error(w, err.Error(), http.StatusPaymentRequired)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Write(data)
+}
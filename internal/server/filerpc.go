@@ -0,0 +1,200 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"orca-peer/internal/approval"
+	orcaClient "orca-peer/internal/client"
+	pb "orca-peer/internal/fileshare"
+	"orca-peer/internal/payment"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	grpcPeer "google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type RPCConfig struct {
+	// host:port the file calls of file_share.proto are served on, none if
+	// empty
+	Listen string `json:"listen"`
+}
+
+// fileService answers the file calls of file_share.proto with the files of a
+// Server, under the same approval policy and payments as its HTTP routes.
+type fileService struct {
+	pb.UnimplementedFileShareServer
+	server *Server
+}
+
+// serveRPC answers file calls on address until the listener fails.
+func (server *Server) serveRPC(address string) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		fmt.Println("Error listening for gRPC:", err)
+		return
+	}
+	grpcServer := grpc.NewServer(grpc.InitialWindowSize(orcaClient.RPCWindow), grpc.InitialConnWindowSize(orcaClient.RPCWindow))
+	pb.RegisterFileShareServer(grpcServer, &fileService{server: server})
+	fmt.Printf("Serving files over gRPC on %s...\n", listener.Addr())
+	if err := grpcServer.Serve(listener); err != nil {
+		fmt.Println("Error serving gRPC:", err)
+	}
+}
+
+// caller is the address the call in ctx came from, which is what approval
+// and reputation know peers by.
+func caller(ctx context.Context) string {
+	from, ok := grpcPeer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	return from.Addr.String()
+}
+
+func incomingHeader(ctx context.Context, key string) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+/*
+SendFile streams the manifest of a stored file and then its chunks in order.
+The payment terms are sent in the header metadata, the same headers sendFile
+answers with, and each chunk is only sent once the receipts of the consumer
+cover it. The consumer pays with SendReceipt.
+*/
+func (service *fileService) SendFile(file *pb.SendFileRequest, stream pb.FileShare_SendFileServer) error {
+	server := service.server
+	ctx := stream.Context()
+	peer := caller(ctx)
+	cid := file.GetCid()
+	manifest, err := server.storage.GetManifest(cid)
+	if err != nil {
+		return status.Error(codes.NotFound, "file not found")
+	}
+
+	publicKey, err := payment.DecodePublicKey(incomingHeader(ctx, payment.HeaderPublicKey))
+	if price := server.payments.Price(cid); err != nil && price > 0 {
+		return status.Errorf(codes.FailedPrecondition, "file costs %f per MB, send the key receipts are signed with in %s", price, payment.HeaderPublicKey)
+	}
+	session := server.payments.Open(cid, publicKey)
+	defer server.closeSession(session)
+	header := metadata.Pairs(
+		payment.HeaderTransferID, session.ID,
+		payment.HeaderFileHash, cid,
+		payment.HeaderPrice, strconv.FormatFloat(session.PricePerMB, 'f', -1, 64),
+		payment.HeaderInterval, strconv.FormatInt(server.payments.Interval, 10),
+	)
+	if err := stream.SendHeader(header); err != nil {
+		return err
+	}
+
+	request := approval.Request{Kind: approval.SendFile, Peer: peer, FileHash: cid, Size: manifest.Size}
+	if !approval.Approve(ctx, server.policy, request) {
+		return status.Errorf(codes.PermissionDenied, "declined to send file '%s'", cid)
+	}
+	server.payments.Requested(cid)
+	if err := stream.Send(&pb.FileChunk{Manifest: orcaClient.ManifestToProto(manifest, "")}); err != nil {
+		return err
+	}
+	for i, chunkHash := range manifest.Chunks {
+		data, err := server.storage.GetChunk(chunkHash)
+		if err != nil {
+			return status.Errorf(codes.DataLoss, "chunk %d is missing", i)
+		}
+		if err := server.reserve(ctx, peer, session, int64(len(data))); err != nil {
+			return status.Error(codes.Aborted, err.Error())
+		}
+		// Send blocks while the consumer's window is full, so a slow
+		// consumer slows the transfer down instead of filling memory
+		if err := stream.Send(&pb.FileChunk{Index: int64(i), Hash: chunkHash, Data: data}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+SendFileToStore stores a file sent the way SendFile sends one: the manifest
+first, then every chunk in order. Every chunk is checked against the manifest
+before it is stored, and the file is only added once all of them arrived.
+*/
+func (service *fileService) SendFileToStore(stream pb.FileShare_SendFileToStoreServer) error {
+	server := service.server
+	ctx := stream.Context()
+	first, err := stream.Recv()
+	if err == io.EOF || (err == nil && first.GetManifest() == nil) {
+		return status.Error(codes.InvalidArgument, "the first message must carry the manifest")
+	}
+	if err != nil {
+		return err
+	}
+	name := first.GetManifest().GetFileName()
+	manifest := orcaClient.ManifestFromProto(first.GetManifest())
+	if err := manifest.Verify(); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	request := approval.Request{Kind: approval.StoreFile, Peer: caller(ctx), FileHash: manifest.Root, FileName: name, Size: manifest.Size}
+	if !approval.Approve(ctx, server.policy, request) {
+		return status.Errorf(codes.PermissionDenied, "declined to store file '%s'", name)
+	}
+	for i := 0; ; i++ {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			if i != len(manifest.Chunks) {
+				return status.Errorf(codes.InvalidArgument, "stream ended after %d of %d chunks", i, len(manifest.Chunks))
+			}
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if chunk.GetIndex() != int64(i) {
+			return status.Errorf(codes.InvalidArgument, "expected chunk %d, got %d", i, chunk.GetIndex())
+		}
+		if err := manifest.VerifySentChunk(i, chunk.GetHash(), chunk.GetData()); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		if _, err := server.storage.PutChunk(chunk.GetData()); err != nil {
+			return status.Errorf(codes.Internal, "storing chunk %d: %s", i, err)
+		}
+	}
+	if err := server.storage.PutManifest(manifest); err != nil {
+		return status.Errorf(codes.Internal, "storing manifest: %s", err)
+	}
+
+	fmt.Printf("\nStored file %s hash %s!\n> ", name, manifest.Root)
+	return stream.SendAndClose(&pb.StorageACKResponse{
+		IsAcknowledged: true,
+		FileName:       name,
+		FileHash:       manifest.Root,
+		FileByteSize:   manifest.Size,
+	})
+}
+
+// SendReceipt pays for a running SendFile, like POST /sendReceipt.
+func (service *fileService) SendReceipt(ctx context.Context, receipt *pb.Receipt) (*emptypb.Empty, error) {
+	var signed payment.SignedReceipt
+	if err := json.Unmarshal(receipt.GetSignedReceipt(), &signed); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "failed to parse receipt")
+	}
+	err := service.server.applyReceipt(caller(ctx), &signed)
+	switch {
+	case errors.Is(err, payment.ErrUnknownTransfer):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &emptypb.Empty{}, nil
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net"
+	"testing"
+
+	orcaClient "orca-peer/internal/client"
+	pb "orca-peer/internal/fileshare"
+	"orca-peer/internal/hash"
+	"orca-peer/internal/payment"
+	"orca-peer/internal/pricing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// startRPC serves the file calls of server and returns a client for them.
+func startRPC(t *testing.T, server *Server) pb.FileShareClient {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpcServer := grpc.NewServer()
+	pb.RegisterFileShareServer(grpcServer, &fileService{server: server})
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	address := listener.Addr().String()
+	rpc, conn, err := orcaClient.DialRPC(&address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return rpc
+}
+
+func TestRPCStoreAndSendFile(t *testing.T) {
+	server, _ := newTestServer(t, []byte("already stored"))
+	rpc := startRPC(t, server)
+	ctx := context.Background()
+	data := make([]byte, 2*hash.ChunkSize+100)
+	rand.Read(data)
+
+	ack, err := orcaClient.SendFileToStore(ctx, rpc, "upload.bin", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ack.IsAcknowledged || ack.FileName != "upload.bin" || ack.FileByteSize != int64(len(data)) {
+		t.Errorf("unexpected acknowledgement %v", ack)
+	}
+	if stored, err := server.storage.GetFile(ack.FileHash); err != nil || !bytes.Equal(stored, data) {
+		t.Fatalf("expected the upload to be stored, got %s", err)
+	}
+
+	var received bytes.Buffer
+	manifest, err := orcaClient.NewClient(t.TempDir(), nil).ReceiveFile(ctx, rpc, ack.FileHash, &received)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Root != ack.FileHash || !bytes.Equal(received.Bytes(), data) {
+		t.Errorf("received file does not match the upload")
+	}
+
+	_, err = orcaClient.NewClient(t.TempDir(), nil).ReceiveFile(ctx, rpc, hash.HashChunk([]byte("missing")), &received)
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected a missing file to be %s, got %v", codes.NotFound, err)
+	}
+}
+
+func TestRPCPaidSendFile(t *testing.T) {
+	data := make([]byte, hash.ChunkSize+10)
+	rand.Read(data)
+	server, cid := newTestServer(t, data)
+	// Receipts cover less than a chunk at a time
+	server.payments = payment.NewManager(payment.Config{Interval: 64 * 1024, AbortAfter: 1}, newPricer(t, pricing.Config{PricePerMB: 5}))
+	rpc := startRPC(t, server)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var received bytes.Buffer
+	_, err = orcaClient.NewClient(t.TempDir(), nil).ReceiveFile(context.Background(), rpc, cid, &received)
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("expected a client that cannot pay to be refused, got %v", err)
+	}
+	received.Reset()
+	if _, err := orcaClient.NewClient(t.TempDir(), key).ReceiveFile(context.Background(), rpc, cid, &received); err != nil {
+		t.Fatalf("expected paid download to succeed, got %s", err)
+	}
+	if !bytes.Equal(received.Bytes(), data) {
+		t.Errorf("paid download does not match original")
+	}
+}
+
+func TestRPCStoreRejectsBadChunks(t *testing.T) {
+	server, _ := newTestServer(t, []byte("already stored"))
+	rpc := startRPC(t, server)
+	data := make([]byte, hash.ChunkSize+10)
+	rand.Read(data)
+	manifest, chunks, err := hash.BuildManifest(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := append([]byte{}, chunks[1]...)
+	tampered[0]++
+
+	tests := []struct {
+		name     string
+		messages []*pb.FileChunk
+	}{
+		{"no manifest", []*pb.FileChunk{{Index: 0, Hash: manifest.Chunks[0], Data: chunks[0]}}},
+		{"tampered chunk", []*pb.FileChunk{
+			{Manifest: orcaClient.ManifestToProto(manifest, "bad")},
+			{Index: 0, Hash: manifest.Chunks[0], Data: chunks[0]},
+			{Index: 1, Hash: manifest.Chunks[1], Data: tampered},
+		}},
+		{"out of order", []*pb.FileChunk{
+			{Manifest: orcaClient.ManifestToProto(manifest, "bad")},
+			{Index: 1, Hash: manifest.Chunks[1], Data: chunks[1]},
+		}},
+		{"missing chunk", []*pb.FileChunk{
+			{Manifest: orcaClient.ManifestToProto(manifest, "bad")},
+			{Index: 0, Hash: manifest.Chunks[0], Data: chunks[0]},
+		}},
+	}
+	for _, test := range tests {
+		stream, err := rpc.SendFileToStore(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, message := range test.messages {
+			if stream.Send(message) != nil {
+				break
+			}
+		}
+		if _, err := stream.CloseAndRecv(); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s: expected %s, got %v", test.name, codes.InvalidArgument, err)
+		}
+	}
+	if _, err := server.storage.GetManifest(manifest.Root); err == nil {
+		t.Errorf("expected a file with bad chunks not to be stored")
+	}
+}
@@ -0,0 +1,182 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/libp2p/go-libp2p"
+	dht "github.com/libp2p/go-libp2p-kad-dht"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/p2p/net/connmgr"
+	"github.com/multiformats/go-multiaddr"
+)
+
+const (
+	ModeServer = "server"
+	ModeClient = "client"
+	ModeAuto   = "auto"
+)
+
+var DefaultListenAddrs = []string{
+	"/ip4/0.0.0.0/tcp/44981",
+	"/ip4/0.0.0.0/udp/44981/quic-v1",
+}
+
+const (
+	DefaultLowWater  = 100
+	DefaultHighWater = 400
+	// Connections younger than this are never closed by the connection
+	// manager
+	connectionGracePeriod = time.Minute
+)
+
+var DefaultBootstrapPeers = []string{
+	"/ip4/194.113.75.165/tcp/44981/p2p/QmS6bES2qGSCN1vTmxEjZGDwwLw5Lsm2ToAcpzf97S7BnR",
+}
+
+/*
+NetworkConfig says how this node joins the network. An empty bootstrap list
+starts a new network that other nodes can bootstrap from. In client mode the
+node uses the DHT without answering queries for others, which suits nodes that
+can not be reached from outside.
+
+Nodes behind a NAT find out that they are not reachable through AutoNAT. With
+AutoRelay they then reserve a slot on a relay and advertise the relayed
+address, and hole punching upgrades relayed connections to direct ones where
+the routers allow it. Relays are the static relays, or the bootstrap peers if
+there are none. Public nodes can offer the relay and AutoNAT services to
+others.
+*/
+type NetworkConfig struct {
+	ListenAddrs    []string `json:"listen_addrs"`
+	BootstrapPeers []string `json:"bootstrap_peers"`
+	Mode           string   `json:"mode"`
+
+	PortMap      bool     `json:"port_map"`
+	AutoRelay    bool     `json:"auto_relay"`
+	StaticRelays []string `json:"static_relays"`
+	HolePunching bool     `json:"hole_punching"`
+	RelayService bool     `json:"relay_service"`
+	NATService   bool     `json:"nat_service"`
+
+	// The connection manager closes connections down to LowWater once there
+	// are more than HighWater. Zero means the default.
+	LowWater  int `json:"low_water"`
+	HighWater int `json:"high_water"`
+
+	// Find nodes on the local network through mDNS, which needs no
+	// bootstrap peer
+	MDNS bool `json:"mdns"`
+
+	// host:port other nodes reach the HTTP server of this node at. Empty
+	// means they reach it by peer ID only.
+	PublicAddress string `json:"public_address"`
+}
+
+func (config NetworkConfig) Validate() error {
+	if len(config.ListenAddrs) == 0 {
+		return fmt.Errorf("at least one listen address is needed")
+	}
+	for _, address := range config.ListenAddrs {
+		if _, err := multiaddr.NewMultiaddr(address); err != nil {
+			return fmt.Errorf("invalid listen address %q: %w", address, err)
+		}
+	}
+	if _, err := config.bootstrapPeers(); err != nil {
+		return err
+	}
+	if _, err := config.dhtMode(); err != nil {
+		return err
+	}
+	if _, err := parsePeers(config.StaticRelays); err != nil {
+		return err
+	}
+	if low, high := config.watermarks(); low < 0 || high < low {
+		return fmt.Errorf("connection watermarks must be positive with low_water <= high_water")
+	}
+	if config.PublicAddress != "" {
+		if host, _, err := net.SplitHostPort(config.PublicAddress); err != nil || host == "" {
+			return fmt.Errorf("public address %q must be a host:port", config.PublicAddress)
+		}
+	}
+	return nil
+}
+
+// hostOptions turns the connection and NAT settings into options for the
+// libp2p host.
+func (config NetworkConfig) hostOptions() ([]libp2p.Option, error) {
+	low, high := config.watermarks()
+	manager, err := connmgr.NewConnManager(low, high, connmgr.WithGracePeriod(connectionGracePeriod))
+	if err != nil {
+		return nil, err
+	}
+	options := []libp2p.Option{libp2p.ConnectionManager(manager), libp2p.EnableRelay()}
+	if config.PortMap {
+		options = append(options, libp2p.NATPortMap())
+	}
+	if config.AutoRelay {
+		relays, err := parsePeers(config.StaticRelays)
+		if err != nil {
+			return nil, err
+		}
+		if len(relays) == 0 {
+			relays, err = config.bootstrapPeers()
+			if err != nil {
+				return nil, err
+			}
+		}
+		if len(relays) > 0 {
+			options = append(options, libp2p.EnableAutoRelayWithStaticRelays(relays))
+		}
+	}
+	if config.HolePunching {
+		options = append(options, libp2p.EnableHolePunching())
+	}
+	if config.RelayService {
+		options = append(options, libp2p.EnableRelayService())
+	}
+	if config.NATService {
+		options = append(options, libp2p.EnableNATService())
+	}
+	return options, nil
+}
+
+func (config NetworkConfig) watermarks() (int, int) {
+	low, high := config.LowWater, config.HighWater
+	if low == 0 {
+		low = DefaultLowWater
+	}
+	if high == 0 {
+		high = DefaultHighWater
+	}
+	return low, high
+}
+
+func (config NetworkConfig) bootstrapPeers() ([]peer.AddrInfo, error) {
+	return parsePeers(config.BootstrapPeers)
+}
+
+func parsePeers(addresses []string) ([]peer.AddrInfo, error) {
+	peers := make([]peer.AddrInfo, 0, len(addresses))
+	for _, address := range addresses {
+		info, err := peer.AddrInfoFromString(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid peer address %q: %w", address, err)
+		}
+		peers = append(peers, *info)
+	}
+	return peers, nil
+}
+
+func (config NetworkConfig) dhtMode() (dht.ModeOpt, error) {
+	switch config.Mode {
+	case ModeServer, "":
+		return dht.ModeServer, nil
+	case ModeClient:
+		return dht.ModeClient, nil
+	case ModeAuto:
+		return dht.ModeAuto, nil
+	}
+	return 0, fmt.Errorf("unknown dht mode %q", config.Mode)
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/libp2p/go-libp2p"
+	dht "github.com/libp2p/go-libp2p-kad-dht"
+)
+
+func TestNetworkConfigValidate(t *testing.T) {
+	valid := NetworkConfig{
+		ListenAddrs:    DefaultListenAddrs,
+		BootstrapPeers: DefaultBootstrapPeers,
+		Mode:           ModeClient,
+	}
+	tests := []struct {
+		name   string
+		change func(*NetworkConfig)
+		ok     bool
+	}{
+		{"defaults", func(config *NetworkConfig) {}, true},
+		{"new network", func(config *NetworkConfig) { config.BootstrapPeers = nil }, true},
+		{"no listen address", func(config *NetworkConfig) { config.ListenAddrs = nil }, false},
+		{"bad listen address", func(config *NetworkConfig) { config.ListenAddrs = []string{"0.0.0.0:44981"} }, false},
+		{"bootstrap peer without id", func(config *NetworkConfig) {
+			config.BootstrapPeers = []string{"/ip4/127.0.0.1/tcp/44981"}
+		}, false},
+		{"unknown mode", func(config *NetworkConfig) { config.Mode = "relay" }, false},
+		{"watermarks", func(config *NetworkConfig) { config.LowWater, config.HighWater = 10, 20 }, true},
+		{"low above high", func(config *NetworkConfig) { config.LowWater, config.HighWater = 20, 10 }, false},
+		{"low above default high", func(config *NetworkConfig) { config.LowWater = DefaultHighWater + 1 }, false},
+		{"public address", func(config *NetworkConfig) { config.PublicAddress = "203.0.113.7:8000" }, true},
+		{"public address without host", func(config *NetworkConfig) { config.PublicAddress = ":8000" }, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := valid
+			test.change(&config)
+			if err := config.Validate(); (err == nil) != test.ok {
+				t.Errorf("expected ok %v, got %v", test.ok, err)
+			}
+		})
+	}
+
+	mode, _ := NetworkConfig{}.dhtMode()
+	if mode != dht.ModeServer {
+		t.Errorf("expected server mode by default, got %v", mode)
+	}
+}
+
+func TestNATOptionsBuildAHost(t *testing.T) {
+	config := NetworkConfig{
+		ListenAddrs:    []string{"/ip4/127.0.0.1/tcp/0", "/ip4/127.0.0.1/udp/0/quic-v1"},
+		BootstrapPeers: DefaultBootstrapPeers,
+		PortMap:        false,
+		AutoRelay:      true,
+		HolePunching:   true,
+		RelayService:   true,
+		NATService:     true,
+	}
+	options, err := config.hostOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Connection manager, relay client, auto relay, hole punching, relay
+	// service and AutoNAT service
+	if len(options) != 6 {
+		t.Errorf("expected 6 options, got %d", len(options))
+	}
+	h, err := libp2p.New(append(options, libp2p.ListenAddrStrings(config.ListenAddrs...))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Close()
+
+	config.StaticRelays = []string{"/ip4/127.0.0.1/tcp/4001"}
+	if err := config.Validate(); err == nil {
+		t.Error("expected a relay without a peer ID to be refused")
+	}
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"orca-peer/internal/payment"
+	"orca-peer/internal/reputation"
+	"strconv"
+)
+
+// openSession starts the payment session for a transfer and tells the
+// consumer its terms in the response headers. If the consumer did not say
+// which key it signs receipts with, the request is refused.
+func (server *Server) openSession(w http.ResponseWriter, r *http.Request, fileHash string) (*payment.Session, bool) {
+	publicKey, err := payment.DecodePublicKey(r.Header.Get(payment.HeaderPublicKey))
+	if price := server.payments.Price(fileHash); err != nil && price > 0 {
+		message := fmt.Sprintf("File costs %f per MB, send the key receipts are signed with in %s", price, payment.HeaderPublicKey)
+		http.Error(w, message, http.StatusPaymentRequired)
+		return nil, false
+	}
+	session := server.payments.Open(fileHash, publicKey)
+	w.Header().Set(payment.HeaderTransferID, session.ID)
+	w.Header().Set(payment.HeaderFileHash, fileHash)
+	w.Header().Set(payment.HeaderPrice, strconv.FormatFloat(session.PricePerMB, 'f', -1, 64))
+	w.Header().Set(payment.HeaderInterval, strconv.FormatInt(server.payments.Interval, 10))
+	return session, true
+}
+
+func (server *Server) closeSession(session *payment.Session) {
+	server.payments.Close(session.ID)
+	if receipt, _ := session.LastReceipt(); receipt != nil {
+		fmt.Printf("\nTransfer %s of %s paid %f for %d bytes\n> ", session.ID, session.FileHash, receipt.Amount, receipt.Bytes)
+	}
+}
+
+// handleReceipt answers POST /sendReceipt with a signed receipt for a
+// running transfer.
+func (server *Server) handleReceipt(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendStatusResponse(w, "Receipts must be sent with POST", http.StatusMethodNotAllowed)
+		return
+	}
+	var signed payment.SignedReceipt
+	if err := json.NewDecoder(r.Body).Decode(&signed); err != nil {
+		sendStatusResponse(w, "Failed to parse receipt", http.StatusBadRequest)
+		return
+	}
+	err := server.applyReceipt(r.RemoteAddr, &signed)
+	switch {
+	case errors.Is(err, payment.ErrUnknownTransfer):
+		sendStatusResponse(w, err.Error(), http.StatusNotFound)
+	case err != nil:
+		sendStatusResponse(w, err.Error(), http.StatusBadRequest)
+	default:
+		sendStatusResponse(w, "Receipt accepted", http.StatusOK)
+	}
+}
+
+// applyReceipt lets the transfer a receipt from peer pays for continue.
+// Peers that send receipts that do not check out lose reputation.
+func (server *Server) applyReceipt(peer string, signed *payment.SignedReceipt) error {
+	_, err := server.payments.Apply(signed)
+	switch {
+	case errors.Is(err, payment.ErrUnknownTransfer), errors.Is(err, payment.ErrStaleReceipt):
+		// Resent after a lost answer, not dishonest
+	case err != nil:
+		server.record(peer, reputation.BadReceipt)
+	default:
+		server.record(peer, reputation.Paid)
+	}
+	return err
+}
+
+// reserve waits for the consumer at peer to pay for size more bytes of
+// session. Consumers that let the payment time out lose reputation.
+func (server *Server) reserve(ctx context.Context, peer string, session *payment.Session, size int64) error {
+	err := session.Reserve(ctx, size, server.payments.AbortAfter)
+	if errors.Is(err, payment.ErrPaymentTimeout) {
+		server.record(peer, reputation.PaymentTimeout)
+	}
+	return err
+}
+
+func (server *Server) record(peer string, event reputation.Event) {
+	if err := server.reputation.Record(peer, event); err != nil {
+		fmt.Println("Error recording reputation:", err)
+	}
+}
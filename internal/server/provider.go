@@ -0,0 +1,207 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multihash"
+)
+
+const marketPrefix = "orcanet/market/"
+
+// How long a provider record is valid once it is put in the DHT
+const ProviderRecordLifetime = 24 * time.Hour
+
+// Clocks of two peers may disagree by this much
+const recordClockSkew = time.Minute
+
+var (
+	ErrMalformedRecord    = errors.New("provider record is malformed")
+	ErrRecordKeyMismatch  = errors.New("provider record is for a different file or peer")
+	ErrRecordWrongPeer    = errors.New("provider record public key does not belong to its peer")
+	ErrBadRecordSignature = errors.New("provider record signature is invalid")
+	ErrRecordExpired      = errors.New("provider record has expired")
+	ErrRecordTooLong      = errors.New("provider record expires too far in the future")
+	ErrNoValidRecord      = errors.New("no valid provider record")
+)
+
+/*
+ProviderRecord tells other peers where a file can be fetched and what it
+costs. Seq grows every time a peer advertises the file again, so the newest
+record always wins over the ones it replaces. A withdrawn record is how a peer
+takes back an advertisement, since the DHT has no way to delete a value.
+*/
+type ProviderRecord struct {
+	FileHash string  `json:"file_hash"`
+	Peer     string  `json:"peer"`
+	Address  string  `json:"address"`
+	Price    float64 `json:"price"`
+	Seq      uint64  `json:"seq"`
+	Expires  string  `json:"expires"`
+
+	Withdrawn bool `json:"withdrawn,omitempty"`
+}
+
+// SignedProviderRecord is the value stored in the DHT. The signature is made
+// with the libp2p key of the peer over the exact record bytes.
+type SignedProviderRecord struct {
+	Record    []byte `json:"record"`
+	Signature []byte `json:"signature"`
+	PublicKey []byte `json:"public_key"`
+}
+
+// ProviderKey is the DHT key of the record peer keeps for fileHash. Every peer
+// has a key of its own, so no peer can replace the record of another.
+func ProviderKey(fileHash string, id peer.ID) string {
+	return marketPrefix + fileHash + "/" + id.String()
+}
+
+// FileCID turns a file hash into the CID providers announce it under.
+func FileCID(fileHash string) (cid.Cid, error) {
+	digest, err := hex.DecodeString(fileHash)
+	if err != nil || len(digest) != sha256.Size {
+		return cid.Undef, fmt.Errorf("invalid file hash %q", fileHash)
+	}
+	encoded, err := multihash.Encode(digest, multihash.SHA2_256)
+	if err != nil {
+		return cid.Undef, err
+	}
+	return cid.NewCidV1(cid.Raw, encoded), nil
+}
+
+func (record *ProviderRecord) expiry() time.Time {
+	expires, _ := time.Parse(time.RFC3339, record.Expires)
+	return expires
+}
+
+// NewProviderRecord builds and signs a record advertising fileHash at address
+// for ttl, which is capped at ProviderRecordLifetime.
+func NewProviderRecord(privateKey crypto.PrivKey, fileHash string, address string, price float64, ttl time.Duration) ([]byte, error) {
+	return signProviderRecord(privateKey, ProviderRecord{FileHash: fileHash, Address: address, Price: price}, ttl)
+}
+
+// NewWithdrawal builds and signs a record saying this peer no longer provides
+// fileHash. It replaces the last record of the peer until it expires.
+func NewWithdrawal(privateKey crypto.PrivKey, fileHash string, ttl time.Duration) ([]byte, error) {
+	return signProviderRecord(privateKey, ProviderRecord{FileHash: fileHash, Withdrawn: true}, ttl)
+}
+
+func signProviderRecord(privateKey crypto.PrivKey, record ProviderRecord, ttl time.Duration) ([]byte, error) {
+	id, err := peer.IDFromPrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	if ttl <= 0 || ttl > ProviderRecordLifetime {
+		ttl = ProviderRecordLifetime
+	}
+	now := time.Now()
+	record.Peer = id.String()
+	// Nanoseconds keep growing across restarts without storing a counter
+	record.Seq = uint64(now.UnixNano())
+	record.Expires = now.Add(ttl).UTC().Format(time.RFC3339)
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := privateKey.Sign(data)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := crypto.MarshalPublicKey(privateKey.GetPublic())
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(SignedProviderRecord{Record: data, Signature: signature, PublicKey: publicKey})
+}
+
+// OrcaValidator only lets signed, unexpired provider records into the DHT.
+type OrcaValidator struct {
+	// Replaced in tests
+	now func() time.Time
+}
+
+func (v OrcaValidator) clock() time.Time {
+	if v.now != nil {
+		return v.now()
+	}
+	return time.Now()
+}
+
+// OpenProviderRecord checks a value stored under key and returns the record
+// in it.
+func (v OrcaValidator) OpenProviderRecord(key string, value []byte) (*ProviderRecord, error) {
+	var signed SignedProviderRecord
+	if err := json.Unmarshal(value, &signed); err != nil {
+		return nil, ErrMalformedRecord
+	}
+	var record ProviderRecord
+	if err := json.Unmarshal(signed.Record, &record); err != nil {
+		return nil, ErrMalformedRecord
+	}
+	fileHash, keyPeer, found := strings.Cut(strings.TrimPrefix(strings.TrimPrefix(key, "/"), marketPrefix), "/")
+	if !found || fileHash != record.FileHash || keyPeer != record.Peer {
+		return nil, ErrRecordKeyMismatch
+	}
+	publicKey, err := crypto.UnmarshalPublicKey(signed.PublicKey)
+	if err != nil {
+		return nil, ErrMalformedRecord
+	}
+	id, err := peer.Decode(record.Peer)
+	if err != nil {
+		return nil, ErrMalformedRecord
+	}
+	if !id.MatchesPublicKey(publicKey) {
+		return nil, ErrRecordWrongPeer
+	}
+	ok, err := publicKey.Verify(signed.Record, signed.Signature)
+	if err != nil || !ok {
+		return nil, ErrBadRecordSignature
+	}
+	expires, err := time.Parse(time.RFC3339, record.Expires)
+	if err != nil {
+		return nil, ErrMalformedRecord
+	}
+	now := v.clock()
+	if !now.Before(expires) {
+		return nil, ErrRecordExpired
+	}
+	if expires.After(now.Add(ProviderRecordLifetime + recordClockSkew)) {
+		return nil, ErrRecordTooLong
+	}
+	return &record, nil
+}
+
+func (v OrcaValidator) Validate(key string, value []byte) error {
+	_, err := v.OpenProviderRecord(key, value)
+	return err
+}
+
+// Select picks the valid record with the highest sequence number. Records
+// with the same sequence number are ordered by expiry.
+func (v OrcaValidator) Select(key string, values [][]byte) (int, error) {
+	best := -1
+	var bestRecord *ProviderRecord
+	for i, value := range values {
+		record, err := v.OpenProviderRecord(key, value)
+		if err != nil {
+			continue
+		}
+		if bestRecord == nil || record.Seq > bestRecord.Seq ||
+			(record.Seq == bestRecord.Seq && record.expiry().After(bestRecord.expiry())) {
+			best = i
+			bestRecord = record
+		}
+	}
+	if best == -1 {
+		return 0, fmt.Errorf("%w for %s", ErrNoValidRecord, key)
+	}
+	return best, nil
+}
@@ -0,0 +1,192 @@
+package server
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multihash"
+)
+
+func newPeerKey(t *testing.T) crypto.PrivKey {
+	privateKey, _, err := crypto.GenerateEd25519Key(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return privateKey
+}
+
+// signRecord signs record as privateKey, whatever peer the record names.
+func signRecord(t *testing.T, privateKey crypto.PrivKey, record ProviderRecord) []byte {
+	data, err := json.Marshal(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := privateKey.Sign(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := crypto.MarshalPublicKey(privateKey.GetPublic())
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := json.Marshal(SignedProviderRecord{Record: data, Signature: signature, PublicKey: publicKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+func TestValidateProviderRecord(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	validator := OrcaValidator{now: func() time.Time { return now }}
+	owner := newPeerKey(t)
+	ownerID, _ := peer.IDFromPrivateKey(owner)
+	attacker := newPeerKey(t)
+	attackerID, _ := peer.IDFromPrivateKey(attacker)
+	key := ProviderKey("abc", ownerID)
+
+	valid := ProviderRecord{
+		FileHash: "abc",
+		Peer:     ownerID.String(),
+		Address:  "10.0.0.1:8080",
+		Price:    1,
+		Seq:      1,
+		Expires:  now.Add(time.Hour).Format(time.RFC3339),
+	}
+	with := func(change func(*ProviderRecord)) ProviderRecord {
+		record := valid
+		change(&record)
+		return record
+	}
+	tampered := signRecord(t, owner, valid)
+	var signed SignedProviderRecord
+	json.Unmarshal(tampered, &signed)
+	signed.Record, _ = json.Marshal(with(func(r *ProviderRecord) { r.Address = "6.6.6.6:80" }))
+	tampered, _ = json.Marshal(signed)
+
+	tests := []struct {
+		name  string
+		key   string
+		value []byte
+		want  error
+	}{
+		{"valid", key, signRecord(t, owner, valid), nil},
+		{"leading slash", "/" + key, signRecord(t, owner, valid), nil},
+		{"garbage", key, []byte("10.0.0.1:8080"), ErrMalformedRecord},
+		{"forged by another peer", key, signRecord(t, attacker, valid), ErrRecordWrongPeer},
+		{"tampered after signing", key, tampered, ErrBadRecordSignature},
+		{"other file", ProviderKey("def", ownerID), signRecord(t, owner, valid), ErrRecordKeyMismatch},
+		{"key of another peer", key, signRecord(t, attacker, with(func(r *ProviderRecord) {
+			r.Peer = attackerID.String()
+		})), ErrRecordKeyMismatch},
+		{"key without peer", marketPrefix + "abc", signRecord(t, owner, valid), ErrRecordKeyMismatch},
+		{"expired", key, signRecord(t, owner, with(func(r *ProviderRecord) {
+			r.Expires = now.Add(-time.Second).Format(time.RFC3339)
+		})), ErrRecordExpired},
+		{"lives too long", key, signRecord(t, owner, with(func(r *ProviderRecord) {
+			r.Expires = now.Add(30 * 24 * time.Hour).Format(time.RFC3339)
+		})), ErrRecordTooLong},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := validator.Validate(test.key, test.value); !errors.Is(err, test.want) {
+				t.Errorf("expected %v, got %v", test.want, err)
+			}
+		})
+	}
+}
+
+func TestSelectNewestProviderRecord(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	validator := OrcaValidator{now: func() time.Time { return now }}
+	owner := newPeerKey(t)
+	ownerID, _ := peer.IDFromPrivateKey(owner)
+	attacker := newPeerKey(t)
+	key := ProviderKey("abc", ownerID)
+
+	record := func(seq uint64, expires time.Duration) ProviderRecord {
+		return ProviderRecord{
+			FileHash: "abc",
+			Peer:     ownerID.String(),
+			Address:  "10.0.0.1:8080",
+			Seq:      seq,
+			Expires:  now.Add(expires).Format(time.RFC3339),
+		}
+	}
+	values := [][]byte{
+		signRecord(t, owner, record(2, time.Hour)),
+		signRecord(t, owner, record(1, 2*time.Hour)),
+		// A forged record with a higher sequence number must not win
+		signRecord(t, attacker, record(9, time.Hour)),
+		// Newest, but expired
+		signRecord(t, owner, record(5, -time.Hour)),
+		signRecord(t, owner, record(3, time.Hour)),
+		signRecord(t, owner, record(3, 2*time.Hour)),
+	}
+	best, err := validator.Select(key, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if best != 5 {
+		t.Errorf("expected record 5 to be selected, got %d", best)
+	}
+
+	// A stale record put after a newer one loses
+	best, err = validator.Select(key, [][]byte{values[4], values[0]})
+	if err != nil || best != 0 {
+		t.Errorf("expected the stored newer record to be kept, got %d (%v)", best, err)
+	}
+
+	if _, err := validator.Select(key, [][]byte{values[2], values[3]}); !errors.Is(err, ErrNoValidRecord) {
+		t.Errorf("expected no valid record, got %v", err)
+	}
+}
+
+func TestNewProviderRecordValidates(t *testing.T) {
+	owner := newPeerKey(t)
+	ownerID, _ := peer.IDFromPrivateKey(owner)
+	value, err := NewProviderRecord(owner, "abc", "10.0.0.1:8080", 2, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := OrcaValidator{}.OpenProviderRecord(ProviderKey("abc", ownerID), value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Address != "10.0.0.1:8080" || record.Price != 2 || record.Withdrawn {
+		t.Errorf("unexpected record %+v", record)
+	}
+
+	// The withdrawal is newer, so it replaces the advertisement
+	withdrawal, err := NewWithdrawal(owner, "abc", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	best, err := OrcaValidator{}.Select(ProviderKey("abc", ownerID), [][]byte{value, withdrawal})
+	if err != nil || best != 1 {
+		t.Errorf("expected the withdrawal to be selected, got %d (%v)", best, err)
+	}
+}
+
+func TestFileCID(t *testing.T) {
+	fileHash := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	fileCID, err := FileCID(fileHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := multihash.Decode(fileCID.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Code != multihash.SHA2_256 || hex.EncodeToString(decoded.Digest) != fileHash {
+		t.Errorf("CID %s does not carry the file hash", fileCID)
+	}
+	if _, err := FileCID("not a hash"); err == nil {
+		t.Error("expected invalid hash to be refused")
+	}
+}
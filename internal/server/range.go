@@ -0,0 +1,67 @@
+package server
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var errUnsatisfiableRange = errors.New("requested range not satisfiable")
+
+// parseRange reads a single "bytes=" range from a Range header and returns the
+// inclusive start and end offsets inside a file of the given size. The boolean
+// is false when the header is absent or asks for several ranges, in which case
+// the whole file should be served instead.
+func parseRange(header string, size int64) (int64, int64, bool, error) {
+	if header == "" {
+		return 0, 0, false, nil
+	}
+	const prefix = "bytes="
+	if !strings.HasPrefix(header, prefix) {
+		return 0, 0, false, errUnsatisfiableRange
+	}
+	spec := strings.TrimSpace(header[len(prefix):])
+	if strings.Contains(spec, ",") {
+		return 0, 0, false, nil
+	}
+	startStr, endStr, found := strings.Cut(spec, "-")
+	if !found {
+		return 0, 0, false, errUnsatisfiableRange
+	}
+	startStr = strings.TrimSpace(startStr)
+	endStr = strings.TrimSpace(endStr)
+
+	var start, end int64
+	if startStr == "" {
+		// Suffix range, e.g. "bytes=-500" for the last 500 bytes
+		suffix, err := strconv.ParseInt(endStr, 10, 64)
+		if err != nil || suffix <= 0 {
+			return 0, 0, false, errUnsatisfiableRange
+		}
+		if suffix > size {
+			suffix = size
+		}
+		start = size - suffix
+		end = size - 1
+	} else {
+		var err error
+		start, err = strconv.ParseInt(startStr, 10, 64)
+		if err != nil || start < 0 {
+			return 0, 0, false, errUnsatisfiableRange
+		}
+		end = size - 1
+		if endStr != "" {
+			end, err = strconv.ParseInt(endStr, 10, 64)
+			if err != nil || end < start {
+				return 0, 0, false, errUnsatisfiableRange
+			}
+			if end > size-1 {
+				end = size - 1
+			}
+		}
+	}
+	if start >= size {
+		return 0, 0, false, errUnsatisfiableRange
+	}
+	return start, end, true, nil
+}
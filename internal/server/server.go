@@ -1,7 +1,9 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"crypto/rsa"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,27 +11,40 @@ import (
 	"net"
 	"net/http"
 	api "orca-peer/internal/api"
+	"orca-peer/internal/approval"
 	"orca-peer/internal/hash"
+	"orca-peer/internal/p2p"
+	"orca-peer/internal/payment"
+	"orca-peer/internal/reputation"
+	"orca-peer/internal/txstore"
+	"orca-peer/internal/wallet"
 	"os"
 	"path/filepath"
-	"time"
+	"strconv"
+	"strings"
+
+	"github.com/libp2p/go-libp2p/core/host"
 )
 
 const keyServerAddr = "serverAddr"
 
-var (
-	eventChannel chan bool
-)
-
 type Server struct {
-	storage *hash.DataStore
+	storage      *hash.DataStore
+	grants       *grantTable
+	policy       approval.Policy
+	payments     *payment.Manager
+	publicKey    *rsa.PublicKey
+	verifier     *hash.Verifier
+	wallet       *wallet.Wallet
+	transactions *txstore.Store
+	// Where the behaviour of consumers is recorded, may be nil
+	reputation *reputation.Book
 }
 
 func Init() {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", getRoot)
 	mux.HandleFunc("/requestFile", getFile)
-	mux.HandleFunc("/sendTransaction", handleTransaction)
 
 	ctx := context.Background()
 	server := &http.Server{
@@ -50,16 +65,6 @@ func Init() {
 	}()
 }
 
-type TransactionFile struct {
-	Bytes               []byte `json:"bytes"`
-	UnlockedTransaction []byte `json:"transaction"`
-	PublicKey           string `json:"public_key"`
-}
-type Transaction struct {
-	Price     float64 `json:"price"`
-	Timestamp string  `json:"timestamp"`
-	Uuid      string  `json:"uuid"`
-}
 type StatusResponse struct {
 	Status string `json:"status"`
 }
@@ -87,7 +92,39 @@ func sendStatusResponse(w http.ResponseWriter, status string, statusCode int) {
 	}
 }
 
-func handleTransaction(w http.ResponseWriter, r *http.Request) {
+// Identity is how a peer tells others which key it signs with and expects
+// to be paid to.
+type Identity struct {
+	PublicKey   string `json:"public_key"`
+	Fingerprint string `json:"fingerprint"`
+	PeerID      string `json:"peer_id"`
+}
+
+func (server *Server) sendIdentity(w http.ResponseWriter, r *http.Request) {
+	if server.publicKey == nil {
+		sendStatusResponse(w, "Node has no identity", http.StatusInternalServerError)
+		return
+	}
+	publicKey, err := hash.ExportRsaPublicKeyAsPemStr(server.publicKey)
+	if err != nil {
+		sendStatusResponse(w, "Failed to export public key", http.StatusInternalServerError)
+		return
+	}
+	fingerprint, err := hash.Fingerprint(server.publicKey)
+	if err != nil {
+		sendStatusResponse(w, "Failed to fingerprint public key", http.StatusInternalServerError)
+		return
+	}
+	peerID, err := hash.PeerIDFromKey(server.publicKey)
+	if err != nil {
+		sendStatusResponse(w, "Failed to derive peer ID", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Identity{PublicKey: string(publicKey), Fingerprint: fingerprint, PeerID: peerID.String()})
+}
+
+func (server *Server) handleTransaction(w http.ResponseWriter, r *http.Request) {
 	// Read the Request Body
 	fmt.Println("Handling a transaction...")
 	body, err := io.ReadAll(r.Body)
@@ -97,95 +134,108 @@ func handleTransaction(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
-	// Print the received byte string
-	var data TransactionFile
-	fmt.Println("Received byte string:")
-	err = json.Unmarshal(body, &data)
+	var signed hash.SignedTransaction
+	err = json.Unmarshal(body, &signed)
 	if err != nil {
 		fmt.Println("Error unmarshalling JSON:", err)
+		sendStatusResponse(w, "Error Marshalling JSON", http.StatusBadRequest)
 		return
 	}
-	publicKey, err := hash.ParseRsaPublicKeyFromPemStr(data.PublicKey)
+	transaction, err := server.verifier.Verify(&signed)
 	if err != nil {
-		fmt.Println("Unable to unmarshalling public key:", err)
+		// A transaction outside its lifetime may just be a wrong clock, the
+		// rest is forged or replayed
+		if !errors.Is(err, hash.ErrTransactionExpired) && !errors.Is(err, hash.ErrTransactionNotYetValid) {
+			server.record(r.RemoteAddr, reputation.InvalidTransaction)
+		}
+		fmt.Println("Rejected transaction:", err)
+		sendStatusResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = server.transactions.Put(txstore.Record{Direction: txstore.Received, Transaction: *transaction, Signed: &signed})
+	if errors.Is(err, txstore.ErrDuplicate) {
+		sendStatusResponse(w, err.Error(), http.StatusConflict)
 		return
 	}
-	timestamp := time.Now()
-	timestampStr := timestamp.Format(time.RFC3339)
-	err = os.WriteFile("./files/transactions/"+timestampStr, body, 0644)
 	if err != nil {
-		fmt.Println("Error writing transaction to file:", err)
+		fmt.Println("Error storing transaction:", err)
 		sendStatusResponse(w, "", http.StatusInternalServerError)
 		return
 	}
-	fmt.Println("Data in struct:", data)
-	error := hash.VerifySignature(data.UnlockedTransaction, data.Bytes, publicKey)
-	if error != nil {
-		fmt.Println("Properly Hashed Transaction")
-	} else {
-		fmt.Println("Did not properly hash transaction")
-		sendStatusResponse(w, "Public Key does not unlock the locked transaction", http.StatusBadRequest)
+	if err := server.wallet.Receive(transaction); err != nil {
+		fmt.Println("Error recording transaction in wallet:", err)
+		sendStatusResponse(w, "", http.StatusInternalServerError)
 		return
 	}
-	var transaction Transaction
-	err = json.Unmarshal(data.UnlockedTransaction, &transaction)
-	if err != nil {
-		fmt.Println("Error unmarshalling JSON:", err)
-		sendStatusResponse(w, "Error Marshalling JSON", http.StatusBadRequest)
-		return
-	} else {
-		fmt.Println("Transaction JSON:")
-		fmt.Println("Sent Amount:")
-		fmt.Println(transaction.Price)
-		fmt.Println("UUID:")
-		fmt.Println(transaction.Uuid)
-		sendStatusResponse(w, "Successfully stored transaction", http.StatusOK)
-	}
-	eventChannel <- true
-	fmt.Println("> ")
+	server.record(r.RemoteAddr, reputation.ValidTransaction)
+	fmt.Printf("\nReceived %f from %s in transaction %s\n> ", transaction.Amount, transaction.Payer, transaction.Uuid)
+	sendStatusResponse(w, "Successfully stored transaction", http.StatusOK)
 }
 
-// Start HTTP server
-func StartServer(port string, serverReady chan bool, confirming *bool, confirmation *string) {
-	eventChannel = make(chan bool)
+// Start HTTP server. The file routes are also served over libp2p streams on
+// p2pHost, if there is one, and files over gRPC if rpc says where.
+func StartServer(port string, serverReady chan bool, storage *hash.DataStore, policy approval.Policy, payments *payment.Manager, publicKey *rsa.PublicKey, userWallet *wallet.Wallet, transactions *txstore.Store, scores *reputation.Book, p2pHost host.Host, rpc RPCConfig) {
+	fingerprint, err := hash.Fingerprint(publicKey)
+	if err != nil {
+		fmt.Println("Error fingerprinting public key:", err)
+		os.Exit(1)
+	}
 	server := Server{
-		storage: hash.NewDataStore("files/stored/"),
+		storage:      storage,
+		grants:       newGrantTable(),
+		policy:       policy,
+		payments:     payments,
+		publicKey:    publicKey,
+		verifier:     hash.NewVerifier(fingerprint),
+		wallet:       userWallet,
+		transactions: transactions,
+		reputation:   scores,
+	}
+	api.InitServer(storage, userWallet, transactions)
+	server.fileRoutes(http.DefaultServeMux)
+
+	if p2pHost != nil {
+		// Only the routes other peers need are reachable over libp2p, the
+		// API stays local
+		p2pRoutes := http.NewServeMux()
+		server.fileRoutes(p2pRoutes)
+		go http.Serve(p2p.Listen(p2pHost), p2pRoutes)
+		api.TrackPeers(p2pHost)
+		fmt.Printf("Serving files over %s...\n", p2p.FileProtocol)
+	}
+	if rpc.Listen != "" {
+		go server.serveRPC(rpc.Listen)
 	}
-	api.InitServer()
-	http.HandleFunc("/requestFile/", func(w http.ResponseWriter, r *http.Request) {
-		server.sendFile(w, r, confirming, confirmation)
-	})
-	http.HandleFunc("/storeFile/", func(w http.ResponseWriter, r *http.Request) {
-		server.storeFile(w, r, confirming, confirmation)
-	})
-	http.HandleFunc("/sendTransaction", handleTransaction)
 
 	fmt.Printf("Listening on port %s...\n", port)
 	serverReady <- true
 	http.ListenAndServe(":"+port, nil)
 }
 
-func (server *Server) sendFile(w http.ResponseWriter, r *http.Request, confirming *bool, confirmation *string) {
+// fileRoutes registers the routes other peers use to fetch, store and pay
+// for files.
+func (server *Server) fileRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/requestFile/", server.sendFile)
+	mux.HandleFunc("/storeFile/", server.storeFile)
+	mux.HandleFunc("/manifest/", server.sendManifest)
+	mux.HandleFunc("/chunk/", server.sendChunk)
+	mux.HandleFunc("/sendReceipt", server.handleReceipt)
+	mux.HandleFunc("/sendTransaction", server.handleTransaction)
+	mux.HandleFunc("/identity", server.sendIdentity)
+}
+
+func (server *Server) sendFile(w http.ResponseWriter, r *http.Request) {
 	// Extract filename from URL path
 	filename := r.URL.Path[len("/requestFile/"):]
 
-	// Ask for confirmation
-	*confirming = true
-	fmt.Printf("\nYou have just received a request to send file '%s'. Do you want to send the file? (yes/no): ", filename)
-
-	// Check if confirmation is received
-	for *confirmation != "yes" {
-		if *confirmation != "" {
-			http.Error(w, fmt.Sprintf("Client declined to send file '%s'.", filename), http.StatusUnauthorized)
-			*confirmation = ""
-			*confirming = false
-			return
-		}
+	// A HEAD request only asks how large the file is, which downloaders use
+	// to split it into pieces, so it does not need to be confirmed
+	if r.Method == http.MethodHead {
+		server.describeFile(w, filename)
+		return
 	}
-	*confirmation = ""
-	*confirming = false
 
-	file, err := os.Open("./files/" + filename)
+	file, size, err := server.openServedFile(filename)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
@@ -193,61 +243,111 @@ func (server *Server) sendFile(w http.ResponseWriter, r *http.Request, confirmin
 
 	defer file.Close()
 
-	stat, err := file.Stat()
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	session, ok := server.openSession(w, r, filename)
+	if !ok {
 		return
 	}
+	defer server.closeSession(session)
+
+	request := approval.Request{Kind: approval.SendFile, Peer: r.RemoteAddr, FileHash: filename, Size: size}
+	if !approval.Approve(r.Context(), server.policy, request) {
+		http.Error(w, fmt.Sprintf("Client declined to send file '%s'.", filename), http.StatusUnauthorized)
+		return
+	}
+	server.payments.Requested(filename)
 
 	// Set content type
 	contentType := "application/octet-stream"
 	switch {
-	case filename[len(filename)-4:] == ".txt":
+	case strings.HasSuffix(filename, ".txt"):
 		contentType = "text/plain"
-	case filename[len(filename)-5:] == ".json":
+	case strings.HasSuffix(filename, ".json"):
 		contentType = "application/json"
-	case filename[len(filename)-4:] == ".mp4":
+	case strings.HasSuffix(filename, ".mp4"):
 		contentType = "video/mp4"
 	}
 
 	// Set content disposition header
 	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
 	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Accept-Ranges", "bytes")
+
+	// Only send the part of the file that the client asked for, so an
+	// interrupted download can pick up from where it stopped
+	start, end, partial, err := parseRange(r.Header.Get("Range"), size)
+	if err != nil {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		http.Error(w, err.Error(), http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+	if !partial {
+		start, end = 0, size-1
+	}
+	length := end - start + 1
+	if _, err := file.Seek(start, io.SeekStart); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(length, 10))
+	if partial {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, size))
+		w.WriteHeader(http.StatusPartialContent)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	// The consumer needs the transfer id from the headers before it can pay
+	// for anything
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
 
 	const chunkSize = 1024
 	fmt.Println("File size: ")
-	fmt.Println(stat.Size())
-	if stat.Size() > chunkSize {
+	fmt.Println(size)
+	if length > chunkSize {
 		fmt.Println("Must serve in chunks")
 		buffer := make([]byte, chunkSize)
-		for {
-			//	time.Sleep(1 * time.Second)
-			// Read 10 bytes from the file
+		remaining := length
+		for remaining > 0 {
+			if remaining < chunkSize {
+				buffer = buffer[:remaining]
+			}
 			n, err := file.Read(buffer)
-			// fmt.Println("n:", n)
-			// fmt.Println("buffer:", buffer)
-			// fmt.Println()
 			if err != nil {
 				// Check if it's the end of the file
-				if err.Error() == "EOF" {
+				if err == io.EOF {
 					break
 				}
-				// Handle other errors
-				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				// Headers are already sent, so just drop the connection
+				fmt.Println("Error reading file:", err)
+				return
+			}
+			// Only send what the consumer has paid for
+			if err := server.reserve(r.Context(), r.RemoteAddr, session, int64(n)); err != nil {
+				fmt.Printf("\nTransfer of %s aborted at byte %d: %s\n> ", filename, start+length-remaining, err)
 				return
 			}
 			fmt.Println("Sending chunk...")
-			// Write the 10-byte chunk to the response
-			w.Write(buffer[:n])
-			<-eventChannel
-			//w.Write([]byte("\n@@@@\n"))
+			if _, err := w.Write(buffer[:n]); err != nil {
+				fmt.Printf("\nTransfer of %s interrupted at byte %d: %s\n> ", filename, start+length-remaining, err)
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			remaining -= int64(n)
 		}
 	} else {
 		fmt.Println("sending in one piece")
+		if err := server.reserve(r.Context(), r.RemoteAddr, session, length); err != nil {
+			fmt.Printf("\nTransfer of %s aborted: %s\n> ", filename, err)
+			return
+		}
 		// Copy file contents to Response Body
-		_, err = io.Copy(w, file)
+		_, err = io.CopyN(w, file, length)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			fmt.Println("Error sending file:", err)
 			return
 		}
 
@@ -256,12 +356,48 @@ func (server *Server) sendFile(w http.ResponseWriter, r *http.Request, confirmin
 	fmt.Printf("\nFile %s sent!\n> ", filename)
 }
 
+func (server *Server) describeFile(w http.ResponseWriter, filename string) {
+	file, size, err := server.openServedFile(filename)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	file.Close()
+
+	w.Header().Set("Accept-Ranges", "bytes")
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	w.Header().Set(payment.HeaderPrice, strconv.FormatFloat(server.payments.Price(filename), 'f', -1, 64))
+	w.WriteHeader(http.StatusOK)
+}
+
+// openServedFile looks for a file in the files directory first and then falls
+// back to the content addressed storage, where files are named by their CID.
+func (server *Server) openServedFile(filename string) (io.ReadSeekCloser, int64, error) {
+	file, err := os.Open(filepath.Join("./files/", filename))
+	if err == nil {
+		stat, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return nil, 0, err
+		}
+		return file, stat.Size(), nil
+	}
+	if server.storage == nil {
+		return nil, 0, err
+	}
+	reader, manifest, err := server.storage.OpenFile(filepath.Base(filename))
+	if err != nil {
+		return nil, 0, err
+	}
+	return reader, manifest.Size, nil
+}
+
 type FileData struct {
 	FileName string `json:"filename"`
 	Content  []byte `json:"content"`
 }
 
-func (server *Server) storeFile(w http.ResponseWriter, r *http.Request, confirming *bool, confirmation *string) {
+func (server *Server) storeFile(w http.ResponseWriter, r *http.Request) {
 	// Parse JSON object from Request Body
 	var fileData FileData
 	err := json.NewDecoder(r.Body).Decode(&fileData)
@@ -270,21 +406,22 @@ func (server *Server) storeFile(w http.ResponseWriter, r *http.Request, confirmi
 		return
 	}
 
-	// Ask for confirmation
-	*confirming = true
-	fmt.Printf("\nYou have just received a request to store file '%s'. Do you want to store the file? (yes/no): ", fileData.FileName)
-
-	// Check if confirmation is received
-	for *confirmation != "yes" {
-		if *confirmation != "" {
-			http.Error(w, fmt.Sprintf("Client declined to store file '%s'.", fileData.FileName), http.StatusUnauthorized)
-			*confirmation = ""
-			*confirming = false
-			return
-		}
+	fileHash, err := hash.ComputeCID(bytes.NewReader(fileData.Content))
+	if err != nil {
+		http.Error(w, "Failed to hash file", http.StatusInternalServerError)
+		return
+	}
+	request := approval.Request{
+		Kind:     approval.StoreFile,
+		Peer:     r.RemoteAddr,
+		FileHash: fileHash,
+		FileName: fileData.FileName,
+		Size:     int64(len(fileData.Content)),
+	}
+	if !approval.Approve(r.Context(), server.policy, request) {
+		http.Error(w, fmt.Sprintf("Client declined to store file '%s'.", fileData.FileName), http.StatusUnauthorized)
+		return
 	}
-	*confirmation = ""
-	*confirming = false
 
 	// Create file
 	file_hash, err := server.storage.PutFile(fileData.Content)
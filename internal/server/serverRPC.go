@@ -2,11 +2,15 @@ package server
 
 import (
 	"context"
+	"crypto/rsa"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"orca-peer/internal/fileshare"
+	orcaHash "orca-peer/internal/hash"
+	"orca-peer/internal/p2p"
+	"orca-peer/internal/payment"
 	"os"
 	"sync"
 	"time"
@@ -14,24 +18,15 @@ import (
 	"github.com/libp2p/go-libp2p"
 	dht "github.com/libp2p/go-libp2p-kad-dht"
 	record "github.com/libp2p/go-libp2p-record"
-	"github.com/libp2p/go-libp2p/core/crypto"
 	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
 	drouting "github.com/libp2p/go-libp2p/p2p/discovery/routing"
 	dutil "github.com/libp2p/go-libp2p/p2p/discovery/util"
-	"github.com/multiformats/go-multiaddr"
 )
 
-type OrcaValidator struct{}
-
-// testing, will actually validate later
-func (v OrcaValidator) Validate(key string, value []byte) error {
-	return nil
-}
-
-func (v OrcaValidator) Select(key string, value [][]byte) (int, error) {
-	return 0, nil
-}
+// How long SearchKey looks for providers of a file
+const searchTimeout = 10 * time.Second
 
 type fileShareServerNode struct {
 	fileshare.UnimplementedFileShareServer
@@ -40,33 +35,35 @@ type fileShareServerNode struct {
 	currentCoins float64
 }
 
-func CreateDHTConnection(bootstrapAddress *string) (context.Context, *dht.IpfsDHT) {
-	if *bootstrapAddress == "local" {
-		return nil, nil
+// CreateDHTConnection starts the libp2p host of this node and joins the DHT
+// through the bootstrap peers in config.
+func CreateDHTConnection(config NetworkConfig, privateKey *rsa.PrivateKey) (context.Context, *dht.IpfsDHT, error) {
+	if err := config.Validate(); err != nil {
+		return nil, nil, err
 	}
-	bootstrapPeer := "/ip4/194.113.75.165/tcp/44981/p2p/QmS6bES2qGSCN1vTmxEjZGDwwLw5Lsm2ToAcpzf97S7BnR"
-	if *bootstrapAddress != "" {
-		bootstrapPeer = *bootstrapAddress
-	}
-	isClient := false
+	bootstrapPeers, _ := config.bootstrapPeers()
+	mode, _ := config.dhtMode()
 
 	ctx := context.Background()
 
-	//Generate private key for peer
-	privKey, _, err := crypto.GenerateKeyPair(crypto.RSA, 2048)
+	// The peer ID comes from the key in config/key.priv, so it is the same on
+	// every start
+	privKey, err := orcaHash.HostKey(privateKey)
 	if err != nil {
-		panic(err)
+		return nil, nil, err
 	}
 
-	//Construct multiaddr from string and create host to listen on it
-	sourceMultiAddr, _ := multiaddr.NewMultiaddr("/ip4/0.0.0.0/tcp/44981")
+	natOptions, err := config.hostOptions()
+	if err != nil {
+		return nil, nil, err
+	}
 	opts := []libp2p.Option{
-		libp2p.ListenAddrStrings(sourceMultiAddr.String()),
+		libp2p.ListenAddrStrings(config.ListenAddrs...),
 		libp2p.Identity(privKey), //derive id from private key
 	}
-	host, err := libp2p.New(opts...)
+	host, err := libp2p.New(append(opts, natOptions...)...)
 	if err != nil {
-		panic(err)
+		return nil, nil, err
 	}
 
 	log.Printf("Host ID: %s", host.ID())
@@ -75,94 +72,171 @@ func CreateDHTConnection(bootstrapAddress *string) (context.Context, *dht.IpfsDH
 		log.Printf("%s/p2p/%s", addr, host.ID())
 	}
 
-	//An array if we want to expand to a more stable peer list instead of providing in args
-	bootstrapPeers := []string{
-		bootstrapPeer,
-	}
-
 	// Start a DHT, for use in peer discovery. We can't just make a new DHT
 	// client because we want each peer to maintain its own local copy of the
 	// DHT, so that the bootstrapping node of the DHT can go down without
 	// inhibiting future peer discovery.
 	var validator record.Validator = OrcaValidator{}
-	var options []dht.Option
-	if isClient { //if no bootstrap peer, go into server mode
-		options = append(options, dht.Mode(dht.ModeClient))
-	} else {
-		options = append(options, dht.Mode(dht.ModeServer))
+	options := []dht.Option{
+		dht.Mode(mode),
+		dht.ProtocolPrefix("orcanet/market"),
+		dht.Validator(validator),
 	}
-	options = append(options, dht.ProtocolPrefix("orcanet/market"), dht.Validator(validator))
 	kDHT, err := dht.New(ctx, host, options...)
 	if err != nil {
-		panic(err)
+		host.Close()
+		return nil, nil, err
 	}
 
 	// Bootstrap the DHT. In the default configuration, this spawns a Background
 	// thread that will refresh the peer table every five minutes.
 	log.Println("Bootstrapping the DHT")
 	if err = kDHT.Bootstrap(ctx); err != nil {
-		panic(err)
+		host.Close()
+		return nil, nil, err
 	}
 
 	// Let's connect to the bootstrap nodes first. They will tell us about the
-	// other nodes in the network.
+	// other nodes in the network. Without any, this node starts a new network.
+	if len(bootstrapPeers) == 0 {
+		log.Println("No bootstrap peers, starting a new network")
+	}
 	var wg sync.WaitGroup
-	for _, peerAddrString := range bootstrapPeers {
-		if peerAddrString == "" {
-			continue
-		}
-		peerAddr, err := multiaddr.NewMultiaddr(peerAddrString)
-		if err != nil {
-			panic(err)
-		}
-		peerinfo, _ := peer.AddrInfoFromP2pAddr(peerAddr)
+	for _, peerinfo := range bootstrapPeers {
 		wg.Add(1)
-		go func() {
+		go func(peerinfo peer.AddrInfo) {
 			defer wg.Done()
-			if err := host.Connect(ctx, *peerinfo); err != nil {
+			if err := host.Connect(ctx, peerinfo); err != nil {
 				log.Println("WARNING: ", err)
 			} else {
-				log.Println("Connection established with bootstrap node:", *peerinfo)
+				log.Println("Connection established with bootstrap node:", peerinfo)
 			}
-		}()
+		}(peerinfo)
 	}
 	wg.Wait()
 
-	go discoverPeers(ctx, host, kDHT, "orcanet/market")
+	if config.MDNS {
+		if _, err := p2p.StartMDNS(host, nil); err != nil {
+			log.Println("WARNING: local discovery not started:", err)
+		} else {
+			log.Println("Looking for peers on the local network")
+		}
+	}
+
+	low, _ := config.watermarks()
+	go discoverPeers(ctx, host, kDHT, "orcanet/market", low)
 	time.Sleep(5 * time.Second)
 
-	return ctx, kDHT
+	return ctx, kDHT, nil
 }
-func PlaceKey(ctx context.Context, kDHT *dht.IpfsDHT, putKey string, putValue string) {
-	err := kDHT.PutValue(ctx, "orcanet/market/"+putKey, []byte(putValue))
+
+// PlaceKey advertises fileHash in the DHT and reports how it went.
+func PlaceKey(ctx context.Context, kDHT *dht.IpfsDHT, fileHash string, address string, price float64) {
+	err := Provide(ctx, kDHT, fileHash, address, price, ProviderRecordLifetime)
 	if err != nil {
 		fmt.Println("Error: ", err)
-		time.Sleep(5 * time.Second)
 		return
 	}
-	fmt.Println("Put key: ", putKey+" Value: "+putValue)
+	fmt.Println("Put key: ", fileHash+" Value: "+address)
 	fmt.Print("> ")
 }
-func SearchKey(ctx context.Context, kDHT *dht.IpfsDHT, searchKey string) []string {
-	valueStream, err := kDHT.SearchValue(ctx, "orcanet/market/"+searchKey)
-	fmt.Println("Searching for " + searchKey)
+
+/*
+Provide advertises in the DHT that this node holds fileHash. The node is added
+to the providers of the file, and its address and price per MB are put in a
+signed record under a key of its own, so every holder of a file stays visible.
+The record expires after ttl unless it is provided again.
+*/
+func Provide(ctx context.Context, kDHT *dht.IpfsDHT, fileHash string, address string, price float64, ttl time.Duration) error {
+	fileCID, err := FileCID(fileHash)
+	if err != nil {
+		return err
+	}
+	host := kDHT.Host()
+	value, err := NewProviderRecord(host.Peerstore().PrivKey(host.ID()), fileHash, address, price, ttl)
+	if err != nil {
+		return err
+	}
+	if err := kDHT.PutValue(ctx, ProviderKey(fileHash, host.ID()), value); err != nil {
+		return err
+	}
+	return kDHT.Provide(ctx, fileCID, true)
+}
+
+// Withdraw replaces the record of this node for fileHash with a withdrawal,
+// so peers that still find it as a provider skip it.
+func Withdraw(ctx context.Context, kDHT *dht.IpfsDHT, fileHash string) error {
+	host := kDHT.Host()
+	value, err := NewWithdrawal(host.Peerstore().PrivKey(host.ID()), fileHash, ProviderRecordLifetime)
+	if err != nil {
+		return err
+	}
+	return kDHT.PutValue(ctx, ProviderKey(fileHash, host.ID()), value)
+}
+
+// DHTAnnouncer provides files in the DHT at a fixed address. Every
+// advertisement carries the price the file has at that moment.
+type DHTAnnouncer struct {
+	DHT     *dht.IpfsDHT
+	Address string
+	Prices  payment.Pricer
+}
+
+func (announcer *DHTAnnouncer) Announce(ctx context.Context, fileHash string, ttl time.Duration) error {
+	return Provide(ctx, announcer.DHT, fileHash, announcer.Address, announcer.Prices.Price(fileHash), ttl)
+}
+
+func (announcer *DHTAnnouncer) Withdraw(ctx context.Context, fileHash string) error {
+	return Withdraw(ctx, announcer.DHT, fileHash)
+}
+
+// SearchKey returns the record of every provider of fileHash that could be
+// found within searchTimeout.
+func SearchKey(ctx context.Context, kDHT *dht.IpfsDHT, fileHash string) []ProviderRecord {
+	fmt.Println("Searching for " + fileHash)
 	fmt.Print("> ")
+	fileCID, err := FileCID(fileHash)
 	if err != nil {
 		fmt.Println("Error: ", err)
-		time.Sleep(5 * time.Second)
 		return nil
 	}
-	time.Sleep(5 * time.Second)
-	allAddress := make([]string, 0)
-	for byteArray := range valueStream {
-		allAddress = append(allAddress, string(byteArray))
-		fmt.Print("Found value: ")
-		fmt.Println(string(byteArray))
-		fmt.Print("> ")
+	ctx, cancel := context.WithTimeout(ctx, searchTimeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	providers := make([]ProviderRecord, 0)
+	validator := OrcaValidator{}
+	for provider := range kDHT.FindProvidersAsync(ctx, fileCID, 0) {
+		wg.Add(1)
+		go func(id peer.ID) {
+			defer wg.Done()
+			key := ProviderKey(fileHash, id)
+			value, err := kDHT.GetValue(ctx, key)
+			if err != nil {
+				fmt.Printf("No provider record from %s: %s\n", id, err)
+				return
+			}
+			// The DHT already validated the value, but the record is still needed
+			record, err := validator.OpenProviderRecord(key, value)
+			if err != nil || record.Withdrawn {
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			providers = append(providers, *record)
+			fmt.Printf("Found value: %s (%s at %f per MB)\n", record.Address, record.Peer, record.Price)
+			fmt.Print("> ")
+		}(provider.ID)
 	}
-	return allAddress
+	wg.Wait()
+	return providers
 }
-func discoverPeers(ctx context.Context, h host.Host, kDHT *dht.IpfsDHT, advertise string) {
+
+// discoverPeers connects to the peers found under the rendezvous string
+// advertise until there are enough connections, past that the connection
+// manager would only close them again.
+func discoverPeers(ctx context.Context, h host.Host, kDHT *dht.IpfsDHT, advertise string, enough int) {
 	routingDiscovery := drouting.NewRoutingDiscovery(kDHT)
 	dutil.Advertise(ctx, routingDiscovery, advertise)
 
@@ -177,12 +251,12 @@ func discoverPeers(ctx context.Context, h host.Host, kDHT *dht.IpfsDHT, advertis
 			if peer.ID == h.ID() {
 				continue // No self connection
 			}
-			err := h.Connect(ctx, peer)
-			if err != nil {
-				fmt.Printf("Failed connecting to %s, error: %s\n", peer.ID, err)
-			} else {
-				// fmt.Println("Connected to:", peer.ID)
+			if h.Network().Connectedness(peer.ID) == network.Connected || len(h.Network().Peers()) >= enough {
+				continue
 			}
+			// Failures are expected for peers that left, the peer table only
+			// shows the ones that are connected
+			h.Connect(ctx, peer)
 		}
 		time.Sleep(time.Second * 10)
 	}
@@ -233,58 +307,3 @@ func sendFileToConsumer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
-
-func runNotifyStore(client fileshare.FileShareClient, file *fileshare.FileDesc) *fileshare.StorageACKResponse {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	ackResponse, err := client.NotifyFileStore(ctx, file)
-	if err != nil {
-		log.Fatalf("client.NotifyFileStorage failed: %v", err)
-	}
-	log.Printf("ACK Response: %v", ackResponse)
-	return ackResponse
-}
-
-func runNotifyUnstore(client fileshare.FileShareClient, file *fileshare.FileDesc) *fileshare.StorageACKResponse {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	ackResponse, err := client.NotifyFileUnstore(ctx, file)
-	if err != nil {
-		log.Fatalf("client.NotifyFileStorage failed: %v", err)
-	}
-	log.Printf("ACK Response: %v", ackResponse)
-	return ackResponse
-}
-
-func NotifyStoreWrapper(client fileshare.FileShareClient, file_name_hash string, file_name string, file_size_bytes int64, file_origin_address string, origin_user_id string, file_cost float32, file_data_hash string, file_bytes []byte) {
-	var file_description = fileshare.FileDesc{FileNameHash: file_name_hash,
-		FileName:          file_name,
-		FileSizeBytes:     file_size_bytes,
-		FileOriginAddress: file_origin_address,
-		OriginUserId:      origin_user_id,
-		FileCost:          file_cost,
-		FileDataHash:      file_data_hash,
-		FileBytes:         file_bytes}
-	var ack = runNotifyUnstore(client, &file_description)
-	if ack.IsAcknowledged {
-		fmt.Printf("[Server]: Market acknowledged stopping storage of file %s with hash %s \n", ack.FileName, ack.FileHash)
-	} else {
-		fmt.Printf("[Server]: Unable to notify market that we are stopping the storage of file %s with hash %s \n", ack.FileName, ack.FileHash)
-	}
-}
-func NotifyUnstoreWrapper(client fileshare.FileShareClient, file_name_hash string, file_name string, file_size_bytes int64, file_origin_address string, origin_user_id string, file_cost float32, file_data_hash string, file_bytes []byte) {
-	var file_description = fileshare.FileDesc{FileNameHash: file_name_hash,
-		FileName:          file_name,
-		FileSizeBytes:     file_size_bytes,
-		FileOriginAddress: file_origin_address,
-		OriginUserId:      origin_user_id,
-		FileCost:          file_cost,
-		FileDataHash:      file_data_hash,
-		FileBytes:         file_bytes}
-	var ack = runNotifyUnstore(client, &file_description)
-	if ack.IsAcknowledged {
-		fmt.Printf("[Server]: Market acknowledged stopping storage of file %s with hash %s \n", ack.FileName, ack.FileHash)
-	} else {
-		fmt.Printf("[Server]: Unable to notify market that we are stopping the storage of file %s with hash %s \n", ack.FileName, ack.FileHash)
-	}
-}
@@ -0,0 +1,365 @@
+package server
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"orca-peer/internal/approval"
+	orcaClient "orca-peer/internal/client"
+	"orca-peer/internal/hash"
+	"orca-peer/internal/payment"
+	"orca-peer/internal/pricing"
+	"orca-peer/internal/reputation"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		header  string
+		start   int64
+		end     int64
+		partial bool
+		wantErr bool
+	}{
+		{header: "", partial: false},
+		{header: "bytes=0-99", start: 0, end: 99, partial: true},
+		{header: "bytes=100-", start: 100, end: 999, partial: true},
+		{header: "bytes=900-5000", start: 900, end: 999, partial: true},
+		{header: "bytes=-100", start: 900, end: 999, partial: true},
+		{header: "bytes=-5000", start: 0, end: 999, partial: true},
+		{header: "bytes=0-1,5-6", partial: false},
+		{header: "bytes=1000-", wantErr: true},
+		{header: "bytes=50-10", wantErr: true},
+		{header: "bytes=abc-", wantErr: true},
+		{header: "items=0-1", wantErr: true},
+	}
+	for _, test := range tests {
+		start, end, partial, err := parseRange(test.header, 1000)
+		if (err != nil) != test.wantErr {
+			t.Errorf("parseRange(%q) error = %v, wantErr %v", test.header, err, test.wantErr)
+			continue
+		}
+		if test.wantErr {
+			continue
+		}
+		if partial != test.partial || (partial && (start != test.start || end != test.end)) {
+			t.Errorf("parseRange(%q) = %d-%d partial=%t, want %d-%d partial=%t",
+				test.header, start, end, partial, test.start, test.end, test.partial)
+		}
+	}
+}
+
+func newPricer(t *testing.T, config pricing.Config) *pricing.Engine {
+	engine, err := pricing.NewEngine(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return engine
+}
+
+// newTestServer stores data in a temporary data store and serves it for free
+// to anyone. It returns the CID of the data.
+func newTestServer(t *testing.T, data []byte) (*Server, string) {
+	storage := hash.NewDataStore(t.TempDir())
+	cid, err := storage.PutFile(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &Server{
+		storage:  storage,
+		grants:   newGrantTable(),
+		policy:   approval.AutoAccept{},
+		payments: payment.NewManager(payment.Config{}, newPricer(t, pricing.Config{})),
+	}
+	return server, cid
+}
+
+func TestSendFileHonorsRange(t *testing.T) {
+	data := make([]byte, 5000)
+	rand.Read(data)
+	server, cid := newTestServer(t, data)
+
+	req := httptest.NewRequest("GET", "/requestFile/"+cid, nil)
+	req.Header.Set("Range", "bytes=1000-3999")
+	rr := httptest.NewRecorder()
+	server.sendFile(rr, req)
+
+	if rr.Code != http.StatusPartialContent {
+		t.Fatalf("expected status %d, got %d", http.StatusPartialContent, rr.Code)
+	}
+	if got := rr.Header().Get("Content-Range"); got != "bytes 1000-3999/5000" {
+		t.Errorf("unexpected Content-Range %q", got)
+	}
+	if !bytes.Equal(rr.Body.Bytes(), data[1000:4000]) {
+		t.Errorf("range body does not match file contents")
+	}
+
+	req = httptest.NewRequest("GET", "/requestFile/"+cid, nil)
+	req.Header.Set("Range", "bytes=6000-")
+	rr = httptest.NewRecorder()
+	server.sendFile(rr, req)
+	if rr.Code != http.StatusRequestedRangeNotSatisfiable {
+		t.Errorf("expected status %d, got %d", http.StatusRequestedRangeNotSatisfiable, rr.Code)
+	}
+}
+
+// killingWriter drops the connection after limit bytes of the body were sent.
+type killingWriter struct {
+	http.ResponseWriter
+	limit   int
+	written int
+}
+
+func (w *killingWriter) Write(p []byte) (int, error) {
+	if w.written+len(p) > w.limit {
+		panic(http.ErrAbortHandler)
+	}
+	w.written += len(p)
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *killingWriter) Flush() {
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+func TestSendFileResumesKilledTransfer(t *testing.T) {
+	data := make([]byte, 3*1024*1024)
+	rand.Read(data)
+	server, cid := newTestServer(t, data)
+
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w = &killingWriter{ResponseWriter: w, limit: 2*1024*1024 + 300}
+		}
+		server.sendFile(w, r)
+	}))
+	defer ts.Close()
+
+	client := orcaClient.NewClient(t.TempDir(), nil)
+	destination := filepath.Join(t.TempDir(), "big.bin")
+	url := fmt.Sprintf("%s/requestFile/%s", ts.URL, cid)
+	if err := client.DownloadResumable(url, destination, ""); err == nil {
+		t.Fatal("expected the killed transfer to fail")
+	}
+	partial, err := os.Stat(destination + ".part")
+	if err != nil || partial.Size() == 0 {
+		t.Fatalf("expected partial download to be kept, got %v", err)
+	}
+	if err := client.DownloadResumable(url, destination, cid); err != nil {
+		t.Fatalf("expected resumed transfer to succeed, got %s", err)
+	}
+	got, err := os.ReadFile(destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("resumed file does not match original")
+	}
+}
+
+func TestChunksRequireGrant(t *testing.T) {
+	data := make([]byte, 2*hash.ChunkSize+10)
+	rand.Read(data)
+	server, cid := newTestServer(t, data)
+
+	req := httptest.NewRequest("GET", "/manifest/"+cid, nil)
+	rr := httptest.NewRecorder()
+	server.sendManifest(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	var manifest hash.Manifest
+	if err := json.Unmarshal(rr.Body.Bytes(), &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Root != cid || len(manifest.Chunks) != 3 {
+		t.Fatalf("unexpected manifest %s", manifest.String())
+	}
+	transferID := rr.Header().Get("X-Transfer-Id")
+	if transferID == "" {
+		t.Fatal("expected a transfer id with the manifest")
+	}
+
+	req = httptest.NewRequest("GET", "/chunk/"+manifest.Chunks[1], nil)
+	rr = httptest.NewRecorder()
+	server.sendChunk(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("expected status %d without a transfer id, got %d", http.StatusForbidden, rr.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/chunk/"+manifest.Chunks[1]+"?transfer="+transferID, nil)
+	rr = httptest.NewRecorder()
+	server.sendChunk(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	if err := manifest.VerifyChunk(1, rr.Body.Bytes()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSendFileDeniedByPolicy(t *testing.T) {
+	server, cid := newTestServer(t, []byte("not for you"))
+	server.policy = &approval.Chain{
+		Policies: []approval.Policy{approval.NewList(nil, []string{"192.0.2.1"}, nil, nil)},
+		Default:  approval.Accept,
+	}
+
+	req := httptest.NewRequest("GET", "/requestFile/"+cid, nil)
+	rr := httptest.NewRecorder()
+	server.sendFile(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected denied peer to get status %d, got %d", http.StatusUnauthorized, rr.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/manifest/"+cid, nil)
+	req.RemoteAddr = "198.51.100.7:4000"
+	rr = httptest.NewRecorder()
+	server.sendManifest(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected other peers to get status %d, got %d", http.StatusOK, rr.Code)
+	}
+}
+
+func TestDeclinedRequestsDoNotRaisePrice(t *testing.T) {
+	server, cid := newTestServer(t, []byte("in demand"))
+	server.payments.Pricer = newPricer(t, pricing.Config{PricePerMB: 1, Demand: pricing.DemandConfig{Step: 1}})
+	server.policy = &approval.Chain{
+		Policies: []approval.Policy{approval.NewList(nil, []string{"192.0.2.1"}, nil, nil)},
+		Default:  approval.Accept,
+	}
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encodedKey, _ := payment.EncodePublicKey(&key.PublicKey)
+	request := func(path, remoteAddr string, handler http.HandlerFunc) int {
+		req := httptest.NewRequest("GET", path+cid, nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set(payment.HeaderPublicKey, encodedKey)
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr.Code
+	}
+
+	if code := request("/requestFile/", "192.0.2.1:1234", server.sendFile); code != http.StatusUnauthorized {
+		t.Fatalf("expected declined file request to get status %d, got %d", http.StatusUnauthorized, code)
+	}
+	if code := request("/manifest/", "192.0.2.1:1234", server.sendManifest); code != http.StatusUnauthorized {
+		t.Fatalf("expected declined manifest request to get status %d, got %d", http.StatusUnauthorized, code)
+	}
+	if price := server.payments.Price(cid); price != 1 {
+		t.Errorf("expected declined requests to leave the price at 1, got %v", price)
+	}
+
+	if code := request("/manifest/", "198.51.100.7:4000", server.sendManifest); code != http.StatusOK {
+		t.Fatalf("expected approved manifest request to get status %d, got %d", http.StatusOK, code)
+	}
+	if price := server.payments.Price(cid); price != 2 {
+		t.Errorf("expected an approved request to raise the price to 2, got %v", price)
+	}
+}
+
+// newPaidTestServer serves data from a node that charges for it, with all of
+// its routes behind one test server.
+func newPaidTestServer(t *testing.T, data []byte) (*httptest.Server, *Server, string) {
+	server, cid := newTestServer(t, data)
+	server.payments = payment.NewManager(payment.Config{Interval: 64 * 1024, AbortAfter: 1}, newPricer(t, pricing.Config{PricePerMB: 5}))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/requestFile/", server.sendFile)
+	mux.HandleFunc("/manifest/", server.sendManifest)
+	mux.HandleFunc("/chunk/", server.sendChunk)
+	mux.HandleFunc("/sendReceipt", server.handleReceipt)
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts, server, cid
+}
+
+func TestPaidDownload(t *testing.T) {
+	data := make([]byte, 300*1024)
+	rand.Read(data)
+	ts, _, cid := newPaidTestServer(t, data)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	url := fmt.Sprintf("%s/requestFile/%s", ts.URL, cid)
+	destination := filepath.Join(t.TempDir(), "paid.bin")
+	if err := orcaClient.NewClient(t.TempDir(), nil).DownloadResumable(url, destination, cid); err == nil {
+		t.Fatal("expected a client that cannot pay to be refused")
+	}
+	if err := orcaClient.NewClient(t.TempDir(), key).DownloadResumable(url, destination, cid); err != nil {
+		t.Fatalf("expected paid download to succeed, got %s", err)
+	}
+	got, err := os.ReadFile(destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("paid download does not match original")
+	}
+}
+
+func TestChunksWaitForPayment(t *testing.T) {
+	data := make([]byte, hash.ChunkSize+10)
+	rand.Read(data)
+	ts, server, cid := newPaidTestServer(t, data)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encodedKey, _ := payment.EncodePublicKey(&key.PublicKey)
+	server.reputation, err = reputation.Open(t.TempDir(), reputation.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest("GET", ts.URL+"/manifest/"+cid, nil)
+	req.Header.Set(payment.HeaderPublicKey, encodedKey)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest hash.Manifest
+	json.NewDecoder(resp.Body).Decode(&manifest)
+	resp.Body.Close()
+	transferID := resp.Header.Get(payment.HeaderTransferID)
+	chunkURL := ts.URL + "/chunk/" + manifest.Chunks[0] + "?transfer=" + transferID
+
+	resp, err = http.Get(chunkURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPaymentRequired {
+		t.Fatalf("expected unpaid chunk to be refused with %d, got %d", http.StatusPaymentRequired, resp.StatusCode)
+	}
+	// The score decays a little between the timeout and now
+	if score := server.reputation.Score("127.0.0.1"); math.Abs(score-reputation.Weights[reputation.PaymentTimeout]) > 1e-3 {
+		t.Errorf("expected the consumer to lose reputation for not paying, got %f", score)
+	}
+
+	receipt, _ := payment.NewPayer(transferID, cid, 5, key).PayUpTo(hash.ChunkSize)
+	if _, err := server.payments.Apply(receipt); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = http.Get(chunkURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected paid chunk to be sent, got %d", resp.StatusCode)
+	}
+}
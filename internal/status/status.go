@@ -20,10 +20,32 @@ type NetworkStatus struct {
 
 type PeerNodeFileData struct {
 	IsMe          bool    `json:"is_me"`
-	Balance       float64 `json:"balance"`
+	Balance       Balance `json:"balance"`
 	PublicKey     string  `json:"public_key"`
 	PublicKeyPath string  `json:"public_key_path"`
 }
+
+// Balance reads both "balance": 100 and "balance": "100", since older config
+// files store the balance as a string.
+type Balance float64
+
+func (balance *Balance) UnmarshalJSON(data []byte) error {
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err != nil {
+		return err
+	}
+	if number == "" {
+		*balance = 0
+		return nil
+	}
+	value, err := number.Float64()
+	if err != nil {
+		return err
+	}
+	*balance = Balance(value)
+	return nil
+}
+
 type PeerNode struct {
 	IsMe      bool
 	Balance   float64
@@ -48,7 +70,7 @@ func GetNodeInfo() PeerNode {
 	json.Unmarshal(byteValue, &meData)
 	defer jsonFile.Close()
 	var me PeerNode
-	me.Balance = meData.Balance
+	me.Balance = float64(meData.Balance)
 	me.IsMe = meData.IsMe
 	me.PublicKey = meData.PublicKey
 	if me.PublicKey == "" {
@@ -6,7 +6,7 @@ import (
 	"io"
 	"log"
 	pb "orca-peer/internal/fileshare"
-	"os"
+	orcaHash "orca-peer/internal/hash"
 	"time"
 )
 
@@ -17,28 +17,26 @@ type FileInfo struct {
 	Size    int64
 }
 
-func GetAllLocalFiles() []FileInfo {
-	files, err := os.ReadDir("files/stored")
+// GetAllLocalFiles lists the files in the data store, named by their CID.
+func GetAllLocalFiles(storage *orcaHash.DataStore) []FileInfo {
+	manifests, err := storage.ListFiles()
 	if err != nil {
 		log.Fatal(err)
 	}
-	fileNames := make([]FileInfo, 1)
-	for _, file := range files {
-		fileInfo, err := os.Stat("files/stored/" + file.Name())
-		if err == nil {
-			fileNames = append(fileNames, FileInfo{IsDir: fileInfo.IsDir(), ModTime: fileInfo.ModTime(), Name: fileInfo.Name(), Size: fileInfo.Size()})
-		}
+	fileNames := make([]FileInfo, 0, len(manifests))
+	for _, manifest := range manifests {
+		fileNames = append(fileNames, FileInfo{IsDir: false, Name: manifest.Root, Size: manifest.Size})
 	}
 	return fileNames
 }
 
-func GetAllMarketFiles(client pb.FileShareClient, me *pb.StorageIP) []*pb.FileDesc {
+func GetAllMarketFiles(client pb.FileShareClient, me *pb.StorageIP) ([]*pb.FileDesc, error) {
 	log.Printf("Requesting All File Names")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	streamOfFiles, err := client.RequestAllAvailableFileNames(ctx, me)
 	if err != nil {
-		fmt.Println("Error requesting files from stream")
+		return nil, fmt.Errorf("client.RequestAllAvailableFileNames failed: %w", err)
 	}
 	var all_files = []*pb.FileDesc{}
 	for {
@@ -47,11 +45,11 @@ func GetAllMarketFiles(client pb.FileShareClient, me *pb.StorageIP) []*pb.FileDe
 			break
 		}
 		if err != nil {
-			log.Fatalf("client.RequestAllAvailableFileNames failed: %v", err)
+			return nil, fmt.Errorf("client.RequestAllAvailableFileNames failed: %w", err)
 		}
 		log.Printf("File named %s found with size %d for price %f ",
 			file_desc.FileNameHash, file_desc.FileBytes, file_desc.FileCost)
 		all_files = append(all_files, file_desc)
 	}
-	return all_files
+	return all_files, nil
 }
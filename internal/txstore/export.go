@@ -0,0 +1,45 @@
+package txstore
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+var csvHeader = []string{"uuid", "direction", "payer", "payee", "amount", "file_hash", "nonce", "timestamp", "expires", "recorded_at"}
+
+func ExportJSON(w io.Writer, records []Record) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	return encoder.Encode(records)
+}
+
+// ExportCSV writes one row per record. Signatures are left out, use the
+// JSON export to keep them.
+func ExportCSV(w io.Writer, records []Record) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, record := range records {
+		transaction := record.Transaction
+		row := []string{
+			transaction.Uuid,
+			string(record.Direction),
+			transaction.Payer,
+			transaction.Payee,
+			strconv.FormatFloat(transaction.Amount, 'f', -1, 64),
+			transaction.FileHash,
+			transaction.Nonce,
+			transaction.Timestamp,
+			transaction.Expires,
+			record.RecordedAt,
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
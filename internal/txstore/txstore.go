@@ -0,0 +1,234 @@
+package txstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"orca-peer/internal/hash"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const recordSuffix = ".json"
+
+type Direction string
+
+const (
+	Sent     Direction = "sent"
+	Received Direction = "received"
+)
+
+var (
+	ErrDuplicate = errors.New("transaction is already stored")
+	ErrNotFound  = errors.New("transaction not found")
+)
+
+// Record is a transaction this node took part in. Signed is kept for
+// received transactions so they can be shown to someone else later.
+type Record struct {
+	Direction   Direction               `json:"direction"`
+	Transaction hash.Transaction        `json:"transaction"`
+	Signed      *hash.SignedTransaction `json:"signed,omitempty"`
+	RecordedAt  string                  `json:"recorded_at"`
+}
+
+func (record *Record) time() time.Time {
+	timestamp, err := time.Parse(time.RFC3339, record.Transaction.Timestamp)
+	if err != nil {
+		timestamp, _ = time.Parse(time.RFC3339, record.RecordedAt)
+	}
+	return timestamp
+}
+
+// Filter selects records. Empty fields match everything, From is inclusive
+// and To is exclusive.
+type Filter struct {
+	Peer     string
+	FileHash string
+	From     time.Time
+	To       time.Time
+}
+
+/*
+Store keeps every transaction in its own file named by the transaction UUID,
+so two transactions can never overwrite each other. All records are loaded at
+startup and indexed by peer, file hash and time.
+*/
+type Store struct {
+	mu     sync.RWMutex
+	path   string
+	byID   map[string]*Record
+	byPeer map[string][]*Record
+	byFile map[string][]*Record
+	// Every record sorted by transaction time
+	byTime []*Record
+}
+
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, err
+	}
+	store := &Store{
+		path:   path,
+		byID:   make(map[string]*Record),
+		byPeer: make(map[string][]*Record),
+		byFile: make(map[string][]*Record),
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		// Files without the suffix were written by older versions, which
+		// named them by timestamp and did not keep the transaction format
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), recordSuffix) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(path, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var record Record
+		if err := json.Unmarshal(data, &record); err != nil {
+			fmt.Printf("Skipping unreadable transaction %s: %s\n", entry.Name(), err)
+			continue
+		}
+		store.index(&record)
+	}
+	return store, nil
+}
+
+// Callers must hold store.mu
+func (store *Store) index(record *Record) {
+	transaction := &record.Transaction
+	store.byID[transaction.Uuid] = record
+	store.byPeer[transaction.Payer] = append(store.byPeer[transaction.Payer], record)
+	if transaction.Payee != transaction.Payer {
+		store.byPeer[transaction.Payee] = append(store.byPeer[transaction.Payee], record)
+	}
+	if transaction.FileHash != "" {
+		store.byFile[transaction.FileHash] = append(store.byFile[transaction.FileHash], record)
+	}
+	at := record.time()
+	i := sort.Search(len(store.byTime), func(i int) bool {
+		return store.byTime[i].time().After(at)
+	})
+	store.byTime = append(store.byTime, nil)
+	copy(store.byTime[i+1:], store.byTime[i:])
+	store.byTime[i] = record
+}
+
+func validID(id string) bool {
+	return id != "" && !strings.ContainsAny(id, `/\.`)
+}
+
+// Put stores a record. A transaction can only be stored once.
+func (store *Store) Put(record Record) error {
+	if !validID(record.Transaction.Uuid) {
+		return fmt.Errorf("invalid transaction id %q", record.Transaction.Uuid)
+	}
+	if record.RecordedAt == "" {
+		record.RecordedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if _, ok := store.byID[record.Transaction.Uuid]; ok {
+		return ErrDuplicate
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first so a crash never leaves half a record
+	path := filepath.Join(store.path, record.Transaction.Uuid+recordSuffix)
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return err
+	}
+	store.index(&record)
+	return nil
+}
+
+func (store *Store) Get(id string) (Record, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	record, ok := store.byID[id]
+	if !ok {
+		return Record{}, ErrNotFound
+	}
+	return *record, nil
+}
+
+// List returns the records matching filter, oldest first.
+func (store *Store) List(filter Filter) []Record {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	// Start from the smallest index that applies
+	candidates := store.byTime
+	if filter.Peer != "" {
+		candidates = store.byPeer[filter.Peer]
+	}
+	if filter.FileHash != "" && (filter.Peer == "" || len(store.byFile[filter.FileHash]) < len(candidates)) {
+		candidates = store.byFile[filter.FileHash]
+	}
+	if filter.Peer == "" && filter.FileHash == "" {
+		candidates = store.timeRange(filter.From, filter.To)
+	}
+
+	records := []Record{}
+	for _, record := range candidates {
+		if filter.matches(record) {
+			records = append(records, *record)
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].time().Before(records[j].time())
+	})
+	return records
+}
+
+// timeRange finds the records in [from, to) in the time index. Callers must
+// hold store.mu
+func (store *Store) timeRange(from time.Time, to time.Time) []*Record {
+	start := 0
+	if !from.IsZero() {
+		start = sort.Search(len(store.byTime), func(i int) bool {
+			return !store.byTime[i].time().Before(from)
+		})
+	}
+	end := len(store.byTime)
+	if !to.IsZero() {
+		end = sort.Search(len(store.byTime), func(i int) bool {
+			return !store.byTime[i].time().Before(to)
+		})
+	}
+	if end < start {
+		return nil
+	}
+	return store.byTime[start:end]
+}
+
+func (filter Filter) matches(record *Record) bool {
+	transaction := &record.Transaction
+	if filter.Peer != "" && transaction.Payer != filter.Peer && transaction.Payee != filter.Peer {
+		return false
+	}
+	if filter.FileHash != "" && transaction.FileHash != filter.FileHash {
+		return false
+	}
+	at := record.time()
+	if !filter.From.IsZero() && at.Before(filter.From) {
+		return false
+	}
+	if !filter.To.IsZero() && !at.Before(filter.To) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,146 @@
+package txstore
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"orca-peer/internal/hash"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var base = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func record(id string, payer string, payee string, fileHash string, offset time.Duration) Record {
+	return Record{
+		Direction: Received,
+		Transaction: hash.Transaction{
+			Uuid:      id,
+			Payer:     payer,
+			Payee:     payee,
+			Amount:    1.5,
+			FileHash:  fileHash,
+			Timestamp: base.Add(offset).Format(time.RFC3339),
+		},
+	}
+}
+
+func ids(records []Record) []string {
+	result := []string{}
+	for _, record := range records {
+		result = append(result, record.Transaction.Uuid)
+	}
+	return result
+}
+
+func sameIDs(got []Record, want ...string) bool {
+	ids := ids(got)
+	if len(ids) != len(want) {
+		return false
+	}
+	for i := range ids {
+		if ids[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestStorePutGetAndReopen(t *testing.T) {
+	dir := t.TempDir()
+	// Left over from the old timestamp named layout
+	os.WriteFile(filepath.Join(dir, base.Format(time.RFC3339)), []byte("{}"), 0644)
+
+	store, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(record("a", "alice", "bob", "f1", 0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(record("a", "alice", "bob", "f1", time.Minute)); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("expected duplicate to be refused, got %v", err)
+	}
+	if err := store.Put(record("../a", "alice", "bob", "f1", 0)); err == nil {
+		t.Error("expected id with a path in it to be refused")
+	}
+	if _, err := store.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+
+	reopened, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := reopened.Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Transaction.Payer != "alice" || got.RecordedAt == "" {
+		t.Errorf("unexpected record after reopening %+v", got)
+	}
+	if len(reopened.List(Filter{})) != 1 {
+		t.Errorf("expected only the new record to be loaded, got %v", ids(reopened.List(Filter{})))
+	}
+}
+
+func TestStoreFilters(t *testing.T) {
+	store, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Stored out of order on purpose
+	store.Put(record("c", "carol", "bob", "f2", 2*time.Hour))
+	store.Put(record("a", "alice", "bob", "f1", 0))
+	store.Put(record("b", "bob", "alice", "f1", time.Hour))
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"all", Filter{}, []string{"a", "b", "c"}},
+		{"payer or payee", Filter{Peer: "alice"}, []string{"a", "b"}},
+		{"file", Filter{FileHash: "f1"}, []string{"a", "b"}},
+		{"peer and file", Filter{Peer: "bob", FileHash: "f2"}, []string{"c"}},
+		{"from", Filter{From: base.Add(time.Hour)}, []string{"b", "c"}},
+		{"to", Filter{To: base.Add(time.Hour)}, []string{"a"}},
+		{"peer in range", Filter{Peer: "bob", From: base.Add(30 * time.Minute), To: base.Add(3 * time.Hour)}, []string{"b", "c"}},
+		{"unknown peer", Filter{Peer: "dave"}, []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := store.List(test.filter); !sameIDs(got, test.want...) {
+				t.Errorf("expected %v, got %v", test.want, ids(got))
+			}
+		})
+	}
+}
+
+func TestExport(t *testing.T) {
+	records := []Record{record("a", "alice", "bob", "f1", 0), record("b", "bob", "alice", "f1", time.Hour)}
+
+	var jsonOut bytes.Buffer
+	if err := ExportJSON(&jsonOut, records); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []Record
+	if err := json.Unmarshal(jsonOut.Bytes(), &decoded); err != nil || !sameIDs(decoded, "a", "b") {
+		t.Errorf("unexpected JSON export %s (%v)", jsonOut.String(), err)
+	}
+
+	var csvOut bytes.Buffer
+	if err := ExportCSV(&csvOut, records); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&csvOut).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || rows[0][0] != "uuid" || rows[1][0] != "a" || rows[2][2] != "bob" || rows[1][4] != "1.5" {
+		t.Errorf("unexpected CSV export %v", rows)
+	}
+}
@@ -0,0 +1,221 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"orca-peer/internal/hash"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const journalName = "journal"
+
+type Kind string
+
+const (
+	Opening  Kind = "opening"
+	Sent     Kind = "sent"
+	Received Kind = "received"
+)
+
+var (
+	ErrInsufficientFunds = errors.New("not enough funds for this transaction")
+	ErrInvalidAmount     = errors.New("amount must be positive")
+)
+
+// Entry is one line of the journal. Balance is the balance right after the
+// entry was applied.
+type Entry struct {
+	Seq          int     `json:"seq"`
+	Kind         Kind    `json:"kind"`
+	Transaction  string  `json:"transaction"`
+	Counterparty string  `json:"counterparty"`
+	FileHash     string  `json:"file_hash"`
+	Amount       float64 `json:"amount"`
+	Balance      float64 `json:"balance"`
+	Timestamp    string  `json:"timestamp"`
+}
+
+/*
+Wallet keeps the balance of this node. Every change is appended to a journal
+on disk before it takes effect and entries are never changed afterwards, so the
+balance can always be rebuilt by replaying it. Money that is on its way out
+in a send that has not been accepted yet is held, so two sends at the same
+time can not spend the same funds.
+*/
+type Wallet struct {
+	mu      sync.Mutex
+	path    string
+	journal *os.File
+	entries []Entry
+	balance float64
+	held    float64
+}
+
+// Open loads the journal in dir. A new journal starts with openingBalance.
+func Open(dir string, openingBalance float64) (*Wallet, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	wallet := &Wallet{path: filepath.Join(dir, journalName)}
+	if err := wallet.replay(); err != nil {
+		return nil, err
+	}
+	journal, err := os.OpenFile(wallet.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	wallet.journal = journal
+	if len(wallet.entries) == 0 {
+		err := wallet.append(Entry{Kind: Opening, Amount: openingBalance})
+		if err != nil {
+			journal.Close()
+			return nil, err
+		}
+	}
+	return wallet, nil
+}
+
+func (wallet *Wallet) replay() error {
+	data, err := os.ReadFile(wallet.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// Only the last line can be cut short, by a crash while appending. Drop it
+	// so the next entry does not get glued onto it.
+	complete := bytes.LastIndexByte(data, '\n') + 1
+	if complete < len(data) {
+		fmt.Println("Dropping incomplete wallet journal entry")
+		if err := os.Truncate(wallet.path, int64(complete)); err != nil {
+			return err
+		}
+	}
+	for _, line := range bytes.Split(data[:complete], []byte{'\n'}) {
+		if len(line) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return fmt.Errorf("corrupt wallet journal entry %d: %w", len(wallet.entries)+1, err)
+		}
+		wallet.balance += signedAmount(entry)
+		wallet.entries = append(wallet.entries, entry)
+	}
+	return nil
+}
+
+func signedAmount(entry Entry) float64 {
+	if entry.Kind == Sent {
+		return -entry.Amount
+	}
+	return entry.Amount
+}
+
+// append writes entry to the journal and applies it. Callers must hold
+// wallet.mu
+func (wallet *Wallet) append(entry Entry) error {
+	entry.Seq = len(wallet.entries) + 1
+	entry.Balance = wallet.balance + signedAmount(entry)
+	if entry.Timestamp == "" {
+		entry.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := wallet.journal.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	if err := wallet.journal.Sync(); err != nil {
+		return err
+	}
+	wallet.balance = entry.Balance
+	wallet.entries = append(wallet.entries, entry)
+	return nil
+}
+
+func (wallet *Wallet) Balance() float64 {
+	wallet.mu.Lock()
+	defer wallet.mu.Unlock()
+	return wallet.balance
+}
+
+// Available is the balance minus what is held for sends in progress.
+func (wallet *Wallet) Available() float64 {
+	wallet.mu.Lock()
+	defer wallet.mu.Unlock()
+	return wallet.balance - wallet.held
+}
+
+// History returns every journal entry, oldest first.
+func (wallet *Wallet) History() []Entry {
+	wallet.mu.Lock()
+	defer wallet.mu.Unlock()
+	history := make([]Entry, len(wallet.entries))
+	copy(history, wallet.entries)
+	return history
+}
+
+/*
+Send holds amount, runs send and records the transaction it returns as sent.
+If the funds are not there send is never called, and if send fails the held
+amount is released again.
+*/
+func (wallet *Wallet) Send(amount float64, send func() (*hash.Transaction, error)) (*hash.Transaction, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	wallet.mu.Lock()
+	if wallet.balance-wallet.held < amount {
+		wallet.mu.Unlock()
+		return nil, ErrInsufficientFunds
+	}
+	wallet.held += amount
+	wallet.mu.Unlock()
+
+	transaction, err := send()
+
+	wallet.mu.Lock()
+	defer wallet.mu.Unlock()
+	wallet.held -= amount
+	if err != nil {
+		return nil, err
+	}
+	err = wallet.append(Entry{
+		Kind:         Sent,
+		Transaction:  transaction.Uuid,
+		Counterparty: transaction.Payee,
+		FileHash:     transaction.FileHash,
+		Amount:       transaction.Amount,
+	})
+	return transaction, err
+}
+
+// Receive records a verified transaction paid to this node.
+func (wallet *Wallet) Receive(transaction *hash.Transaction) error {
+	if transaction.Amount <= 0 {
+		return ErrInvalidAmount
+	}
+	wallet.mu.Lock()
+	defer wallet.mu.Unlock()
+	return wallet.append(Entry{
+		Kind:         Received,
+		Transaction:  transaction.Uuid,
+		Counterparty: transaction.Payer,
+		FileHash:     transaction.FileHash,
+		Amount:       transaction.Amount,
+	})
+}
+
+func (wallet *Wallet) Close() error {
+	wallet.mu.Lock()
+	defer wallet.mu.Unlock()
+	return wallet.journal.Close()
+}
@@ -0,0 +1,121 @@
+package wallet
+
+import (
+	"errors"
+	"orca-peer/internal/hash"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func sentTransaction(amount float64) func() (*hash.Transaction, error) {
+	return func() (*hash.Transaction, error) {
+		return &hash.Transaction{Uuid: "out", Payee: "peer", Amount: amount}, nil
+	}
+}
+
+func TestWalletSendAndReceive(t *testing.T) {
+	dir := t.TempDir()
+	wallet, err := Open(dir, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wallet.Send(30, sentTransaction(30)); err != nil {
+		t.Fatal(err)
+	}
+	if err := wallet.Receive(&hash.Transaction{Uuid: "in", Payer: "peer", Amount: 5, FileHash: "abc"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wallet.Send(80, sentTransaction(80)); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("expected overdraw to be refused, got %v", err)
+	}
+	failed := errors.New("peer went away")
+	_, err = wallet.Send(10, func() (*hash.Transaction, error) { return nil, failed })
+	if !errors.Is(err, failed) {
+		t.Errorf("expected send error to be returned, got %v", err)
+	}
+	if wallet.Balance() != 75 || wallet.Available() != 75 {
+		t.Errorf("expected balance 75, got %f (available %f)", wallet.Balance(), wallet.Available())
+	}
+	wallet.Close()
+
+	reopened, err := Open(dir, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if reopened.Balance() != 75 {
+		t.Errorf("expected balance 75 after reopening, got %f", reopened.Balance())
+	}
+	history := reopened.History()
+	if len(history) != 3 || history[0].Kind != Opening || history[2].Kind != Received || history[2].Balance != 75 {
+		t.Errorf("unexpected history %+v", history)
+	}
+}
+
+func TestWalletHoldsFundsDuringSend(t *testing.T) {
+	wallet, err := Open(t.TempDir(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wallet.Close()
+
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		wallet.Send(8, func() (*hash.Transaction, error) {
+			<-release
+			return &hash.Transaction{Uuid: "slow", Amount: 8}, nil
+		})
+	}()
+	for wallet.Available() != 2 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := wallet.Send(5, sentTransaction(5)); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("expected held funds not to be spent twice, got %v", err)
+	}
+	close(release)
+	wg.Wait()
+	if wallet.Balance() != 2 {
+		t.Errorf("expected balance 2, got %f", wallet.Balance())
+	}
+}
+
+func TestWalletSkipsTornJournalLine(t *testing.T) {
+	dir := t.TempDir()
+	wallet, err := Open(dir, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wallet.Send(5, sentTransaction(5))
+	wallet.Close()
+
+	journal, err := os.OpenFile(filepath.Join(dir, journalName), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	journal.WriteString(`{"seq":3,"kind":"sent","amo`)
+	journal.Close()
+
+	reopened, err := Open(dir, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reopened.Send(5, sentTransaction(5)); err != nil {
+		t.Fatal(err)
+	}
+	reopened.Close()
+
+	again, err := Open(dir, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer again.Close()
+	if again.Balance() != 10 || len(again.History()) != 3 {
+		t.Errorf("expected balance 10 after three entries, got %f after %d", again.Balance(), len(again.History()))
+	}
+}
@@ -32,7 +32,9 @@ func RunTestServer() {
 	}
 	defer conn.Close()
 	client := pb.NewFileShareClient(conn)
-	orcaClient.RequestFileFromMarket(client, &pb.CheckHoldersRequest{})
+	if _, err := orcaClient.RequestFileFromMarket(client, &pb.CheckHoldersRequest{}); err != nil {
+		fmt.Println("Error requesting holders:", err)
+	}
 
 	blockchainIP := "localhost:50052"
 	go SetupTestBlockchain()